@@ -14,6 +14,7 @@ import (
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/remote/discord"
 	"github.com/hectorgimenez/koolo/internal/remote/telegram"
+	"github.com/hectorgimenez/koolo/internal/remote/webhook"
 	"github.com/hectorgimenez/koolo/internal/server"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
@@ -35,6 +36,10 @@ func main() {
 	}
 	defer sloggger.FlushLog()
 
+	for field, err := range config.Koolo.Runtime.UnavailableSecrets {
+		logger.Warn("Secret unavailable, it's left empty until Koolo is restarted", slog.String("field", field), slog.String("error", err))
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("fatal error detected, Koolo will close with the following error: %v\n Stacktrace: %s", r, debug.Stack())
@@ -63,7 +68,7 @@ func main() {
 	g.Go(func() error {
 		defer cancel()
 		displayScale := config.GetCurrentDisplayScale()
-		w, err := gowebview.New(&gowebview.Config{URL: "http://localhost:8087", WindowConfig: &gowebview.WindowConfig{
+		w, err := gowebview.New(&gowebview.Config{URL: srv.DashboardURL(8087), WindowConfig: &gowebview.WindowConfig{
 			Title: "Koolo",
 			Size: &gowebview.Point{
 				X: int64(1280 * displayScale),
@@ -88,13 +93,13 @@ func main() {
 
 	// Discord Bot initialization
 	if config.Koolo.Discord.Enabled {
-		discordBot, err := discord.NewBot(config.Koolo.Discord.Token, config.Koolo.Discord.ChannelID, manager)
+		discordBot, err := discord.NewBot(config.Koolo.Discord.Token, config.Koolo.Discord.ChannelID, manager, logger)
 		if err != nil {
 			logger.Error("Discord could not been initialized", slog.Any("error", err))
 			return
 		}
 
-		eventListener.Register(discordBot.Handle)
+		eventListener.Register(discordBot.Handle, event.WithName("discord"))
 		g.Go(func() error {
 			return discordBot.Start(ctx)
 		})
@@ -102,18 +107,38 @@ func main() {
 
 	// Telegram Bot initialization
 	if config.Koolo.Telegram.Enabled {
-		telegramBot, err := telegram.NewBot(config.Koolo.Telegram.Token, config.Koolo.Telegram.ChatID, logger)
+		telegramBot, err := telegram.NewBot(config.Koolo.Telegram.Token, config.Koolo.Telegram.ChatID, manager, logger)
 		if err != nil {
 			logger.Error("Telegram could not been initialized", slog.Any("error", err))
 			return
 		}
 
-		eventListener.Register(telegramBot.Handle)
+		eventListener.Register(telegramBot.Handle, event.WithName("telegram"))
 		g.Go(func() error {
 			return telegramBot.Start(ctx)
 		})
 	}
 
+	// Generic webhook initialization
+	if config.Koolo.Webhook.Enabled {
+		webhookNotifier, err := webhook.NewNotifier(webhook.Config{
+			URL:                config.Koolo.Webhook.URL,
+			Secret:             config.Koolo.Webhook.Secret,
+			Events:             config.Koolo.Webhook.Events,
+			MaxRetries:         config.Koolo.Webhook.MaxRetries,
+			IncludeScreenshots: config.Koolo.Webhook.IncludeScreenshots,
+		}, logger)
+		if err != nil {
+			logger.Error("Webhook could not been initialized", slog.Any("error", err))
+			return
+		}
+
+		eventListener.Register(webhookNotifier.Handle, event.WithName("webhook"))
+		g.Go(func() error {
+			return webhookNotifier.Start(ctx)
+		})
+	}
+
 	g.Go(func() error {
 		defer cancel()
 		return srv.Listen(8087)
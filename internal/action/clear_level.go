@@ -8,6 +8,7 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
 	"github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
@@ -15,7 +16,17 @@ func ClearCurrentLevel(openChests bool, filter data.MonsterFilter) error {
 	ctx := context.Get()
 	ctx.SetLastAction("ClearCurrentLevel")
 
-	rooms := ctx.PathFinder.OptimizeRoomsTraverseOrder()
+	tourCfg := ctx.CharacterCfg.Game.RoomTour
+	rooms := ctx.PathFinder.OptimizeRoomsTraverseOrderWith(pather.TourOptions{
+		TimeBudget: tourCfg.TimeBudget,
+		Priority: func(r data.Room) bool {
+			if openChests && tourCfg.PrioritizeChests && roomHasChest(r) {
+				return true
+			}
+
+			return tourCfg.PrioritizeSuperUniques && roomHasSuperUnique(r)
+		},
+	})
 	for _, r := range rooms {
 		err := clearRoom(r, filter)
 		if err != nil {
@@ -48,6 +59,28 @@ func ClearCurrentLevel(openChests bool, filter data.MonsterFilter) error {
 	return nil
 }
 
+func roomHasChest(r data.Room) bool {
+	ctx := context.Get()
+	for _, o := range ctx.Data.Objects {
+		if o.IsChest() && o.Selectable && r.IsInside(o.Position) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func roomHasSuperUnique(r data.Room) bool {
+	ctx := context.Get()
+	for _, m := range ctx.Data.Monsters.Enemies() {
+		if m.Type == data.MonsterTypeSuperUnique && r.IsInside(m.Position) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func clearRoom(room data.Room, filter data.MonsterFilter) error {
 	ctx := context.Get()
 	ctx.SetLastAction("clearRoom")
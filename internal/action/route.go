@@ -0,0 +1,45 @@
+package action
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/pather"
+)
+
+// MoveToAreaRoute goes to any area from the current one, following the cheapest route of waypoints and adjacent
+// levels. Unlike MoveToArea, the destination doesn't need to be adjacent to the current area.
+func MoveToAreaRoute(dst area.ID) error {
+	ctx := context.Get()
+	ctx.SetLastAction("MoveToAreaRoute")
+
+	graph := pather.NewLevelGraph(ctx.Data.Areas, ctx.Data.PlayerUnit.AvailableWaypoints)
+	route, found := graph.Route(ctx.Data.PlayerUnit.Area, ctx.Data.PlayerUnit.Position, dst)
+	if !found {
+		// WayPoint is still able to reach not discovered waypoints walking from the previous one
+		if _, isWaypoint := area.WPAddresses[dst]; isWaypoint {
+			return WayPoint(dst)
+		}
+
+		return fmt.Errorf("no route found from %s to %s", ctx.Data.PlayerUnit.Area.Area().Name, dst.Area().Name)
+	}
+
+	ctx.Logger.Debug("Moving to area", slog.String("area", dst.Area().Name), slog.String("route", route.String()))
+
+	for _, s := range route.Steps {
+		var err error
+		switch s.Kind {
+		case pather.RouteStepWaypoint:
+			err = WayPoint(s.Area)
+		case pather.RouteStepWalk:
+			err = MoveToArea(s.Area)
+		}
+		if err != nil {
+			return fmt.Errorf("error moving to %s: %w", s.Area.Area().Name, err)
+		}
+	}
+
+	return nil
+}
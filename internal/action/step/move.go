@@ -3,6 +3,7 @@ package step
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"time"
 
@@ -10,10 +11,16 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/mode"
 	"github.com/hectorgimenez/d2go/pkg/data/skill"
 	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/pather"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
-const DistanceToFinishMoving = 7
+const (
+	DistanceToFinishMoving = 7
+	// The character is stuck when it moves less than stuckMinDistance during stuckWindow
+	stuckWindow      = time.Second * 2
+	stuckMinDistance = 3
+)
 
 func MoveTo(dest data.Position) error {
 	minDistanceToFinishMoving := DistanceToFinishMoving
@@ -40,11 +47,12 @@ func MoveTo(dest data.Position) error {
 
 	startedAt := time.Now()
 	lastRun := time.Time{}
-	previousPosition := data.Position{}
 	previousDistance := 0
+	stuck := pather.NewStuckDetector(stuckWindow, stuckMinDistance)
 
 	for {
 		ctx.RefreshGameData()
+		stuck.Record(ctx.Data.PlayerUnit.Position, time.Now())
 
 		// Pause the execution if the priority is not the same as the execution priority
 		ctx.PauseIfNotPriority()
@@ -81,6 +89,22 @@ func MoveTo(dest data.Position) error {
 				return nil
 			}
 
+			// There is no walking path, but we still can jump over the gaps
+			if ctx.Data.CanTeleport() {
+				if hops, _, hopsFound := ctx.PathFinder.GetTeleportPath(dest); hopsFound {
+					if time.Since(lastRun) < ctx.Data.PlayerCastDuration() {
+						continue
+					}
+					if timeout > 0 && time.Since(startedAt) > timeout {
+						return nil
+					}
+					lastRun = time.Now()
+					if ctx.PathFinder.TeleportThroughPath(hops) {
+						continue
+					}
+				}
+			}
+
 			return errors.New("path could not be calculated. Current area: [" + ctx.Data.PlayerUnit.Area.Area().Name + "]. Trying to path to Destination: [" + fmt.Sprintf("%d,%d", dest.X, dest.Y) + "]")
 		}
 		if distance <= minDistanceToFinishMoving || len(path) <= minDistanceToFinishMoving || len(path) == 0 {
@@ -105,9 +129,31 @@ func MoveTo(dest data.Position) error {
 
 		lastRun = time.Now()
 
-		// If we are stuck in the same position, make a random movement and cross fingers
-		if previousPosition == ctx.Data.PlayerUnit.Position && !ctx.Data.CanTeleport() {
-			ctx.PathFinder.RandomMovement()
+		// Every time we keep stuck after trying to recover, the next recovery is a bit more aggressive. Teleporting
+		// characters jump over whatever is blocking them, the recovery is only for walking.
+		if ctx.Data.CanTeleport() {
+			stuck.Reset()
+		} else if recovery := stuck.NextRecovery(); recovery != pather.RecoveryNone {
+			ctx.Logger.Debug("Character stuck, trying to recover", slog.String("recovery", recovery.String()))
+			switch recovery {
+			case pather.RecoveryReplan:
+				ctx.PathFinder.InvalidatePath()
+			case pather.RecoveryAvoidBlocker:
+				ctx.PathFinder.AvoidBlocker(path)
+			case pather.RecoverySidestep:
+				if !ctx.PathFinder.Sidestep(path) {
+					ctx.PathFinder.RandomMovement()
+				}
+			case pather.RecoveryRandomMovement:
+				ctx.PathFinder.RandomMovement()
+			case pather.RecoveryGiveUp:
+				// Same as the timeout, the caller decides what to do from the position we ended up
+				ctx.Logger.Warn("Character stuck, giving up the movement",
+					slog.Any("position", ctx.Data.PlayerUnit.Position),
+					slog.Any("destination", dest),
+				)
+				return nil
+			}
 			continue
 		}
 
@@ -118,8 +164,15 @@ func MoveTo(dest data.Position) error {
 			minDistanceToFinishMoving = DistanceToFinishMoving
 		}
 
-		previousPosition = ctx.Data.PlayerUnit.Position
 		previousDistance = distance
+
+		// Teleport hops go straight over the walls instead of following the corridors
+		if ctx.Data.CanTeleport() {
+			if hops, _, hopsFound := ctx.PathFinder.GetTeleportPath(dest); hopsFound && ctx.PathFinder.TeleportThroughPath(hops) {
+				continue
+			}
+		}
+
 		ctx.PathFinder.MoveThroughPath(path, walkDuration)
 	}
 }
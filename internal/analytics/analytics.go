@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// RunAnalytics contains the aggregated timing and profitability of every finished run sharing the same name
+type RunAnalytics struct {
+	Name           string
+	Runs           int
+	TotalDuration  time.Duration
+	AvgDuration    time.Duration
+	MedianDuration time.Duration
+	P95Duration    time.Duration
+	Deaths         int
+	Chickens       int
+	Errors         int
+	DeathRate      float64
+	ChickenRate    float64
+	ErrorRate      float64
+	Potions        map[data.PotionType]int
+	PotionsPerRun  float64
+	Drops          int
+	DropsPerHour   float64
+	Rules          []RuleAnalytics
+}
+
+// RuleAnalytics contains the drops matching a single pickit rule
+type RuleAnalytics struct {
+	Rule         string
+	RuleFile     string
+	Drops        int
+	DropsPerHour float64
+}
+
+// Compute aggregates the stats per run name, sorted by name. Runs that are still in progress are ignored.
+func Compute(stats bot.Stats) []RunAnalytics {
+	durations := make(map[string][]time.Duration)
+	results := make(map[string]*RunAnalytics)
+	ruleDrops := make(map[string]map[string]*RuleAnalytics)
+
+	for _, g := range stats.Games {
+		for _, r := range g.Runs {
+			if r.FinishedAt.IsZero() {
+				continue
+			}
+
+			ra, found := results[r.Name]
+			if !found {
+				ra = &RunAnalytics{Name: r.Name, Potions: make(map[data.PotionType]int)}
+				results[r.Name] = ra
+				ruleDrops[r.Name] = make(map[string]*RuleAnalytics)
+			}
+
+			duration := r.FinishedAt.Sub(r.StartedAt)
+			durations[r.Name] = append(durations[r.Name], duration)
+			ra.Runs++
+			ra.TotalDuration += duration
+
+			switch r.Reason {
+			case event.FinishedDied:
+				ra.Deaths++
+			case event.FinishedChicken, event.FinishedMercChicken:
+				ra.Chickens++
+			case event.FinishedError:
+				ra.Errors++
+			}
+
+			for _, p := range r.UsedPotions {
+				ra.Potions[p.PotionType]++
+			}
+
+			for _, d := range r.Drops {
+				ra.Drops++
+				key := d.RuleFile + "|" + d.Rule
+				rd, found := ruleDrops[r.Name][key]
+				if !found {
+					rd = &RuleAnalytics{Rule: d.Rule, RuleFile: d.RuleFile}
+					ruleDrops[r.Name][key] = rd
+				}
+				rd.Drops++
+			}
+		}
+	}
+
+	analytics := make([]RunAnalytics, 0, len(results))
+	for name, ra := range results {
+		runs := float64(ra.Runs)
+		hours := ra.TotalDuration.Hours()
+
+		ra.AvgDuration = ra.TotalDuration / time.Duration(ra.Runs)
+		ra.MedianDuration = percentile(durations[name], 50)
+		ra.P95Duration = percentile(durations[name], 95)
+		ra.DeathRate = float64(ra.Deaths) / runs
+		ra.ChickenRate = float64(ra.Chickens) / runs
+		ra.ErrorRate = float64(ra.Errors) / runs
+
+		totalPotions := 0
+		for _, amount := range ra.Potions {
+			totalPotions += amount
+		}
+		ra.PotionsPerRun = float64(totalPotions) / runs
+
+		if hours > 0 {
+			ra.DropsPerHour = float64(ra.Drops) / hours
+		}
+
+		for _, rd := range ruleDrops[name] {
+			if hours > 0 {
+				rd.DropsPerHour = float64(rd.Drops) / hours
+			}
+			ra.Rules = append(ra.Rules, *rd)
+		}
+		sort.Slice(ra.Rules, func(i, j int) bool {
+			if ra.Rules[i].Drops != ra.Rules[j].Drops {
+				return ra.Rules[i].Drops > ra.Rules[j].Drops
+			}
+			return ra.Rules[i].Rule < ra.Rules[j].Rule
+		})
+
+		analytics = append(analytics, *ra)
+	}
+
+	sort.Slice(analytics, func(i, j int) bool {
+		return analytics[i].Name < analytics[j].Name
+	})
+
+	return analytics
+}
+
+// percentile uses the nearest-rank method, durations don't need to be sorted
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
@@ -0,0 +1,156 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+var start = time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+
+// finishedRun returns a run finished after the given duration, with a drop for every rule
+func finishedRun(name string, duration time.Duration, reason event.FinishReason, rules ...string) bot.RunStats {
+	r := bot.RunStats{Name: name, Reason: reason, StartedAt: start, FinishedAt: start.Add(duration)}
+	for _, rule := range rules {
+		r.Drops = append(r.Drops, data.Drop{Rule: rule, RuleFile: "unique.nip"})
+	}
+
+	return r
+}
+
+func minutes(from, to int) []bot.RunStats {
+	runs := make([]bot.RunStats, 0)
+	for m := from; m <= to; m++ {
+		runs = append(runs, finishedRun("pit", time.Duration(m)*time.Minute, event.FinishedOK))
+	}
+
+	return runs
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     []bot.RunStats
+		expected []RunAnalytics
+	}{
+		{
+			name:     "no runs",
+			runs:     nil,
+			expected: []RunAnalytics{},
+		},
+		{
+			name:     "run in progress is ignored",
+			runs:     []bot.RunStats{{Name: "pit", StartedAt: start}},
+			expected: []RunAnalytics{},
+		},
+		{
+			name: "single run",
+			runs: []bot.RunStats{finishedRun("pit", 3*time.Minute, event.FinishedDied, "[name] == ring")},
+			expected: []RunAnalytics{{
+				Name: "pit", Runs: 1, TotalDuration: 3 * time.Minute,
+				AvgDuration: 3 * time.Minute, MedianDuration: 3 * time.Minute, P95Duration: 3 * time.Minute,
+				Deaths: 1, DeathRate: 1, Drops: 1, DropsPerHour: 20,
+				Rules: []RuleAnalytics{{Rule: "[name] == ring", RuleFile: "unique.nip", Drops: 1, DropsPerHour: 20}},
+			}},
+		},
+		{
+			name: "ten runs",
+			runs: minutes(1, 10),
+			expected: []RunAnalytics{{
+				Name: "pit", Runs: 10, TotalDuration: 55 * time.Minute,
+				AvgDuration: 5*time.Minute + 30*time.Second, MedianDuration: 5 * time.Minute, P95Duration: 10 * time.Minute,
+			}},
+		},
+		{
+			name: "rates and drops per hour",
+			runs: []bot.RunStats{
+				finishedRun("pit", 10*time.Minute, event.FinishedChicken, "[name] == ring", "[name] == amulet"),
+				finishedRun("pit", 20*time.Minute, event.FinishedMercChicken, "[name] == ring"),
+				finishedRun("pit", 15*time.Minute, event.FinishedError),
+				finishedRun("pit", 15*time.Minute, event.FinishedOK, "[name] == ring"),
+			},
+			expected: []RunAnalytics{{
+				Name: "pit", Runs: 4, TotalDuration: time.Hour,
+				AvgDuration: 15 * time.Minute, MedianDuration: 15 * time.Minute, P95Duration: 20 * time.Minute,
+				Chickens: 2, Errors: 1, ChickenRate: 0.5, ErrorRate: 0.25, Drops: 4, DropsPerHour: 4,
+				Rules: []RuleAnalytics{
+					{Rule: "[name] == ring", RuleFile: "unique.nip", Drops: 3, DropsPerHour: 3},
+					{Rule: "[name] == amulet", RuleFile: "unique.nip", Drops: 1, DropsPerHour: 1},
+				},
+			}},
+		},
+		{
+			name: "instant run has no drops per hour",
+			runs: []bot.RunStats{finishedRun("pit", 0, event.FinishedOK, "[name] == ring")},
+			expected: []RunAnalytics{{
+				Name: "pit", Runs: 1, Drops: 1,
+				Rules: []RuleAnalytics{{Rule: "[name] == ring", RuleFile: "unique.nip", Drops: 1}},
+			}},
+		},
+		{
+			name: "sorted by run name",
+			runs: []bot.RunStats{
+				finishedRun("pit", time.Minute, event.FinishedOK),
+				finishedRun("andariel", time.Minute, event.FinishedOK),
+			},
+			expected: []RunAnalytics{
+				{Name: "andariel", Runs: 1, TotalDuration: time.Minute, AvgDuration: time.Minute, MedianDuration: time.Minute, P95Duration: time.Minute},
+				{Name: "pit", Runs: 1, TotalDuration: time.Minute, AvgDuration: time.Minute, MedianDuration: time.Minute, P95Duration: time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(bot.Stats{Games: []bot.GameStats{{StartedAt: start, Runs: tt.runs}}})
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %d runs, got %d", len(tt.expected), len(got))
+			}
+
+			for i, e := range tt.expected {
+				g := got[i]
+				if g.Name != e.Name || g.Runs != e.Runs || g.TotalDuration != e.TotalDuration {
+					t.Errorf("Expected %s with %d runs in %s, got %s with %d runs in %s", e.Name, e.Runs, e.TotalDuration, g.Name, g.Runs, g.TotalDuration)
+				}
+				if g.AvgDuration != e.AvgDuration || g.MedianDuration != e.MedianDuration || g.P95Duration != e.P95Duration {
+					t.Errorf("Expected avg/median/p95 %s/%s/%s, got %s/%s/%s", e.AvgDuration, e.MedianDuration, e.P95Duration, g.AvgDuration, g.MedianDuration, g.P95Duration)
+				}
+				if g.Deaths != e.Deaths || g.Chickens != e.Chickens || g.Errors != e.Errors {
+					t.Errorf("Expected %d deaths, %d chickens and %d errors, got %d, %d and %d", e.Deaths, e.Chickens, e.Errors, g.Deaths, g.Chickens, g.Errors)
+				}
+				if !almostEqual(g.DeathRate, e.DeathRate) || !almostEqual(g.ChickenRate, e.ChickenRate) || !almostEqual(g.ErrorRate, e.ErrorRate) {
+					t.Errorf("Expected death/chicken/error rates %.2f/%.2f/%.2f, got %.2f/%.2f/%.2f", e.DeathRate, e.ChickenRate, e.ErrorRate, g.DeathRate, g.ChickenRate, g.ErrorRate)
+				}
+				if g.Drops != e.Drops || !almostEqual(g.DropsPerHour, e.DropsPerHour) {
+					t.Errorf("Expected %d drops at %.2f per hour, got %d at %.2f", e.Drops, e.DropsPerHour, g.Drops, g.DropsPerHour)
+				}
+				if len(g.Rules) != len(e.Rules) {
+					t.Fatalf("Expected %d rules, got %d", len(e.Rules), len(g.Rules))
+				}
+				for j, r := range e.Rules {
+					if g.Rules[j].Rule != r.Rule || g.Rules[j].Drops != r.Drops || !almostEqual(g.Rules[j].DropsPerHour, r.DropsPerHour) {
+						t.Errorf("Expected rule %s with %d drops at %.2f per hour, got %s with %d at %.2f", r.Rule, r.Drops, r.DropsPerHour, g.Rules[j].Rule, g.Rules[j].Drops, g.Rules[j].DropsPerHour)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPotionsPerRun(t *testing.T) {
+	run := finishedRun("pit", time.Minute, event.FinishedOK)
+	run.UsedPotions = []event.UsedPotionEvent{{PotionType: data.HealingPotion}, {PotionType: data.HealingPotion}, {PotionType: data.ManaPotion}}
+	got := Compute(bot.Stats{Games: []bot.GameStats{{Runs: []bot.RunStats{run, finishedRun("pit", time.Minute, event.FinishedOK)}}}})
+
+	if got[0].Potions[data.HealingPotion] != 2 || got[0].Potions[data.ManaPotion] != 1 || !almostEqual(got[0].PotionsPerRun, 1.5) {
+		t.Errorf("Expected 2 healing and 1 mana potions, 1.5 per run, got %v and %.2f per run", got[0].Potions, got[0].PotionsPerRun)
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
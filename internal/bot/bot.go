@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
@@ -11,24 +12,32 @@ import (
 	botCtx "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/health"
+	"github.com/hectorgimenez/koolo/internal/planner"
 	"github.com/hectorgimenez/koolo/internal/run"
 	"golang.org/x/sync/errgroup"
 )
 
+var errRunTimeBudget = errors.New("run time budget exceeded")
+
 type Bot struct {
-	ctx *botCtx.Context
+	ctx     *botCtx.Context
+	planner *planner.Planner
 }
 
 func NewBot(ctx *botCtx.Context) *Bot {
 	return &Bot{
-		ctx: ctx,
+		ctx:     ctx,
+		planner: planner.New(),
 	}
 }
-func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
+func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Planned) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	g, ctx := errgroup.WithContext(ctx)
 
+	// Deadline of the current run time budget as unix nanoseconds, zero if the run doesn't have a budget
+	var runDeadline atomic.Int64
+
 	gameStartedAt := time.Now()
 	b.ctx.SwitchPriority(botCtx.PriorityNormal) // Restore priority to normal, in case it was stopped in previous game
 	b.ctx.CurrentGame = botCtx.NewGameHelper()  // Reset current game helper structure
@@ -82,6 +91,11 @@ func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
 					b.Stop()
 					return err
 				}
+				// Only the current run is stopped, the game goes on with the next one
+				if deadline := runDeadline.Load(); deadline > 0 && time.Now().UnixNano() > deadline && runDeadline.CompareAndSwap(deadline, 0) {
+					b.ctx.Logger.Warn("Run time budget exceeded, skipping to the next run")
+					b.ctx.CancelRun()
+				}
 				if time.Since(gameStartedAt).Seconds() > float64(b.ctx.CharacterCfg.MaxGameLength) {
 					cancel()
 					b.Stop()
@@ -177,15 +191,25 @@ func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
 		}()
 
 		b.ctx.AttachRoutine(botCtx.PriorityNormal)
-		for _, r := range runs {
-			event.Send(event.RunStarted(event.Text(b.ctx.Name, fmt.Sprintf("Starting run: %s", r.Name())), r.Name()))
+		for i, pr := range runs {
+			r := pr.Run
+			// Loot of the previous run is stashed here, so the run is only started after it
 			err = action.PreRun(firstRun)
 			if err != nil {
 				return err
 			}
+			event.Send(event.RunStarted(event.Text(b.ctx.Name, fmt.Sprintf("Starting run: %s", r.Name())), r.Name()))
 
 			firstRun = false
-			err = r.Run()
+			b.ctx.ResetRunCancel()
+			if pr.Entry.TimeBudget > 0 {
+				runDeadline.Store(time.Now().Add(pr.Entry.TimeBudget).UnixNano())
+			}
+			err = executeRun(r)
+			// The budget can run out while the run is returning, it must not interrupt the next steps
+			runDeadline.Store(0)
+			b.ctx.ResetRunCancel()
+			b.planner.RecordResult(pr.Entry.Run, err)
 
 			var runFinishReason event.FinishReason
 			if err != nil {
@@ -205,11 +229,11 @@ func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
 
 			event.Send(event.RunFinished(event.Text(b.ctx.Name, fmt.Sprintf("Finished run: %s", r.Name())), r.Name(), runFinishReason))
 
-			if err != nil {
+			if err != nil && !errors.Is(err, errRunTimeBudget) {
 				return err
 			}
 
-			err = action.PostRun(r == runs[len(runs)-1])
+			err = action.PostRun(i == len(runs)-1)
 			if err != nil {
 				return err
 			}
@@ -220,6 +244,20 @@ func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
 	return g.Wait()
 }
 
+// executeRun runs r, returning errRunTimeBudget if it was interrupted by CancelRun. Any other panic is propagated.
+func executeRun(r run.Run) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if rec != botCtx.ErrRunCancelled {
+				panic(rec)
+			}
+			err = errRunTimeBudget
+		}
+	}()
+
+	return r.Run()
+}
+
 func (b *Bot) Stop() {
 	b.ctx.SwitchPriority(botCtx.PriorityStop)
 	b.ctx.Detach()
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	botCtx "github.com/hectorgimenez/koolo/internal/context"
+)
+
+type fakeRun func() error
+
+func (r fakeRun) Name() string {
+	return "fake"
+}
+
+func (r fakeRun) Run() error {
+	return r()
+}
+
+func TestExecuteRun(t *testing.T) {
+	errRun := errors.New("run failed")
+	if err := executeRun(fakeRun(func() error { return errRun })); err != errRun {
+		t.Errorf("Expected the run error, got %v", err)
+	}
+
+	cancelled := executeRun(fakeRun(func() error { panic(botCtx.ErrRunCancelled) }))
+	if !errors.Is(cancelled, errRunTimeBudget) {
+		t.Errorf("Expected a cancelled run to exceed the time budget, got %v", cancelled)
+	}
+
+	defer func() {
+		if rec := recover(); rec != "Bot is stopped" {
+			t.Errorf("Expected other panics to be propagated, got %v", rec)
+		}
+	}()
+	executeRun(fakeRun(func() error { panic("Bot is stopped") }))
+}
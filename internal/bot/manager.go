@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
@@ -26,6 +27,9 @@ type SupervisorManager struct {
 	supervisors    map[string]Supervisor
 	crashDetectors map[string]*game.CrashDetector
 	eventListener  *event.Listener
+	statsStoresMu  sync.Mutex
+	statsStores    map[string]*StatsStore
+	unregisterFns  map[string]func()
 }
 
 func NewSupervisorManager(logger *slog.Logger, eventListener *event.Listener) *SupervisorManager {
@@ -34,12 +38,14 @@ func NewSupervisorManager(logger *slog.Logger, eventListener *event.Listener) *S
 		supervisors:    make(map[string]Supervisor),
 		crashDetectors: make(map[string]*game.CrashDetector),
 		eventListener:  eventListener,
+		statsStores:    make(map[string]*StatsStore),
+		unregisterFns:  make(map[string]func()),
 	}
 }
 
 func (mng *SupervisorManager) AvailableSupervisors() []string {
 	availableSupervisors := make([]string, 0)
-	for name := range config.Characters {
+	for name := range config.Characters() {
 		if name != "template" {
 			availableSupervisors = append(availableSupervisors, name)
 		}
@@ -65,6 +71,15 @@ func (mng *SupervisorManager) Start(supervisorName string, attachToExisting bool
 		return err
 	}
 
+	if cfg, found := config.Characters()[supervisorName]; found {
+		if err = cfg.Runtime.Validation.Err(); err != nil {
+			return fmt.Errorf("supervisor %s can not be started: %w", supervisorName, err)
+		}
+		for _, w := range cfg.Runtime.Validation.Warnings {
+			supervisorLogger.Warn("Config warning", slog.String("field", w.Field), slog.String("message", w.Message))
+		}
+	}
+
 	var optionalPID uint32
 	var optionalHWND win.HWND
 
@@ -131,6 +146,12 @@ func (mng *SupervisorManager) Stop(supervisor string) {
 			cd.Stop()
 			delete(mng.crashDetectors, supervisor)
 		}
+
+		// Stop receiving events, otherwise stats would be duplicated after a restart
+		if unregister, ok := mng.unregisterFns[supervisor]; ok {
+			unregister()
+			delete(mng.unregisterFns, supervisor)
+		}
 	}
 }
 
@@ -151,6 +172,34 @@ func (mng *SupervisorManager) Status(characterName string) Stats {
 	return Stats{}
 }
 
+// History returns the stats persisted for the given supervisor (lifetime, per day and per session), it doesn't
+// require the supervisor to be running
+func (mng *SupervisorManager) History(characterName string) (StatsHistory, error) {
+	store, err := mng.statsStore(characterName)
+	if err != nil {
+		return StatsHistory{}, err
+	}
+
+	return store.History()
+}
+
+func (mng *SupervisorManager) statsStore(supervisorName string) (*StatsStore, error) {
+	mng.statsStoresMu.Lock()
+	defer mng.statsStoresMu.Unlock()
+
+	if store, found := mng.statsStores[supervisorName]; found {
+		return store, nil
+	}
+
+	store, err := NewStatsStore(config.Koolo.LogSaveDirectory, supervisorName)
+	if err != nil {
+		return nil, err
+	}
+	mng.statsStores[supervisorName] = store
+
+	return store, nil
+}
+
 func (mng *SupervisorManager) GetData(characterName string) *game.Data {
 	for name, supervisor := range mng.supervisors {
 		if name == characterName {
@@ -172,7 +221,7 @@ func (mng *SupervisorManager) GetContext(characterName string) *context.Context
 }
 
 func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slog.Logger, attach bool, optionalPID uint32, optionalHWND win.HWND) (Supervisor, *game.CrashDetector, error) {
-	cfg, found := config.Characters[supervisorName]
+	cfg, found := config.Characters()[supervisorName]
 	if !found {
 		return nil, nil, fmt.Errorf("character %s not found", supervisorName)
 	}
@@ -208,7 +257,7 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 	ctx := context.NewContext(supervisorName)
 
 	hidM := game.NewHID(gr, gi)
-	pf := pather.NewPathFinder(gr, ctx.Data, hidM, cfg)
+	pf := pather.NewPathFinder(supervisorName, gr, ctx.Data, hidM, cfg)
 
 	bm := health.NewBeltManager(ctx.Data, hidM, logger, supervisorName)
 	hm := health.NewHealthManager(bm, ctx.Data)
@@ -231,8 +280,15 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 
 	bot := NewBot(ctx.Context)
 
-	statsHandler := NewStatsHandler(supervisorName, logger)
-	mng.eventListener.Register(statsHandler.Handle)
+	statsStore, err := mng.statsStore(supervisorName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating stats store: %w", err)
+	}
+
+	statsHandler := NewStatsHandler(supervisorName, logger, statsStore)
+	// Stats can not lose events, the handler only updates memory (the journal is written from its own goroutine), so
+	// it is fast enough to block the dispatch if needed
+	mng.unregisterFns[supervisorName] = mng.eventListener.Register(statsHandler.Handle, event.WithName("stats-"+supervisorName), event.WithOverflowPolicy(event.OverflowBlock))
 
 	var supervisor Supervisor
 
@@ -258,7 +314,7 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 			tokenAuthStarting := false
 
 			// Get the current supervisor's config
-			supCfg := config.Characters[supervisorName]
+			supCfg := config.Characters()[supervisorName]
 
 			for _, sup := range supervisorList {
 
@@ -274,7 +330,7 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 						break
 					}
 
-					sCfg, found := config.Characters[sup]
+					sCfg, found := config.Characters()[sup]
 					if found {
 						if sCfg.AuthMethod == "TokenAuth" {
 							// A client that uses token auth is currently starting, hold off restart
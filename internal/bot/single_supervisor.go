@@ -5,11 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data/skill"
-	"github.com/hectorgimenez/koolo/internal/config"
 	ct "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
@@ -45,11 +43,14 @@ func NewSinglePlayerSupervisor(name string, bot *Bot, statsHandler *StatsHandler
 func (s *SinglePlayerSupervisor) Start() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancelFn = cancel
+	// Stops watching the config when the supervisor can't continue
+	defer cancel()
 
 	err := s.ensureProcessIsRunningAndPrepare()
 	if err != nil {
 		return fmt.Errorf("error preparing game: %w", err)
 	}
+	go s.watchConfig(ctx)
 
 	firstRun := true
 	for {
@@ -64,6 +65,9 @@ func (s *SinglePlayerSupervisor) Start() error {
 				}
 			}
 
+			// The game is created with the reloaded config, the difficulty may have changed
+			s.applyConfigReload()
+
 			// By this point, we should be in the character selection screen.
 			if !s.bot.ctx.Manager.InGame() {
 				// Create the game
@@ -79,18 +83,24 @@ func (s *SinglePlayerSupervisor) Start() error {
 				}
 			}
 
-			runs := run.BuildRuns(s.bot.ctx.CharacterCfg)
-			gameStart := time.Now()
-			if config.Characters[s.name].Game.RandomizeRuns {
-				rand.Shuffle(len(runs), func(i, j int) { runs[i], runs[j] = runs[j], runs[i] })
+			// Map data is fetched before planning, so the runs are planned with the layout rolled for this seed. The game
+			// fetches it again if it fails here.
+			if err = s.bot.ctx.GameReader.FetchMapData(); err != nil {
+				s.bot.ctx.Logger.Warn("Map data could not be fetched before planning the runs", slog.Any("error", err))
 			}
-			event.Send(event.GameCreated(event.Text(s.name, "New game created"), "", ""))
-			s.bot.ctx.LastBuffAt = time.Time{}
-			s.logGameStart(runs)
 
-			// Refresh game data to make sure we have the latest information
+			// Refresh game data to make sure we have the latest information, runs are planned based on it
 			s.bot.ctx.RefreshGameData()
 
+			plan := s.bot.planner.Next(*s.bot.ctx.Data)
+			runs := run.BuildRuns(plan)
+			gameStart := time.Now()
+			event.Send(event.GameCreated(event.Text(s.name, "New game created"), "", ""))
+			s.bot.ctx.LastBuffAt = time.Time{}
+			// Danger zones are positions of the previous game maps
+			s.bot.ctx.PathFinder.ClearDangerZones()
+			s.logGameStart(plan, runs)
+
 			// Perform keybindings check on the first run only
 			if firstRun {
 				missingKeybindings := s.bot.ctx.Char.CheckKeyBindings()
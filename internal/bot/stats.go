@@ -2,8 +2,11 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
@@ -18,23 +21,42 @@ const (
 	Crashed    SupervisorStatus = "Crashed"
 )
 
+// journalQueueSize is how many records can be waiting to be written to the stats journal, the next ones wait for room
+const journalQueueSize = 64
+
 type SupervisorStatus string
 
+// StatsHandler keeps the stats of the current session in memory and journals the finished games and the drops. Events
+// are handled from the listener goroutine while Stats is called from the supervisor and the server, so both are
+// guarded by mu. Journal writes are queued and written from their own goroutine until the handler is closed, since
+// the handler can block the event dispatch.
 type StatsHandler struct {
+	mu     sync.Mutex
 	stats  *Stats
 	name   string
 	logger *slog.Logger
+	store  *StatsStore
+
+	journalQueue  chan func() error
+	journalClosed bool
+	journalWG     sync.WaitGroup
 }
 
-func NewStatsHandler(name string, logger *slog.Logger) *StatsHandler {
-	return &StatsHandler{
+func NewStatsHandler(name string, logger *slog.Logger, store *StatsStore) *StatsHandler {
+	h := &StatsHandler{
 		name:   name,
 		logger: logger,
+		store:  store,
 		stats: &Stats{
 			SupervisorStatus: Starting,
 			StartedAt:        time.Now(),
 		},
+		journalQueue: make(chan func() error, journalQueueSize),
 	}
+	h.journalWG.Add(1)
+	go h.writeJournal()
+
+	return h
 }
 
 func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
@@ -43,6 +65,9 @@ func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
 		return nil
 	}
 
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	switch evt := e.(type) {
 	case event.GameCreatedEvent:
 		h.stats.Games = append(h.stats.Games, GameStats{
@@ -54,6 +79,7 @@ func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
 		if len(h.stats.Games) > 0 {
 			h.stats.Games[len(h.stats.Games)-1].FinishedAt = evt.OccurredAt()
 			h.stats.Games[len(h.stats.Games)-1].Reason = evt.Reason
+			h.persistGame(h.stats.Games[len(h.stats.Games)-1].clone())
 		}
 
 	case event.RunStartedEvent:
@@ -80,6 +106,16 @@ func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
 
 	case event.ItemStashedEvent:
 		h.stats.Drops = append(h.stats.Drops, evt.Item)
+		h.creditDrop(evt.Item)
+		if h.store != nil {
+			sessionStartedAt := h.stats.StartedAt
+			h.journal(func() error {
+				if err := h.store.appendDrop(sessionStartedAt, evt.OccurredAt(), evt.Item); err != nil {
+					return fmt.Errorf("error saving drop to stats journal: %w", err)
+				}
+				return nil
+			})
+		}
 
 	case event.UsedPotionEvent:
 		if len(h.stats.Games) > 0 && len(h.stats.Games[len(h.stats.Games)-1].Runs) > 0 {
@@ -91,8 +127,82 @@ func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
 	return nil
 }
 
+// creditDrop adds the drop to the run it was picked up in. Items are stashed in town, while returning to town during
+// the run or before starting the next one, so that's the last run started. Loot of the last run of a game is stashed
+// in the next game, before its first run, then the finished game is journaled again with the drop.
+func (h *StatsHandler) creditDrop(d data.Drop) {
+	for i := len(h.stats.Games) - 1; i >= 0; i-- {
+		g := &h.stats.Games[i]
+		if len(g.Runs) == 0 {
+			continue
+		}
+
+		lastRun := &g.Runs[len(g.Runs)-1]
+		lastRun.Drops = append(lastRun.Drops, d)
+		if !g.FinishedAt.IsZero() {
+			h.persistGame(g.clone())
+		}
+		return
+	}
+}
+
+func (h *StatsHandler) persistGame(g GameStats) {
+	if h.store == nil {
+		return
+	}
+
+	sessionStartedAt := h.stats.StartedAt
+	h.journal(func() error {
+		if err := h.store.appendGame(sessionStartedAt, g); err != nil {
+			return fmt.Errorf("error saving game to stats journal: %w", err)
+		}
+		return nil
+	})
+}
+
+// journal queues a write to the stats journal, waiting for room when the queue is full. Once the handler is closed
+// the record is written right away, events still pending when the supervisor stops are not lost. It's called with mu
+// held.
+func (h *StatsHandler) journal(write func() error) {
+	if !h.journalClosed {
+		h.journalQueue <- write
+		return
+	}
+
+	if err := write(); err != nil {
+		h.logger.Error(err.Error())
+	}
+}
+
+// writeJournal writes the queued records until the handler is closed
+func (h *StatsHandler) writeJournal() {
+	defer h.journalWG.Done()
+
+	for write := range h.journalQueue {
+		if err := write(); err != nil {
+			h.logger.Error(err.Error())
+		}
+	}
+}
+
+// Close writes the records still queued and stops the journal writer, it's called when the supervisor stops
+func (h *StatsHandler) Close() {
+	h.mu.Lock()
+	if !h.journalClosed {
+		h.journalClosed = true
+		close(h.journalQueue)
+	}
+	h.mu.Unlock()
+
+	h.journalWG.Wait()
+}
+
+// Stats returns a copy of the current stats, safe to be used while events keep being handled
 func (h *StatsHandler) Stats() Stats {
-	return *h.stats
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.stats.clone()
 }
 
 type Stats struct {
@@ -114,11 +224,38 @@ type RunStats struct {
 	Name        string
 	Reason      event.FinishReason
 	StartedAt   time.Time
-	Items       []data.Item
+	Drops       []data.Drop
 	FinishedAt  time.Time
 	UsedPotions []event.UsedPotionEvent
 }
 
+func (s Stats) clone() Stats {
+	c := s
+	c.Drops = slices.Clone(s.Drops)
+	if s.Games != nil {
+		c.Games = make([]GameStats, len(s.Games))
+		for i, g := range s.Games {
+			c.Games[i] = g.clone()
+		}
+	}
+
+	return c
+}
+
+func (g GameStats) clone() GameStats {
+	c := g
+	if g.Runs != nil {
+		c.Runs = make([]RunStats, len(g.Runs))
+		for i, r := range g.Runs {
+			c.Runs[i] = r
+			c.Runs[i].Drops = slices.Clone(r.Drops)
+			c.Runs[i].UsedPotions = slices.Clone(r.UsedPotions)
+		}
+	}
+
+	return c
+}
+
 func (s Stats) TotalGames() int {
 	return len(s.Games)
 }
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+)
+
+const (
+	statsRecordGame statsRecordType = "game"
+	statsRecordDrop statsRecordType = "drop"
+
+	statsDayLayout = "2006-01-02"
+)
+
+type statsRecordType string
+
+// statsRecord is a single line of the stats journal
+type statsRecord struct {
+	Type    statsRecordType `json:"type"`
+	Session time.Time       `json:"session"`
+	At      time.Time       `json:"at"`
+	Game    *GameStats      `json:"game,omitempty"`
+	Drop    *data.Drop      `json:"drop,omitempty"`
+}
+
+// StatsStore is an append-only JSONL journal keeping the finished games and drops of a supervisor across restarts
+type StatsStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// StatsHistory contains the supervisor stats rebuilt from the journal, grouped by lifetime, day and session
+type StatsHistory struct {
+	Lifetime Stats
+	Days     map[string]Stats
+	Sessions []Stats
+}
+
+func NewStatsStore(logDir, supervisor string) (*StatsStore, error) {
+	if logDir == "" {
+		logDir = "logs"
+	}
+
+	dir := filepath.Join(logDir, "stats")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating stats directory: %w", err)
+	}
+
+	return &StatsStore{path: filepath.Join(dir, supervisor+".jsonl")}, nil
+}
+
+func (s *StatsStore) appendGame(session time.Time, g GameStats) error {
+	return s.append(statsRecord{Type: statsRecordGame, Session: session, At: g.StartedAt, Game: &g})
+}
+
+func (s *StatsStore) appendDrop(session time.Time, at time.Time, d data.Drop) error {
+	return s.append(statsRecord{Type: statsRecordDrop, Session: session, At: at, Drop: &d})
+}
+
+func (s *StatsStore) append(rec statsRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("error encoding stats record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening stats journal: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+
+	return err
+}
+
+func (s *StatsStore) records() ([]statsRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening stats journal: %w", err)
+	}
+	defer f.Close()
+
+	records := make([]statsRecord, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var rec statsRecord
+		// A line truncated by a crash while writing is not fatal, just skip it
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	return records, scanner.Err()
+}
+
+// History rebuilds the lifetime, per day and per session stats from the journal
+func (s *StatsStore) History() (StatsHistory, error) {
+	records, err := s.records()
+	if err != nil {
+		return StatsHistory{}, err
+	}
+
+	// The same game can be journaled more than once (e.g. error exiting the game), last record wins
+	games := make(map[time.Time]int)
+	history := StatsHistory{Days: make(map[string]Stats)}
+	sessions := make(map[time.Time]*Stats)
+	var orderedRecords []statsRecord
+	for _, rec := range records {
+		if rec.Type == statsRecordGame {
+			if rec.Game == nil {
+				continue
+			}
+			if idx, found := games[rec.Game.StartedAt]; found {
+				orderedRecords[idx] = rec
+				continue
+			}
+			games[rec.Game.StartedAt] = len(orderedRecords)
+		}
+		orderedRecords = append(orderedRecords, rec)
+	}
+
+	addTo := func(st *Stats, rec statsRecord) {
+		if st.StartedAt.IsZero() || rec.Session.Before(st.StartedAt) {
+			st.StartedAt = rec.Session
+		}
+		switch rec.Type {
+		case statsRecordGame:
+			st.Games = append(st.Games, *rec.Game)
+		case statsRecordDrop:
+			if rec.Drop != nil {
+				st.Drops = append(st.Drops, *rec.Drop)
+			}
+		}
+	}
+
+	history.Lifetime.SupervisorStatus = NotStarted
+	for _, rec := range orderedRecords {
+		addTo(&history.Lifetime, rec)
+
+		day := rec.At.Local().Format(statsDayLayout)
+		dayStats := history.Days[day]
+		dayStats.SupervisorStatus = NotStarted
+		addTo(&dayStats, rec)
+		history.Days[day] = dayStats
+
+		session, found := sessions[rec.Session]
+		if !found {
+			session = &Stats{SupervisorStatus: NotStarted}
+			sessions[rec.Session] = session
+		}
+		addTo(session, rec)
+	}
+
+	for _, st := range sessions {
+		history.Sessions = append(history.Sessions, *st)
+	}
+	sort.Slice(history.Sessions, func(i, j int) bool {
+		return history.Sessions[i].StartedAt.Before(history.Sessions[j].StartedAt)
+	})
+
+	return history, nil
+}
+
+// SortedDays returns the days containing stats, most recent first
+func (h StatsHistory) SortedDays() []string {
+	days := make([]string, 0, len(h.Days))
+	for day := range h.Days {
+		days = append(days, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	return days
+}
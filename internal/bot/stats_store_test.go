@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func TestStatsJournal(t *testing.T) {
+	store, err := NewStatsStore(t.TempDir(), "sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two sessions, the first one spanning midnight
+	firstSession := time.Date(2024, 12, 1, 22, 0, 0, 0, time.Local)
+	secondSession := time.Date(2024, 12, 3, 10, 0, 0, 0, time.Local)
+	games := []struct {
+		session time.Time
+		game    GameStats
+	}{
+		{firstSession, GameStats{StartedAt: firstSession.Add(time.Hour), Reason: event.FinishedOK}},
+		{firstSession, GameStats{StartedAt: firstSession.Add(3 * time.Hour), Reason: event.FinishedError}},
+		{secondSession, GameStats{StartedAt: secondSession.Add(time.Minute), Reason: event.FinishedOK}},
+		// Same game journaled again, it replaces the previous record
+		{firstSession, GameStats{StartedAt: firstSession.Add(3 * time.Hour), Reason: event.FinishedChicken}},
+	}
+	for _, g := range games {
+		if err = store.appendGame(g.session, g.game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = store.appendDrop(secondSession, secondSession.Add(2*time.Minute), data.Drop{Rule: "[type] == ring"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A line half written by a crash is skipped
+	f, err := os.OpenFile(store.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"game","sess`)
+	f.Close()
+
+	history, err := store.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if history.Lifetime.TotalGames() != 3 || len(history.Lifetime.Drops) != 1 {
+		t.Errorf("Expected 3 games and 1 drop in lifetime stats, got %d games and %d drops", history.Lifetime.TotalGames(), len(history.Lifetime.Drops))
+	}
+	if !history.Lifetime.StartedAt.Equal(firstSession) {
+		t.Errorf("Expected lifetime stats to start with the first session, got %s", history.Lifetime.StartedAt)
+	}
+	if reason := history.Lifetime.Games[1].Reason; reason != event.FinishedChicken {
+		t.Errorf("Expected the last record of a game to win, got %s", reason)
+	}
+
+	expectedDays := map[string]int{"2024-12-01": 1, "2024-12-02": 1, "2024-12-03": 1}
+	if len(history.Days) != len(expectedDays) {
+		t.Errorf("Expected %d days, got %v", len(expectedDays), history.SortedDays())
+	}
+	for day, games := range expectedDays {
+		if got := history.Days[day].TotalGames(); got != games {
+			t.Errorf("Expected %d games on %s, got %d", games, day, got)
+		}
+	}
+	if days := history.SortedDays(); days[0] != "2024-12-03" {
+		t.Errorf("Expected most recent day first, got %v", days)
+	}
+	if drops := len(history.Days["2024-12-03"].Drops); drops != 1 {
+		t.Errorf("Expected the drop to be in its day, got %d drops", drops)
+	}
+
+	if len(history.Sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(history.Sessions))
+	}
+	if !history.Sessions[0].StartedAt.Equal(firstSession) || history.Sessions[0].TotalGames() != 2 {
+		t.Errorf("Expected first session with 2 games, got %d games started at %s", history.Sessions[0].TotalGames(), history.Sessions[0].StartedAt)
+	}
+	if !history.Sessions[1].StartedAt.Equal(secondSession) || len(history.Sessions[1].Drops) != 1 {
+		t.Errorf("Expected second session with the drop, got %d drops started at %s", len(history.Sessions[1].Drops), history.Sessions[1].StartedAt)
+	}
+}
+
+func TestStatsJournalWithoutRecords(t *testing.T) {
+	store, err := NewStatsStore(t.TempDir(), "sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := store.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Lifetime.TotalGames() != 0 || len(history.Days) != 0 || len(history.Sessions) != 0 {
+		t.Errorf("Expected empty history without a journal, got %+v", history)
+	}
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func newTestStatsHandler(t *testing.T) (*StatsHandler, *StatsStore) {
+	t.Helper()
+
+	store, err := NewStatsStore(t.TempDir(), "sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewStatsHandler("sorc", slog.New(slog.NewTextHandler(io.Discard, nil)), store), store
+}
+
+func TestStatsHandler(t *testing.T) {
+	h, store := newTestStatsHandler(t)
+	be := event.Text("sorc", "")
+	events := []event.Event{
+		event.GameCreated(be, "game-1", ""),
+		event.RunStarted(be, "pit"),
+		event.UsedPotion(be, data.HealingPotion, false),
+		event.RunFinished(be, "pit", event.FinishedOK),
+		event.ItemStashed(be, data.Drop{Rule: "[type] == ring"}, data.Position{}),
+		event.GameFinished(be, event.FinishedOK),
+		// Events from other supervisors are ignored
+		event.GameCreated(event.Text("paladin", ""), "game-2", ""),
+	}
+	for _, e := range events {
+		h.Handle(context.Background(), e)
+	}
+
+	stats := h.Stats()
+	if stats.TotalGames() != 1 || len(stats.Drops) != 1 || stats.SupervisorStatus != InGame {
+		t.Fatalf("Expected 1 game and 1 drop in game, got %d games, %d drops and status %s", stats.TotalGames(), len(stats.Drops), stats.SupervisorStatus)
+	}
+	if run := stats.Games[0].Runs[0]; run.Name != "pit" || len(run.Drops) != 1 || len(run.UsedPotions) != 1 {
+		t.Errorf("Expected the drop and the potion in the pit run, got %+v", run)
+	}
+
+	// The copy doesn't change when more events are handled, and changing it doesn't change the handler stats
+	h.Handle(context.Background(), event.ItemStashed(be, data.Drop{Rule: "[type] == amulet"}, data.Position{}))
+	stats.Games[0].Runs[0].Name = "changed"
+	if len(stats.Drops) != 1 || len(stats.Games[0].Runs[0].Drops) != 1 {
+		t.Errorf("Expected stats copy not to change, got %d drops", len(stats.Drops))
+	}
+	if name := h.Stats().Games[0].Runs[0].Name; name != "pit" {
+		t.Errorf("Expected handler stats not to change, got run %s", name)
+	}
+
+	h.Close()
+	history, err := store.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Lifetime.TotalGames() != 1 || len(history.Lifetime.Drops) != 2 {
+		t.Errorf("Expected the game and the drops to be journaled, got %d games and %d drops", history.Lifetime.TotalGames(), len(history.Lifetime.Drops))
+	}
+}
+
+func TestStatsHandlerConcurrentReads(t *testing.T) {
+	h, _ := newTestStatsHandler(t)
+	be := event.Text("sorc", "")
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			h.Handle(context.Background(), event.GameCreated(be, "game", ""))
+			h.Handle(context.Background(), event.RunStarted(be, "pit"))
+			h.Handle(context.Background(), event.ItemStashed(be, data.Drop{}, data.Position{}))
+			h.Handle(context.Background(), event.GameFinished(be, event.FinishedOK))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		stats := h.Stats()
+		_ = stats.TotalGames() + len(stats.Drops)
+	}
+	wg.Wait()
+	h.Close()
+
+	if games := h.Stats().TotalGames(); games != 100 {
+		t.Errorf("Expected 100 games, got %d", games)
+	}
+}
+
+func TestStatsJournalIsFlushedOnClose(t *testing.T) {
+	h, store := newTestStatsHandler(t)
+	be := event.Text("sorc", "")
+
+	// More records than the queue fits, the handler waits for room instead of dropping them
+	for i := 0; i < journalQueueSize*2; i++ {
+		h.Handle(context.Background(), event.ItemStashed(be, data.Drop{}, data.Position{}))
+	}
+	h.Close()
+	// Events delivered after closing are written right away
+	h.Handle(context.Background(), event.ItemStashed(be, data.Drop{}, data.Position{}))
+	h.Close()
+
+	history, err := store.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drops := len(history.Lifetime.Drops); drops != journalQueueSize*2+1 {
+		t.Errorf("Expected %d drops to be journaled, got %d", journalQueueSize*2+1, drops)
+	}
+}
+
+func TestStatsDropsCreditedToPreviousRun(t *testing.T) {
+	h, store := newTestStatsHandler(t)
+	be := event.Text("sorc", "")
+	events := []event.Event{
+		event.GameCreated(be, "game-1", ""),
+		event.RunStarted(be, "countess"),
+		event.RunFinished(be, "countess", event.FinishedOK),
+		// Countess loot, stashed before starting the next run
+		event.ItemStashed(be, data.Drop{Rule: "[type] == rune"}, data.Position{}),
+		event.RunStarted(be, "pit"),
+		event.RunFinished(be, "pit", event.FinishedOK),
+		event.GameFinished(be, event.FinishedOK),
+		// Pit loot, stashed in the next game before its first run
+		event.GameCreated(be, "game-2", ""),
+		event.ItemStashed(be, data.Drop{Rule: "[type] == ring"}, data.Position{}),
+		event.RunStarted(be, "countess"),
+	}
+	for _, e := range events {
+		h.Handle(context.Background(), e)
+	}
+
+	stats := h.Stats()
+	runs := stats.Games[0].Runs
+	if len(runs[0].Drops) != 1 || runs[0].Drops[0].Rule != "[type] == rune" {
+		t.Errorf("Expected the rune to be credited to countess, got %v", runs[0].Drops)
+	}
+	if len(runs[1].Drops) != 1 || runs[1].Drops[0].Rule != "[type] == ring" {
+		t.Errorf("Expected the ring to be credited to pit, got %v", runs[1].Drops)
+	}
+	if drops := stats.Games[1].Runs[0].Drops; len(drops) != 0 {
+		t.Errorf("Expected no drops in the next game run, got %v", drops)
+	}
+
+	h.Close()
+	history, err := store.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if games := history.Lifetime.Games; len(games) != 1 || len(games[0].Runs[1].Drops) != 1 {
+		t.Errorf("Expected the finished game to be journaled again with the pit drop, got %+v", games)
+	}
+}
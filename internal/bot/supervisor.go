@@ -6,11 +6,14 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/hectorgimenez/koolo/internal/config"
 	ct "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/planner"
 	"github.com/hectorgimenez/koolo/internal/run"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/lxn/win"
@@ -32,6 +35,8 @@ type baseSupervisor struct {
 	name         string
 	statsHandler *StatsHandler
 	cancelFn     context.CancelFunc
+	// pendingCfg is the config reloaded from disk waiting to be applied before the next game
+	pendingCfg atomic.Pointer[config.CharacterCfg]
 }
 
 func newBaseSupervisor(
@@ -68,6 +73,47 @@ func (s *baseSupervisor) TogglePause() {
 	}
 }
 
+// watchConfig reloads the config when its files change until the context is cancelled, valid configs are applied
+// before the next game
+func (s *baseSupervisor) watchConfig(ctx context.Context) {
+	w := config.NewWatcher(config.DefaultLoader(), s.name, s.bot.ctx.CharacterCfg, config.DefaultWatchInterval, func(cfg *config.CharacterCfg, err error) {
+		if err != nil {
+			s.rejectConfigReload(err.Error())
+			return
+		}
+
+		s.bot.ctx.Logger.Info("Config changed, it will be applied before the next game")
+		s.pendingCfg.Store(cfg)
+	})
+	w.Start(ctx)
+}
+
+// applyConfigReload swaps the reloaded config in, it's called between games so the config never changes while a run
+// is executed
+func (s *baseSupervisor) applyConfigReload() {
+	cfg := s.pendingCfg.Swap(nil)
+	if cfg == nil {
+		return
+	}
+
+	// The character is built for the class when the supervisor starts
+	if current := s.bot.ctx.CharacterCfg.Character.Class; cfg.Character.Class != current {
+		s.rejectConfigReload(fmt.Sprintf("class changed from %s to %s, restart the supervisor to apply it", current, cfg.Character.Class))
+		return
+	}
+
+	s.bot.ctx.SetCharacterCfg(cfg)
+	// The game manager, the UI and the API read the config from there
+	config.SetCharacter(s.name, cfg)
+	s.bot.ctx.Logger.Info("Config reloaded", slog.Int("pickitRules", len(cfg.Runtime.Rules)), slog.Int("warnings", len(cfg.Runtime.Validation.Warnings)))
+	event.Send(event.ConfigReloaded(event.Text(s.name, "Config reloaded"), true, nil))
+}
+
+func (s *baseSupervisor) rejectConfigReload(reason string) {
+	s.bot.ctx.Logger.Warn("Config changes rejected, keeping the previous config", slog.String("reason", reason))
+	event.Send(event.ConfigReloaded(event.Text(s.name, "Config changes rejected: "+reason), false, strings.Split(reason, "\n")))
+}
+
 func (s *baseSupervisor) Stop() {
 	s.bot.ctx.Logger.Info("Stopping...", slog.String("configuration", s.name))
 	if s.cancelFn != nil {
@@ -83,6 +129,9 @@ func (s *baseSupervisor) Stop() {
 		s.KillClient()
 	}
 
+	// The stats journal is flushed, events handled from now on are written right away
+	s.statsHandler.Close()
+
 	s.bot.ctx.Logger.Info("Finished stopping", slog.String("configuration", s.name))
 }
 
@@ -108,12 +157,27 @@ func (s *baseSupervisor) ensureProcessIsRunningAndPrepare() error {
 	return s.bot.ctx.MemoryInjector.Load()
 }
 
-func (s *baseSupervisor) logGameStart(runs []run.Run) {
-	runNames := ""
+func (s *baseSupervisor) logGameStart(plan planner.Plan, runs []run.Planned) {
+	runNames := make([]string, 0, len(runs))
 	for _, r := range runs {
-		runNames += r.Name() + ", "
+		runNames = append(runNames, r.Run.Name())
+	}
+	s.bot.ctx.Logger.Info(fmt.Sprintf("Starting Game #%d. Run list: %s", s.statsHandler.Stats().TotalGames(), strings.Join(runNames, ", ")))
+
+	for _, d := range plan.Decisions {
+		if !d.Selected {
+			s.bot.ctx.Logger.Debug(fmt.Sprintf("Skipping run %s: %s", d.Run, d.Reason))
+		}
+	}
+
+	for r, layout := range plan.Layouts {
+		s.bot.ctx.Logger.Info(
+			fmt.Sprintf("Run %s layout analyzed", r),
+			slog.Int("distance", layout.Distance),
+			slog.Duration("estimated", layout.Estimated),
+			slog.String("variant", layout.Variant),
+		)
 	}
-	s.bot.ctx.Logger.Info(fmt.Sprintf("Starting Game #%d. Run list: %s", s.statsHandler.Stats().TotalGames(), runNames[:len(runNames)-2]))
 }
 
 func (s *baseSupervisor) waitUntilCharacterSelectionScreen() error {
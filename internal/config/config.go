@@ -1,31 +1,28 @@
 package config
 
 import (
-	"errors"
-	"fmt"
-	"path/filepath"
+	"maps"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 
-	"os"
 	"strings"
 
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
 	"github.com/hectorgimenez/d2go/pkg/data/item"
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
-	cp "github.com/otiai10/copy"
 
 	"github.com/hectorgimenez/d2go/pkg/nip"
-
-	"gopkg.in/yaml.v3"
 )
 
 var (
-	Koolo      *KooloCfg
-	Characters map[string]*CharacterCfg
-	Version    = "dev"
+	Koolo   *KooloCfg
+	Version = "dev"
+
+	// characters is replaced as a whole, the config watchers reload it while the HTTP server and the supervisors read it
+	characters atomic.Pointer[map[string]*CharacterCfg]
 )
 
 type KooloCfg struct {
@@ -40,7 +37,12 @@ type KooloCfg struct {
 	LogSaveDirectory      string `yaml:"logSaveDirectory"`
 	D2LoDPath             string `yaml:"D2LoDPath"`
 	D2RPath               string `yaml:"D2RPath"`
-	Discord               struct {
+	Server                struct {
+		BindAddress string `yaml:"bindAddress"`
+		Password    string `yaml:"password"`
+		Token       string `yaml:"token"`
+	} `yaml:"server"`
+	Discord struct {
 		Enabled                      bool     `yaml:"enabled"`
 		EnableGameCreatedMessages    bool     `yaml:"enableGameCreatedMessages"`
 		EnableNewRunMessages         bool     `yaml:"enableNewRunMessages"`
@@ -51,10 +53,70 @@ type KooloCfg struct {
 		Token                        string   `yaml:"token"`
 	} `yaml:"discord"`
 	Telegram struct {
-		Enabled bool   `yaml:"enabled"`
-		ChatID  int64  `yaml:"chatId"`
-		Token   string `yaml:"token"`
+		Enabled   bool    `yaml:"enabled"`
+		ChatID    int64   `yaml:"chatId"`
+		Token     string  `yaml:"token"`
+		BotAdmins []int64 `yaml:"botAdmins"`
 	}
+	Webhook struct {
+		Enabled            bool     `yaml:"enabled"`
+		URL                string   `yaml:"url"`
+		Secret             string   `yaml:"secret"`
+		Events             []string `yaml:"events"`
+		MaxRetries         *int     `yaml:"maxRetries,omitempty"`
+		IncludeScreenshots bool     `yaml:"includeScreenshots"`
+	} `yaml:"webhook"`
+	DropNotifications struct {
+		Enabled           bool     `yaml:"enabled"`
+		Qualities         []string `yaml:"qualities"`
+		Rules             []string `yaml:"rules"`
+		IncludeScreenshot bool     `yaml:"includeScreenshot"`
+	} `yaml:"dropNotifications"`
+	Runtime struct {
+		// SecretRefs are the vault references the secrets were loaded from, by field
+		SecretRefs map[string]string `yaml:"-"`
+		// UnavailableSecrets are the errors of the secrets that couldn't be read from the vault, by field
+		UnavailableSecrets map[string]string `yaml:"-"`
+	} `yaml:"-"`
+}
+
+// RunConditions restricts when a run from the run list is executed, zero values disable each condition
+type RunConditions struct {
+	// TerrorZones only executes the run when any of these areas is terrorized
+	TerrorZones []area.ID `yaml:"terrorZones"`
+	// MinLevel and MaxLevel only execute the run when MinLevel <= character level < MaxLevel
+	MinLevel int `yaml:"minLevel"`
+	MaxLevel int `yaml:"maxLevel"`
+	// EveryNthGame executes the run on the first game and then once every N games
+	EveryNthGame int `yaml:"everyNthGame"`
+	// SkipAfterFailures skips the run after failing N times in a row, until FailureCooldownGames games are played or
+	// the supervisor is restarted if it's zero
+	SkipAfterFailures    int `yaml:"skipAfterFailures"`
+	FailureCooldownGames int `yaml:"failureCooldownGames"`
+	// TimeBudget stops the run when it takes longer than this, the game goes on with the next planned run
+	TimeBudget time.Duration `yaml:"timeBudget"`
+	// MaxLayoutDistance skips the run when the walking distance to its key locations, in tiles, is longer than this in
+	// the map rolled for the game. Only countess, summoner, duriel, diablo and ancient_tunnels are analyzed.
+	MaxLayoutDistance int `yaml:"maxLayoutDistance"`
+}
+
+// PathCosts are the weights used to calculate walking paths, zero values use the defaults: the base costs of the
+// collision types for Monster, Object and WallProximity, and no extra cost for the rest
+type PathCosts struct {
+	// Monster, Object and WallProximity are the costs of walking through a tile with a monster, an object, or close
+	// to a wall or an object
+	Monster       int `yaml:"monster"`
+	Object        int `yaml:"object"`
+	WallProximity int `yaml:"wallProximity"`
+	// MonsterDensity is added to the tiles around every monster, so crowded zones are avoided
+	MonsterDensity int `yaml:"monsterDensity"`
+	// EliteProximity is added to the tiles around elites and bosses, higher the closer, to keep away from aura packs
+	EliteProximity int `yaml:"eliteProximity"`
+	// DangerZone is added to the tiles inside the danger zones registered by the runs
+	DangerZone int `yaml:"dangerZone"`
+	// MonsterFootprint is the amount of tiles around every monster also costing as a monster tile, 0 only uses the
+	// monster position
+	MonsterFootprint int `yaml:"monsterFootprint"`
 }
 
 type Day struct {
@@ -73,6 +135,10 @@ type TimeRange struct {
 }
 
 type CharacterCfg struct {
+	// Profiles are the shared profiles from config/profiles merged in order before this config, the values set here
+	// override the ones from the profiles
+	Profiles []string `yaml:"profiles,omitempty"`
+
 	MaxGameLength   int    `yaml:"maxGameLength"`
 	Username        string `yaml:"username"`
 	Password        string `yaml:"password"`
@@ -101,10 +167,11 @@ type CharacterCfg struct {
 		BeltColumns   BeltColumns `yaml:"beltColumns"`
 	} `yaml:"inventory"`
 	Character struct {
-		Class         string `yaml:"class"`
-		UseMerc       bool   `yaml:"useMerc"`
-		StashToShared bool   `yaml:"stashToShared"`
-		UseTeleport   bool   `yaml:"useTeleport"`
+		Class         string    `yaml:"class"`
+		UseMerc       bool      `yaml:"useMerc"`
+		StashToShared bool      `yaml:"stashToShared"`
+		UseTeleport   bool      `yaml:"useTeleport"`
+		PathCosts     PathCosts `yaml:"pathCosts"`
 		BerserkerBarb struct {
 			FindItemSwitch              bool `yaml:"find_item_switch"`
 			SkipPotionPickupInTravincal bool `yaml:"skip_potion_pickup_in_travincal"`
@@ -120,9 +187,16 @@ type CharacterCfg struct {
 		Difficulty             difficulty.Difficulty `yaml:"difficulty"`
 		RandomizeRuns          bool                  `yaml:"randomizeRuns"`
 		Runs                   []Run                 `yaml:"runs"`
+		RunConditions          map[Run]RunConditions `yaml:"runConditions"`
 		CreateLobbyGames       bool                  `yaml:"createLobbyGames"`
-		PublicGameCounter      int                   `yaml:"-"`
-		Pindleskin             struct {
+		RoomTour               struct {
+			// TimeBudget limits the time spent improving the order of the rooms when clearing a level
+			TimeBudget             time.Duration `yaml:"timeBudget"`
+			PrioritizeChests       bool          `yaml:"prioritizeChests"`
+			PrioritizeSuperUniques bool          `yaml:"prioritizeSuperUniques"`
+		} `yaml:"roomTour"`
+		PublicGameCounter int `yaml:"-"`
+		Pindleskin        struct {
 			SkipOnImmunities []stat.Resist `yaml:"skipOnImmunities"`
 		} `yaml:"pindleskin"`
 		Cows struct {
@@ -234,6 +308,14 @@ type CharacterCfg struct {
 	Runtime struct {
 		Rules nip.Rules   `yaml:"-"`
 		Drops []data.Item `yaml:"-"`
+		// Layers are the config files merged into this config, profiles first and the character config last
+		Layers []string `yaml:"-"`
+		// Validation is the result of validating the config file when it was loaded
+		Validation Validation `yaml:"-"`
+		// SecretRefs are the vault references the secrets were loaded from, by field
+		SecretRefs map[string]string `yaml:"-"`
+		// UnavailableSecrets are the errors of the secrets that couldn't be read from the vault, by field
+		UnavailableSecrets map[string]string `yaml:"-"`
 	} `yaml:"-"`
 }
 
@@ -260,154 +342,84 @@ func (bm BeltColumns) Total(potionType data.PotionType) int {
 	return total
 }
 
-// Load reads the config.ini file and returns a Config struct filled with data from the ini file
+// Load reads the config from the working directory, Koolo and the characters are only replaced when it's loaded
 func Load() error {
-	Characters = make(map[string]*CharacterCfg)
-
-	// Get the absolute path of the current working directory
-	cwd, err := os.Getwd()
+	koolo, chars, err := defaultLoader.Load()
 	if err != nil {
-		return fmt.Errorf("error getting current working directory: %w", err)
-	}
-
-	// Function to get absolute path
-	getAbsPath := func(relPath string) string {
-		return filepath.Join(cwd, relPath)
-	}
-
-	kooloPath := getAbsPath("config/koolo.yaml")
-	r, err := os.Open(kooloPath)
-	if err != nil {
-		return fmt.Errorf("error loading koolo.yaml: %w", err)
+		return err
 	}
-	defer r.Close()
+	Koolo = koolo
+	SetCharacters(chars)
 
-	d := yaml.NewDecoder(r)
-	if err = d.Decode(&Koolo); err != nil {
-		return fmt.Errorf("error reading config %s: %w", kooloPath, err)
-	}
+	return nil
+}
 
-	configDir := getAbsPath("config")
-	entries, err := os.ReadDir(configDir)
-	if err != nil {
-		return fmt.Errorf("error reading config directory %s: %w", configDir, err)
+// Characters returns the loaded character configs by name. The map is never modified once it's published, it must be
+// treated as read only.
+func Characters() map[string]*CharacterCfg {
+	if chars := characters.Load(); chars != nil {
+		return *chars
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	return nil
+}
 
-		charCfg := CharacterCfg{}
-		charConfigPath := getAbsPath(filepath.Join("config", entry.Name(), "config.yaml"))
-		r, err = os.Open(charConfigPath)
-		if err != nil {
-			return fmt.Errorf("error loading config.yaml: %w", err)
-		}
-		defer r.Close()
+// SetCharacters replaces every character config
+func SetCharacters(chars map[string]*CharacterCfg) {
+	characters.Store(&chars)
+}
 
-		d := yaml.NewDecoder(r)
-		if err = d.Decode(&charCfg); err != nil {
-			return fmt.Errorf("error reading %s character config: %w", charConfigPath, err)
+// SetCharacter replaces the config of a single character. The map is copied, so readers of the previous one are not
+// affected.
+func SetCharacter(name string, cfg *CharacterCfg) {
+	for {
+		current := characters.Load()
+		var chars map[string]*CharacterCfg
+		if current != nil {
+			chars = maps.Clone(*current)
 		}
-
-		pickitPath := getAbsPath(filepath.Join("config", entry.Name(), "pickit")) + "\\"
-		rules, err := nip.ReadDir(pickitPath)
-		if err != nil {
-			return fmt.Errorf("error reading pickit directory %s: %w", pickitPath, err)
+		if chars == nil {
+			chars = make(map[string]*CharacterCfg)
 		}
-
-		if len(charCfg.Game.Runs) > 0 && charCfg.Game.Runs[0] == "leveling" {
-			levelingPickitPath := getAbsPath(filepath.Join("config", entry.Name(), "pickit_leveling")) + "\\"
-			levelingRules, err := nip.ReadDir(levelingPickitPath)
-			if err != nil {
-				return fmt.Errorf("error reading pickit_leveling directory %s: %w", levelingPickitPath, err)
-			}
-			rules = append(rules, levelingRules...)
+		chars[name] = cfg
+		if characters.CompareAndSwap(current, &chars) {
+			return
 		}
-
-		charCfg.Runtime.Rules = rules
-
-		Characters[entry.Name()] = &charCfg
-	}
-	for _, charCfg := range Characters {
-		charCfg.Validate()
 	}
+}
 
-	return nil
+// Dir is the config directory Koolo is loaded from
+func Dir() string {
+	return defaultLoader.Dir()
 }
 
 func CreateFromTemplate(name string) error {
-	if name == "" {
-		return errors.New("name cannot be empty")
-	}
-
-	if _, err := os.Stat("config/" + name); !os.IsNotExist(err) {
-		return errors.New("configuration with that name already exists")
-	}
-
-	err := cp.Copy("config/template", "config/"+name)
-	if err != nil {
-		return fmt.Errorf("error copying template: %w", err)
+	if err := defaultLoader.CreateFromTemplate(name); err != nil {
+		return err
 	}
 
 	return Load()
 }
 
 func ValidateAndSaveConfig(config KooloCfg) error {
-	// Trim executable from the path, just in case
-	config.D2LoDPath = strings.ReplaceAll(strings.ToLower(config.D2LoDPath), "game.exe", "")
-	config.D2RPath = strings.ReplaceAll(strings.ToLower(config.D2RPath), "d2r.exe", "")
-
-	// Validate paths
-	if _, err := os.Stat(config.D2LoDPath + "/d2data.mpq"); os.IsNotExist(err) {
-		return errors.New("D2LoDPath is not valid")
-	}
-
-	if _, err := os.Stat(config.D2RPath + "/d2r.exe"); os.IsNotExist(err) {
-		return errors.New("D2RPath is not valid")
-	}
-
-	text, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("error parsing koolo config: %w", err)
-	}
-
-	err = os.WriteFile("config/koolo.yaml", text, 0644)
-	if err != nil {
-		return fmt.Errorf("error writing koolo config: %w", err)
+	if err := defaultLoader.SaveKooloConfig(config); err != nil {
+		return err
 	}
 
 	return Load()
 }
 
 func SaveSupervisorConfig(supervisorName string, config *CharacterCfg) error {
-	filePath := filepath.Join("config", supervisorName, "config.yaml")
-	d, err := yaml.Marshal(config)
-	config.Validate()
-	if err != nil {
+	if err := defaultLoader.SaveCharacterConfig(supervisorName, config); err != nil {
 		return err
 	}
 
-	err = os.WriteFile(filePath, d, 0644)
-	if err != nil {
-		return fmt.Errorf("error writing supervisor config: %w", err)
-	}
-
 	return Load()
 }
 
 func (c *CharacterCfg) Validate() {
 	if c.Character.Class == "nova" {
-		minThreshold := 65 // Default
-		switch c.Game.Difficulty {
-		case difficulty.Normal:
-			minThreshold = 1
-		case difficulty.Nightmare:
-			minThreshold = 33
-		case difficulty.Hell:
-			minThreshold = 50
-		}
+		minThreshold := novaMinStaticThreshold(c.Game.Difficulty)
 		if c.Character.NovaSorceress.BossStaticThreshold < minThreshold || c.Character.NovaSorceress.BossStaticThreshold > 100 {
 			c.Character.NovaSorceress.BossStaticThreshold = minThreshold
 		}
@@ -0,0 +1,11 @@
+package config
+
+import "github.com/lxn/win"
+
+func GetCurrentDisplayScale() float64 {
+	hDC := win.GetDC(0)
+	defer win.ReleaseDC(0, hDC)
+	dpiX := win.GetDeviceCaps(hDC, win.LOGPIXELSX)
+
+	return float64(dpiX) / 96.0
+}
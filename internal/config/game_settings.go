@@ -3,17 +3,17 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
-	"github.com/lxn/win"
 	cp "github.com/otiai10/copy"
 )
 
 var userProfile = os.Getenv("USERPROFILE")
-var settingsPath = userProfile + "\\Saved Games\\Diablo II Resurrected"
+var settingsPath = filepath.Join(userProfile, "Saved Games", "Diablo II Resurrected")
 
 func ReplaceGameSettings(modName string) error {
-	modDirPath := settingsPath + "\\mods\\" + modName
-	modSettingsPath := modDirPath + "\\Settings.json"
+	modDirPath := filepath.Join(settingsPath, "mods", modName)
+	modSettingsPath := filepath.Join(modDirPath, "Settings.json")
 
 	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
 		return fmt.Errorf("game settings not found at %s", settingsPath)
@@ -34,31 +34,24 @@ func ReplaceGameSettings(modName string) error {
 		}
 	}
 
-	return cp.Copy("config/Settings.json", modSettingsPath)
+	return cp.Copy(filepath.Join(Dir(), "Settings.json"), modSettingsPath)
 }
 
 func InstallMod() error {
-	if _, err := os.Stat(Koolo.D2RPath + "\\d2r.exe"); os.IsNotExist(err) {
+	if _, err := os.Stat(filepath.Join(Koolo.D2RPath, "d2r.exe")); os.IsNotExist(err) {
 		return fmt.Errorf("game not found at %s", Koolo.D2RPath)
 	}
 
-	if _, err := os.Stat(Koolo.D2RPath + "\\mods\\koolo\\koolo.mpq\\modinfo.json"); err == nil {
+	modPath := filepath.Join(Koolo.D2RPath, "mods", "koolo", "koolo.mpq")
+	if _, err := os.Stat(filepath.Join(modPath, "modinfo.json")); err == nil {
 		return nil
 	}
 
-	if err := os.MkdirAll(Koolo.D2RPath+"\\mods\\koolo\\koolo.mpq", os.ModePerm); err != nil {
+	if err := os.MkdirAll(modPath, os.ModePerm); err != nil {
 		return fmt.Errorf("error creating mod folder: %w", err)
 	}
 
 	modFileContent := []byte(`{"name":"koolo","savepath":"koolo/"}`)
 
-	return os.WriteFile(Koolo.D2RPath+"\\mods\\koolo\\koolo.mpq\\modinfo.json", modFileContent, 0644)
-}
-
-func GetCurrentDisplayScale() float64 {
-	hDC := win.GetDC(0)
-	defer win.ReleaseDC(0, hDC)
-	dpiX := win.GetDeviceCaps(hDC, win.LOGPIXELSX)
-
-	return float64(dpiX) / 96.0
+	return os.WriteFile(filepath.Join(modPath, "modinfo.json"), modFileContent, 0644)
 }
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultLoader reads the config from the working directory, where koolo.exe is placed
+var defaultLoader = NewLoader(".", nil)
+
+// Loader reads and writes the config files below a root directory, the one containing the config directory. All the
+// paths are built from the root, so configs can be loaded from anywhere, tests use temporary directories.
+type Loader struct {
+	root    string
+	secrets Secrets
+}
+
+// NewLoader creates a loader for the config directory inside root, secrets are stored in the vault file of the config
+// directory unless other storage is given
+func NewLoader(root string, secrets Secrets) *Loader {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+
+	l := &Loader{root: root, secrets: secrets}
+	if l.secrets == nil {
+		l.secrets = NewVault(filepath.Join(l.Dir(), VaultFile), MasterKey)
+	}
+
+	return l
+}
+
+// DefaultLoader is the loader of the config in the working directory, the one Koolo runs with
+func DefaultLoader() *Loader {
+	return defaultLoader
+}
+
+// Dir is the config directory, with koolo.yaml, the template, the shared profiles and a directory for every character
+func (l *Loader) Dir() string {
+	return filepath.Join(l.root, "config")
+}
+
+// Load reads koolo.yaml and the config of every character
+func (l *Loader) Load() (*KooloCfg, map[string]*CharacterCfg, error) {
+	kooloPath := filepath.Join(l.Dir(), "koolo.yaml")
+	text, err := os.ReadFile(kooloPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading koolo.yaml: %w", err)
+	}
+
+	koolo := &KooloCfg{}
+	if err = yaml.Unmarshal(text, koolo); err != nil {
+		return nil, nil, fmt.Errorf("error reading config %s: %w", kooloPath, err)
+	}
+	koolo.Runtime.SecretRefs, _, koolo.Runtime.UnavailableSecrets = resolveSecrets(l.secrets, koolo)
+
+	entries, err := os.ReadDir(l.Dir())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading config directory %s: %w", l.Dir(), err)
+	}
+
+	characters := make(map[string]*CharacterCfg)
+	for _, entry := range entries {
+		// Skip the shared profiles, they are not characters
+		if !entry.IsDir() || entry.Name() == ProfilesDir {
+			continue
+		}
+
+		charCfg, err := l.LoadCharacter(entry.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		characters[entry.Name()] = charCfg
+	}
+
+	return koolo, characters, nil
+}
+
+// LoadCharacter reads the config of a single character, merging its profiles, resolving its secrets and loading its
+// pickit rules
+func (l *Loader) LoadCharacter(name string) (*CharacterCfg, error) {
+	layers, err := loadLayers(l.Dir(), filepath.Join(l.Dir(), name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s character config: %w", name, err)
+	}
+
+	// Decoded in two steps, the merged yaml node is also needed to find the fields that don't exist in the config
+	charCfg := &CharacterCfg{}
+	merged := mergeLayers(layers)
+	if err = merged.Decode(charCfg); err != nil {
+		return nil, fmt.Errorf("error reading %s character config: %w", name, err)
+	}
+	if charCfg.Profiles, err = profileNames(layers[len(layers)-1].node); err != nil {
+		return nil, fmt.Errorf("error reading %s character config: %w", name, err)
+	}
+	charCfg.Runtime.Validation = ValidateCharacterConfig(charCfg, merged)
+	for _, l := range layers {
+		charCfg.Runtime.Layers = append(charCfg.Runtime.Layers, filepath.Join(l.dir, "config.yaml"))
+	}
+
+	refs, plain, unavailable := resolveSecrets(l.secrets, charCfg)
+	charCfg.Runtime.SecretRefs, charCfg.Runtime.UnavailableSecrets = refs, unavailable
+	for _, field := range plain {
+		charCfg.Runtime.Validation.warn(field, "stored in plain text, it will be moved to the secrets vault when the config is saved")
+	}
+	for _, field := range sortedSecretFields(charCfg) {
+		if err, found := unavailable[field]; found {
+			charCfg.Runtime.Validation.error(field, "secret unavailable: %s", err)
+		}
+	}
+
+	leveling := len(charCfg.Game.Runs) > 0 && charCfg.Game.Runs[0] == "leveling"
+	if charCfg.Runtime.Rules, err = loadPickitRules(layers, leveling); err != nil {
+		return nil, err
+	}
+	charCfg.Validate()
+
+	return charCfg, nil
+}
+
+// CreateFromTemplate creates the config directory of a new character as a copy of the template
+func (l *Loader) CreateFromTemplate(name string) error {
+	if name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if name == ProfilesDir {
+		return fmt.Errorf("%s is reserved for the shared profiles", ProfilesDir)
+	}
+
+	charDir := filepath.Join(l.Dir(), name)
+	if _, err := os.Stat(charDir); !os.IsNotExist(err) {
+		return errors.New("configuration with that name already exists")
+	}
+
+	if err := cp.Copy(filepath.Join(l.Dir(), "template"), charDir); err != nil {
+		return fmt.Errorf("error copying template: %w", err)
+	}
+
+	return nil
+}
+
+// SaveKooloConfig checks the game paths and writes koolo.yaml
+func (l *Loader) SaveKooloConfig(config KooloCfg) error {
+	// Trim executable from the path, just in case
+	config.D2LoDPath = trimExecutable(config.D2LoDPath, "game.exe")
+	config.D2RPath = trimExecutable(config.D2RPath, "d2r.exe")
+
+	// Validate paths
+	if !gameFileExists(config.D2LoDPath, "d2data.mpq") {
+		return errors.New("D2LoDPath is not valid")
+	}
+	if !gameFileExists(config.D2RPath, "d2r.exe") {
+		return errors.New("D2RPath is not valid")
+	}
+
+	// Secrets are stored in the vault, the config only keeps the references
+	if err := storeSecrets(l.secrets, "koolo", &config, config.Runtime.SecretRefs, config.Runtime.UnavailableSecrets); err != nil {
+		return err
+	}
+
+	text, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error parsing koolo config: %w", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(l.Dir(), "koolo.yaml"), text, 0644); err != nil {
+		return fmt.Errorf("error writing koolo config: %w", err)
+	}
+
+	return nil
+}
+
+// SaveCharacterConfig writes the config of a character, without the settings inherited from its profiles and with
+// the secrets moved to the vault
+func (l *Loader) SaveCharacterConfig(name string, config *CharacterCfg) error {
+	stored := *config
+	if err := storeSecrets(l.secrets, name, &stored, config.Runtime.SecretRefs, config.Runtime.UnavailableSecrets); err != nil {
+		return err
+	}
+
+	d, err := overrides(l.Dir(), &stored)
+	config.Validate()
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(filepath.Join(l.Dir(), name, "config.yaml"), d, 0644); err != nil {
+		return fmt.Errorf("error writing supervisor config: %w", err)
+	}
+
+	return nil
+}
+
+// trimExecutable returns the directory of the executable when the path points to the executable itself
+func trimExecutable(path, executable string) string {
+	if strings.EqualFold(filepath.Base(path), executable) {
+		return filepath.Dir(path)
+	}
+
+	return path
+}
+
+func gameFileExists(dir, file string) bool {
+	if dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, file))
+
+	return err == nil
+}
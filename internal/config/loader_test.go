@@ -0,0 +1,147 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newTestLoader creates a root with koolo.yaml and the given files, keys are the paths relative to the config directory
+func newTestLoader(t *testing.T, files map[string]string) *Loader {
+	t.Helper()
+
+	l := NewLoader(t.TempDir(), memorySecrets{})
+	files = maps.Clone(files)
+	files["koolo.yaml"] = "debug:\n  log: true\n"
+	for path, text := range files {
+		path = filepath.Join(l.Dir(), filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return l
+}
+
+const testCharacter = validConfig + `
+characterName: Sorc
+`
+
+func TestLoad(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"sorc/config.yaml":            testCharacter,
+		"sorc/pickit/unique.nip":      "[name] == ring && [quality] == unique\n",
+		"profiles/common/config.yaml": "health:\n  chickenAt: 30\n",
+	})
+
+	koolo, characters, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !koolo.Debug.Log {
+		t.Errorf("Expected koolo.yaml to be loaded")
+	}
+	if len(characters) != 1 || characters["sorc"] == nil {
+		t.Fatalf("Expected only the sorc character, got %d characters", len(characters))
+	}
+	if sorc := characters["sorc"]; sorc.CharacterName != "Sorc" || len(sorc.Runtime.Rules) != 1 {
+		t.Errorf("Expected character config and pickit rules to be loaded, got %q with %d rules", sorc.CharacterName, len(sorc.Runtime.Rules))
+	}
+}
+
+func TestLoadMissingPickit(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"sorc/config.yaml": testCharacter,
+	})
+
+	_, _, err := l.Load()
+	if err == nil || !strings.Contains(err.Error(), "pickit") {
+		t.Errorf("Expected missing pickit directory error, got %v", err)
+	}
+}
+
+func TestLoadLevelingPickit(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"sorc/config.yaml":                  strings.Replace(testCharacter, "runs: [ countess, pit ]", "runs: [ leveling ]", 1),
+		"sorc/pickit/unique.nip":            "[name] == ring && [quality] == unique\n",
+		"sorc/pickit_leveling/leveling.nip": "[name] == amulet && [quality] == unique\n[type] == gold # [gold] >= 100\n",
+	})
+
+	_, characters, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := characters["sorc"].Runtime.Rules; len(rules) != 3 {
+		t.Errorf("Expected pickit and leveling rules to be merged, got %d rules", len(rules))
+	}
+}
+
+func TestCreateFromTemplate(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"template/config.yaml":       testCharacter,
+		"template/pickit/unique.nip": "[name] == ring && [quality] == unique\n",
+	})
+
+	if err := l.CreateFromTemplate("sorc"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"config.yaml", filepath.Join("pickit", "unique.nip")} {
+		if _, err := os.Stat(filepath.Join(l.Dir(), "sorc", path)); err != nil {
+			t.Errorf("Expected %s to be copied from the template, got %v", path, err)
+		}
+	}
+
+	if err := l.CreateFromTemplate("sorc"); err == nil {
+		t.Errorf("Expected an error creating an existing configuration")
+	}
+	if err := l.CreateFromTemplate(ProfilesDir); err == nil {
+		t.Errorf("Expected an error creating a configuration named %s", ProfilesDir)
+	}
+}
+
+func TestSaveKooloConfig(t *testing.T) {
+	l := newTestLoader(t, map[string]string{})
+	games := t.TempDir()
+	for _, path := range []string{filepath.Join("lod", "d2data.mpq"), filepath.Join("d2r", "d2r.exe")} {
+		path = filepath.Join(games, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := KooloCfg{D2LoDPath: filepath.Join(games, "missing"), D2RPath: filepath.Join(games, "d2r")}
+	if err := l.SaveKooloConfig(cfg); err == nil || !strings.Contains(err.Error(), "D2LoDPath") {
+		t.Errorf("Expected invalid D2LoDPath error, got %v", err)
+	}
+	cfg = KooloCfg{D2LoDPath: filepath.Join(games, "lod"), D2RPath: ""}
+	if err := l.SaveKooloConfig(cfg); err == nil || !strings.Contains(err.Error(), "D2RPath") {
+		t.Errorf("Expected invalid D2RPath error, got %v", err)
+	}
+
+	// The path to the executable is also accepted
+	cfg = KooloCfg{D2LoDPath: filepath.Join(games, "lod", "Game.exe"), D2RPath: filepath.Join(games, "d2r", "D2R.exe")}
+	if err := l.SaveKooloConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "koolo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := KooloCfg{}
+	if err = yaml.Unmarshal(text, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.D2LoDPath != filepath.Join(games, "lod") || saved.D2RPath != filepath.Join(games, "d2r") {
+		t.Errorf("Expected game directories to be saved, got %s and %s", saved.D2LoDPath, saved.D2RPath)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptMasterKey asks for the master key in the terminal
+func promptMasterKey(confirm bool) (string, error) {
+	in := bufio.NewReader(os.Stdin)
+
+	return askMasterKey(func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		line, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}, confirm)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hectorgimenez/koolo/internal/utils/winproc"
+	"golang.org/x/sys/windows"
+)
+
+// promptMasterKey asks for the master key in a console window, Koolo runs without a console so one is opened for it
+func promptMasterKey(confirm bool) (string, error) {
+	// It fails when the process already has a console, then that one is used
+	if allocated, _, _ := winproc.AllocConsole.Call(); allocated != 0 {
+		defer winproc.FreeConsole.Call()
+	}
+
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	// The key is not echoed while it's typed
+	h := windows.Handle(in.Fd())
+	var mode uint32
+	if err = windows.GetConsoleMode(h, &mode); err != nil {
+		return "", err
+	}
+	if err = windows.SetConsoleMode(h, mode&^windows.ENABLE_ECHO_INPUT); err != nil {
+		return "", err
+	}
+	defer windows.SetConsoleMode(h, mode)
+
+	reader := bufio.NewReader(in)
+
+	return askMasterKey(func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		line, err := reader.ReadString('\n')
+		fmt.Fprintln(out)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}, confirm)
+}
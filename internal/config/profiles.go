@@ -0,0 +1,256 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/nip"
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesDir is the directory inside config with the shared profiles. A profile is a directory like the character
+// ones, with a config.yaml containing only the settings it changes and an optional pickit directory.
+const ProfilesDir = "profiles"
+
+// layer is a config file merged to build the effective character config
+type layer struct {
+	dir  string
+	node *yaml.Node
+}
+
+// loadLayers returns the profiles extended by the config in dir, resolved recursively, followed by the config itself
+func loadLayers(configDir, dir string, extending []string) ([]layer, error) {
+	node, err := readNode(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := profileNames(node)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profiles of %s: %w", dir, err)
+	}
+
+	layers, err := profileLayers(configDir, profiles, extending)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(layers, layer{dir: dir, node: node}), nil
+}
+
+// profileLayers returns the layers of the profiles in order, profiles extended more than once are only applied the
+// first time
+func profileLayers(configDir string, profiles, extending []string) ([]layer, error) {
+	layers := make([]layer, 0, len(profiles))
+	for _, name := range profiles {
+		if slices.Contains(extending, name) {
+			return nil, fmt.Errorf("profile %s extends itself: %s", name, strings.Join(append(extending, name), " -> "))
+		}
+		nested, err := loadLayers(configDir, filepath.Join(configDir, ProfilesDir, name), append(slices.Clone(extending), name))
+		if err != nil {
+			return nil, fmt.Errorf("error loading profile %s: %w", name, err)
+		}
+		for _, l := range nested {
+			if !slices.ContainsFunc(layers, func(existing layer) bool { return existing.dir == l.dir }) {
+				layers = append(layers, l)
+			}
+		}
+	}
+
+	return layers, nil
+}
+
+// readNode reads a yaml file, an empty file is an empty mapping
+func readNode(path string) (*yaml.Node, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	defer r.Close()
+
+	doc := yaml.Node{}
+	if err = yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+		}
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	node := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		node = doc.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("error reading %s: expected a mapping at line %d", path, node.Line)
+	}
+
+	return node, nil
+}
+
+func profileNames(node *yaml.Node) ([]string, error) {
+	p := struct {
+		Profiles []string `yaml:"profiles"`
+	}{}
+	if err := node.Decode(&p); err != nil {
+		return nil, err
+	}
+
+	return p.Profiles, nil
+}
+
+// mergeLayers merges the layers in order, the values of every layer replace the ones of the previous layers. Nested
+// mappings are merged key by key, anything else, lists included, is replaced as a whole. The profiles key is not
+// merged, each file extends its own profiles.
+func mergeLayers(layers []layer) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, l := range layers {
+		merged = mergeNodes(merged, withoutKey(l.node, "profiles"))
+	}
+
+	return merged
+}
+
+// mergeNodes returns a new mapping with the keys of both mappings, override wins, inputs are not modified
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: base.Tag, Content: slices.Clone(base.Content)}
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		idx := keyIndex(merged, key.Value)
+		switch {
+		case idx == -1:
+			merged.Content = append(merged.Content, key, value)
+		case merged.Content[idx+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			merged.Content[idx+1] = mergeNodes(merged.Content[idx+1], value)
+		default:
+			merged.Content[idx+1] = value
+		}
+	}
+
+	return merged
+}
+
+// diffNodes returns the keys of full with a different value in base, nil when there are none
+func diffNodes(base, full *yaml.Node) *yaml.Node {
+	diff := &yaml.Node{Kind: yaml.MappingNode, Tag: full.Tag}
+	for i := 0; i+1 < len(full.Content); i += 2 {
+		key, value := full.Content[i], full.Content[i+1]
+		idx := keyIndex(base, key.Value)
+		switch {
+		case idx == -1:
+			diff.Content = append(diff.Content, key, value)
+		case base.Content[idx+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if d := diffNodes(base.Content[idx+1], value); d != nil {
+				diff.Content = append(diff.Content, key, d)
+			}
+		case !equalNodes(base.Content[idx+1], value):
+			diff.Content = append(diff.Content, key, value)
+		}
+	}
+
+	if len(diff.Content) == 0 {
+		return nil
+	}
+
+	return diff
+}
+
+func equalNodes(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func keyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func withoutKey(mapping *yaml.Node, key string) *yaml.Node {
+	idx := keyIndex(mapping, key)
+	if idx == -1 {
+		return mapping
+	}
+
+	n := *mapping
+	n.Content = slices.Delete(slices.Clone(mapping.Content), idx, idx+2)
+
+	return &n
+}
+
+// loadPickitRules reads the pickit rules of every layer, profile rules go first. Profiles and characters extending
+// them don't need to have their own pickit directories.
+func loadPickitRules(layers []layer, leveling bool) (nip.Rules, error) {
+	dirs := []string{"pickit"}
+	if leveling {
+		dirs = append(dirs, "pickit_leveling")
+	}
+
+	rules := make(nip.Rules, 0)
+	for _, l := range layers {
+		for _, d := range dirs {
+			pickitPath := filepath.Join(l.dir, d)
+			if _, err := os.Stat(pickitPath); os.IsNotExist(err) && len(layers) > 1 {
+				continue
+			}
+
+			// nip.ReadDir appends the file names to the path as they are
+			layerRules, err := nip.ReadDir(pickitPath + string(filepath.Separator))
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s directory %s: %w", d, pickitPath, err)
+			}
+			rules = append(rules, layerRules...)
+		}
+	}
+
+	return rules, nil
+}
+
+// overrides returns the yaml of the character config without the values inherited from its profiles, so later
+// changes in the profiles are still applied to the character
+func overrides(configDir string, cfg *CharacterCfg) ([]byte, error) {
+	if len(cfg.Profiles) == 0 {
+		return yaml.Marshal(cfg)
+	}
+
+	layers, err := profileLayers(configDir, cfg.Profiles, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both sides are encoded from the struct, so equal values are always encoded the same way
+	inherited := CharacterCfg{}
+	if err = mergeLayers(layers).Decode(&inherited); err != nil {
+		return nil, err
+	}
+	base, full := yaml.Node{}, yaml.Node{}
+	if err = base.Encode(&inherited); err != nil {
+		return nil, err
+	}
+	if err = full.Encode(cfg); err != nil {
+		return nil, err
+	}
+
+	diff := diffNodes(&base, &full)
+	if diff == nil {
+		diff = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	return yaml.Marshal(diff)
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var profileConfigs = map[string]string{
+	"profiles/common/config.yaml": `
+health:
+  healingPotionAt: 75
+  chickenAt: 30
+inventory:
+  inventoryLock:
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+  beltColumns: [ healing, healing, mana, rejuvenation ]
+game:
+  difficulty: hell
+  runs: [ countess, pit ]
+`,
+	"profiles/sorceress/config.yaml": `
+profiles: [ common ]
+character:
+  class: sorceress
+  useTeleport: true
+health:
+  chickenAt: 40
+`,
+	"sorc/config.yaml": `
+profiles: [ sorceress, common ]
+characterName: Sorc
+game:
+  runs: [ mephisto ]
+`,
+}
+
+func TestProfilesAreMerged(t *testing.T) {
+	cfg, err := newTestLoader(t, profileConfigs).LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.CharacterName != "Sorc" || cfg.Character.Class != "sorceress" || !cfg.Character.UseTeleport {
+		t.Errorf("Expected settings from all the layers, got name %q and class %q", cfg.CharacterName, cfg.Character.Class)
+	}
+	if cfg.Health.HealingPotionAt != 75 || cfg.Health.ChickenAt != 40 {
+		t.Errorf("Expected nested settings to be merged one by one, got %+v", cfg.Health)
+	}
+	if !slices.Equal(cfg.Game.Runs, []Run{MephistoRun}) || cfg.Game.Difficulty != "hell" {
+		t.Errorf("Expected runs to be replaced and difficulty inherited, got %v in %s", cfg.Game.Runs, cfg.Game.Difficulty)
+	}
+	if !slices.Equal(cfg.Profiles, []string{"sorceress", "common"}) {
+		t.Errorf("Expected character profiles only, got %v", cfg.Profiles)
+	}
+}
+
+func TestProfileLoop(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"profiles/a/config.yaml": "profiles: [ b ]",
+		"profiles/b/config.yaml": "profiles: [ a ]",
+		"char/config.yaml":       "profiles: [ a ]",
+	})
+
+	_, err := l.LoadCharacter("char")
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("Expected profile loop error, got %v", err)
+	}
+}
+
+func TestOverridesOnlyKeepChanges(t *testing.T) {
+	l := newTestLoader(t, profileConfigs)
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Health.ManaPotionAt = 20
+
+	text, err := overrides(l.Dir(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := yaml.Node{}
+	if err = yaml.Unmarshal(text, &node); err != nil {
+		t.Fatal(err)
+	}
+	saved := node.Content[0]
+	keys := make([]string, 0)
+	for i := 0; i < len(saved.Content); i += 2 {
+		keys = append(keys, saved.Content[i].Value)
+	}
+	if !slices.Equal(keys, []string{"profiles", "characterName", "health", "game"}) {
+		t.Errorf("Expected only the changed settings to be saved, got %v:\n%s", keys, text)
+	}
+	if health := saved.Content[slices.Index(keys, "health")*2+1]; len(health.Content) != 2 || health.Content[0].Value != "manaPotionAt" {
+		t.Errorf("Expected only manaPotionAt to be saved in health, got:\n%s", text)
+	}
+
+	// Loading it again is the same config
+	if err = os.WriteFile(filepath.Join(l.Dir(), "sorc", "config.yaml"), text, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Health != cfg.Health || !slices.Equal(reloaded.Game.Runs, cfg.Game.Runs) || reloaded.CharacterName != cfg.CharacterName {
+		t.Errorf("Expected saved config to be loaded back the same, got %+v", reloaded)
+	}
+}
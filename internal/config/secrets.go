@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// MasterKeyEnv is the environment variable with the master key of the secrets vault, it's asked when not set
+	MasterKeyEnv = "KOOLO_MASTER_KEY"
+	// SecretPrefix starts the values of the secret settings referencing a secret in the vault, like vault:koolo/discord.token
+	SecretPrefix = "vault:"
+	// RedactedSecret replaces the secrets stored in plain text when configs are shown
+	RedactedSecret = "<redacted>"
+)
+
+// Secrets stores the values of the secret settings, passwords and tokens. Config files only hold references to them,
+// so config directories can be shared without sharing the secrets.
+type Secrets interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+// MasterKey returns the master key of the secrets vault from the environment, asking for it when it's not set. When
+// confirm is set it's asked twice, a mistyped key would make the secrets of a new vault unreadable.
+func MasterKey(confirm bool) (string, error) {
+	if key := os.Getenv(MasterKeyEnv); key != "" {
+		return key, nil
+	}
+
+	return promptMasterKey(confirm)
+}
+
+// askMasterKey asks for the master key, and asks again to confirm it when confirm is set
+func askMasterKey(ask func(prompt string) (string, error), confirm bool) (string, error) {
+	key, err := ask("Master key of the Koolo secrets vault: ")
+	if err != nil || !confirm {
+		return key, err
+	}
+
+	again, err := ask("Repeat the master key to confirm it: ")
+	if err != nil {
+		return "", err
+	}
+	if again != key {
+		return "", errors.New("the master keys don't match")
+	}
+
+	return key, nil
+}
+
+func SecretRef(name string) string {
+	return SecretPrefix + name
+}
+
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretPrefix)
+}
+
+// secretHolder is a config with secret settings, by field path
+type secretHolder interface {
+	secretFields() map[string]*string
+}
+
+func (c *KooloCfg) secretFields() map[string]*string {
+	return map[string]*string{
+		"server.password": &c.Server.Password,
+		"server.token":    &c.Server.Token,
+		"discord.token":   &c.Discord.Token,
+		"telegram.token":  &c.Telegram.Token,
+		"webhook.secret":  &c.Webhook.Secret,
+	}
+}
+
+func (c *CharacterCfg) secretFields() map[string]*string {
+	return map[string]*string{
+		"password":               &c.Password,
+		"authToken":              &c.AuthToken,
+		"companion.gamePassword": &c.Companion.GamePassword,
+	}
+}
+
+// Redacted returns a copy of the config safe to be shown, with the secrets replaced by their references
+func (c *KooloCfg) Redacted() *KooloCfg {
+	r := *c
+	redactSecrets(&r, c.Runtime.SecretRefs)
+
+	return &r
+}
+
+// Redacted returns a copy of the config safe to be shown, with the secrets replaced by their references
+func (c *CharacterCfg) Redacted() *CharacterCfg {
+	r := *c
+	redactSecrets(&r, c.Runtime.SecretRefs)
+
+	return &r
+}
+
+// resolveSecrets replaces the references in the secret fields with the secrets, it returns the references by field,
+// the fields with values stored in plain text and the errors of the secrets that couldn't be read. Those are left
+// empty, a config is still loaded when the vault can't be opened.
+func resolveSecrets(s Secrets, h secretHolder) (map[string]string, []string, map[string]string) {
+	refs := make(map[string]string)
+	plain := make([]string, 0)
+	unavailable := make(map[string]string)
+	for _, field := range sortedSecretFields(h) {
+		value := h.secretFields()[field]
+		if *value == "" {
+			continue
+		}
+		if !IsSecretRef(*value) {
+			plain = append(plain, field)
+			continue
+		}
+
+		refs[field] = *value
+		secret, err := s.Get(strings.TrimPrefix(*value, SecretPrefix))
+		if err != nil {
+			unavailable[field] = err.Error()
+			*value = ""
+			continue
+		}
+		*value = secret
+	}
+
+	return refs, plain, unavailable
+}
+
+// storeSecrets moves the secrets to the vault, replacing them with their references. Secrets that didn't change keep
+// the reference they were loaded from, which may be a secret shared with other configs, new ones are stored as
+// owner/field. Removed secrets are deleted from the vault when they are owned by this config, the ones that couldn't
+// be read keep their reference.
+func storeSecrets(s Secrets, owner string, h secretHolder, refs, unavailable map[string]string) error {
+	for _, field := range sortedSecretFields(h) {
+		value := h.secretFields()[field]
+		name := owner + "/" + field
+		if _, found := unavailable[field]; found && *value == "" {
+			*value = refs[field]
+			continue
+		}
+		if *value == "" {
+			if refs[field] == SecretRef(name) {
+				if err := s.Delete(name); err != nil {
+					return fmt.Errorf("error removing secret of %s: %w", field, err)
+				}
+			}
+			continue
+		}
+		if IsSecretRef(*value) {
+			continue
+		}
+
+		if ref, found := refs[field]; found {
+			if current, err := s.Get(strings.TrimPrefix(ref, SecretPrefix)); err == nil && current == *value {
+				*value = ref
+				continue
+			}
+		}
+
+		if err := s.Set(name, *value); err != nil {
+			return fmt.Errorf("error storing secret of %s: %w", field, err)
+		}
+		*value = SecretRef(name)
+	}
+
+	return nil
+}
+
+func redactSecrets(h secretHolder, refs map[string]string) {
+	for field, value := range h.secretFields() {
+		switch {
+		case refs[field] != "":
+			*value = refs[field]
+		case *value == "":
+		default:
+			*value = RedactedSecret
+		}
+	}
+}
+
+func sortedSecretFields(h secretHolder) []string {
+	fields := make([]string, 0)
+	for field := range h.secretFields() {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	return fields
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memorySecrets keeps the secrets in memory, for the tests that don't need a vault file
+type memorySecrets map[string]string
+
+func (m memorySecrets) Get(name string) (string, error) {
+	value, found := m[name]
+	if !found {
+		return "", fmt.Errorf("%w: %s", errSecretNotFound, name)
+	}
+
+	return value, nil
+}
+
+func (m memorySecrets) Set(name, value string) error {
+	m[name] = value
+	return nil
+}
+
+func (m memorySecrets) Delete(name string) error {
+	delete(m, name)
+	return nil
+}
+
+// unavailableSecrets fails like a vault that can't be opened
+type unavailableSecrets struct{}
+
+func (unavailableSecrets) Get(name string) (string, error) { return "", errors.New("wrong master key") }
+func (unavailableSecrets) Set(name, value string) error    { return errors.New("wrong master key") }
+func (unavailableSecrets) Delete(name string) error        { return errors.New("wrong master key") }
+
+func masterKey(key string) func(bool) (string, error) {
+	return func(bool) (string, error) {
+		return key, nil
+	}
+}
+
+func TestVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), VaultFile)
+	asked, confirmed := false, false
+	v := NewVault(path, func(confirm bool) (string, error) {
+		asked, confirmed = true, confirm
+		return "correct horse", nil
+	})
+
+	if _, err := v.Get("sorc/password"); !errors.Is(err, errSecretNotFound) || asked {
+		t.Errorf("Expected secret not found without asking for the master key, got %v", err)
+	}
+	if err := v.Set("sorc/password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if !confirmed {
+		t.Errorf("Expected the master key to be confirmed when the vault is created")
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "hunter2") || strings.Contains(string(text), "sorc/password") {
+		t.Errorf("Expected vault file to be encrypted, got %s", text)
+	}
+
+	value, err := NewVault(path, masterKey("correct horse")).Get("sorc/password")
+	if err != nil || value != "hunter2" {
+		t.Errorf("Expected secret to be read back, got %q: %v", value, err)
+	}
+	if _, err = NewVault(path, masterKey("wrong horse")).Get("sorc/password"); err == nil {
+		t.Errorf("Expected an error opening the vault with a wrong master key")
+	}
+
+	v = NewVault(path, masterKey("correct horse"))
+	if err = v.Delete("sorc/password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewVault(path, masterKey("correct horse")).Get("sorc/password"); !errors.Is(err, errSecretNotFound) {
+		t.Errorf("Expected the secret to be deleted from the vault file, got %v", err)
+	}
+}
+
+func TestVaultAsksMasterKeyOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), VaultFile)
+	if err := NewVault(path, masterKey("correct horse")).Set("sorc/password", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	asked := 0
+	v := NewVault(path, func(confirm bool) (string, error) {
+		asked++
+		if confirm {
+			t.Errorf("Expected the master key of an existing vault not to be confirmed")
+		}
+		return "wrong horse", nil
+	})
+	for range 3 {
+		if _, err := v.Get("sorc/password"); err == nil {
+			t.Errorf("Expected an error opening the vault with a wrong master key")
+		}
+	}
+	if err := v.Set("sorc/password", "hunter3"); err == nil {
+		t.Errorf("Expected an error storing a secret in a vault that couldn't be opened")
+	}
+	if asked != 1 {
+		t.Errorf("Expected the master key to be asked once, got %d", asked)
+	}
+}
+
+func TestAskMasterKey(t *testing.T) {
+	answers := func(keys ...string) func(string) (string, error) {
+		return func(string) (string, error) {
+			key := keys[0]
+			keys = keys[1:]
+			return key, nil
+		}
+	}
+
+	if key, err := askMasterKey(answers("correct horse"), false); err != nil || key != "correct horse" {
+		t.Errorf("Expected the master key to be asked once, got %q: %v", key, err)
+	}
+	if key, err := askMasterKey(answers("correct horse", "correct horse"), true); err != nil || key != "correct horse" {
+		t.Errorf("Expected the confirmed master key, got %q: %v", key, err)
+	}
+	if _, err := askMasterKey(answers("correct horse", "correct hrose"), true); err == nil {
+		t.Errorf("Expected an error when the master keys don't match")
+	}
+}
+
+func TestCharacterSecretsAreMovedToTheVault(t *testing.T) {
+	secrets := memorySecrets{}
+	l := newTestLoader(t, map[string]string{
+		"sorc/config.yaml":       testCharacter + "password: hunter2\n",
+		"sorc/pickit/unique.nip": "[name] == ring && [quality] == unique\n",
+	})
+	l.secrets = secrets
+
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fields(cfg.Runtime.Validation.Warnings); len(got) != 1 || got[0] != "password" {
+		t.Errorf("Expected a warning for the password stored in plain text, got %v", cfg.Runtime.Validation.Warnings)
+	}
+	if cfg.Redacted().Password != RedactedSecret {
+		t.Errorf("Expected plain text password to be redacted, got %s", cfg.Redacted().Password)
+	}
+
+	if err = l.SaveCharacterConfig("sorc", cfg); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "sorc", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "hunter2") || !strings.Contains(string(text), "password: vault:sorc/password") {
+		t.Errorf("Expected the config to reference the secret, got:\n%s", text)
+	}
+	if secrets["sorc/password"] != "hunter2" || cfg.Password != "hunter2" {
+		t.Errorf("Expected password to be stored in the vault and kept in the config, got %q and %q", secrets["sorc/password"], cfg.Password)
+	}
+
+	reloaded, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Password != "hunter2" || len(reloaded.Runtime.Validation.Warnings) > 0 {
+		t.Errorf("Expected password to be read from the vault without warnings, got %q and %v", reloaded.Password, reloaded.Runtime.Validation.Warnings)
+	}
+	if reloaded.Redacted().Password != "vault:sorc/password" {
+		t.Errorf("Expected password to be redacted as its reference, got %s", reloaded.Redacted().Password)
+	}
+}
+
+func TestProfileSecretsAreShared(t *testing.T) {
+	secrets := memorySecrets{"team/authToken": "token"}
+	l := newTestLoader(t, map[string]string{
+		"profiles/team/config.yaml": "authToken: vault:team/authToken\n",
+		"sorc/config.yaml":          "profiles: [ team ]\n" + testCharacter,
+		"sorc/pickit/unique.nip":    "[name] == ring && [quality] == unique\n",
+	})
+	l.secrets = secrets
+
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AuthToken != "token" {
+		t.Errorf("Expected token to be read from the vault, got %q", cfg.AuthToken)
+	}
+
+	// Unchanged secrets keep referencing the profile, changed ones are stored for the character
+	if err = l.SaveCharacterConfig("sorc", cfg); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "sorc", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "authToken") {
+		t.Errorf("Expected the token to be inherited from the profile, got:\n%s", text)
+	}
+
+	cfg.AuthToken = "new token"
+	if err = l.SaveCharacterConfig("sorc", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if secrets["sorc/authToken"] != "new token" || secrets["team/authToken"] != "token" {
+		t.Errorf("Expected the new token to be stored for the character only, got %v", secrets)
+	}
+}
+
+func TestKooloSecretsAreMovedToTheVault(t *testing.T) {
+	secrets := memorySecrets{}
+	l := newTestLoader(t, map[string]string{})
+	l.secrets = secrets
+	games := t.TempDir()
+	for _, path := range []string{"d2data.mpq", "d2r.exe"} {
+		if err := os.WriteFile(filepath.Join(games, path), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := KooloCfg{D2LoDPath: games, D2RPath: games}
+	cfg.Discord.Token = "discord token"
+	if err := l.SaveKooloConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "koolo.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "discord token") {
+		t.Errorf("Expected the token not to be saved in koolo.yaml, got:\n%s", text)
+	}
+
+	koolo, _, err := l.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if koolo.Discord.Token != "discord token" || koolo.Redacted().Discord.Token != "vault:koolo/discord.token" {
+		t.Errorf("Expected token to be read from the vault, got %q", koolo.Discord.Token)
+	}
+}
+
+func TestCharacterSecretsUnavailable(t *testing.T) {
+	l := newTestLoader(t, map[string]string{
+		"sorc/config.yaml":       testCharacter + "password: vault:sorc/password\n",
+		"sorc/pickit/unique.nip": "[name] == ring && [quality] == unique\n",
+	})
+	l.secrets = unavailableSecrets{}
+
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatalf("Expected the config to be loaded without its secrets, got %v", err)
+	}
+	if cfg.Password != "" {
+		t.Errorf("Expected the password to be left empty, got %q", cfg.Password)
+	}
+	if got := fields(cfg.Runtime.Validation.Errors); len(got) != 1 || got[0] != "password" {
+		t.Errorf("Expected an error for the unavailable password, got %v", cfg.Runtime.Validation.Errors)
+	}
+
+	// Saving keeps the reference, the secret is read again once the vault can be opened
+	if err = l.SaveCharacterConfig("sorc", cfg); err != nil {
+		t.Fatal(err)
+	}
+	text, err := os.ReadFile(filepath.Join(l.Dir(), "sorc", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "password: vault:sorc/password") {
+		t.Errorf("Expected the config to keep referencing the secret, got:\n%s", text)
+	}
+}
+
+func TestRemovedSecretsAreDeletedFromTheVault(t *testing.T) {
+	secrets := memorySecrets{"sorc/password": "hunter2", "team/authToken": "token"}
+	l := newTestLoader(t, map[string]string{
+		"profiles/team/config.yaml": "authToken: vault:team/authToken\n",
+		"sorc/config.yaml":          "profiles: [ team ]\n" + testCharacter + "password: vault:sorc/password\n",
+		"sorc/pickit/unique.nip":    "[name] == ring && [quality] == unique\n",
+	})
+	l.secrets = secrets
+
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Password, cfg.AuthToken = "", ""
+	if err = l.SaveCharacterConfig("sorc", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shared secrets are kept, other configs may still use them
+	if _, found := secrets["sorc/password"]; found {
+		t.Errorf("Expected the removed password to be deleted from the vault, got %v", secrets)
+	}
+	if secrets["team/authToken"] != "token" {
+		t.Errorf("Expected the secret of the profile to be kept, got %v", secrets)
+	}
+}
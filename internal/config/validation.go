@@ -0,0 +1,301 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is wrapped by the errors of the configs that didn't pass the validation
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ValidationIssue is a problem found in a config field, Field is the path of the field in the yaml file, like
+// game.runs[2] or health.chickenAt
+type ValidationIssue struct {
+	Field   string
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// Validation is the result of validating a character config. Errors prevent the supervisor from starting, warnings
+// are settings that are accepted but probably not what the user wants.
+type Validation struct {
+	Errors   []ValidationIssue
+	Warnings []ValidationIssue
+}
+
+func (v Validation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// Err returns all the errors joined, nil if the config is valid
+func (v Validation) Err() error {
+	if v.Valid() {
+		return nil
+	}
+
+	errs := make([]error, 0, len(v.Errors))
+	for _, issue := range v.Errors {
+		errs = append(errs, errors.New(issue.String()))
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+func (v *Validation) error(field, format string, args ...any) {
+	v.Errors = append(v.Errors, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *Validation) warn(field, format string, args ...any) {
+	v.Warnings = append(v.Warnings, ValidationIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+var beltColumnTypes = []string{"healing", "mana", "rejuvenation"}
+
+// ValidateCharacterConfig checks the character config decoded from the yaml node, node can be nil to skip the
+// detection of unknown fields
+func ValidateCharacterConfig(c *CharacterCfg, node *yaml.Node) Validation {
+	v := Validation{}
+	if node != nil {
+		unknownFields(node, reflect.TypeOf(*c), "", &v)
+	}
+
+	if c.Character.Class == "" {
+		v.error("character.class", "is required")
+	}
+	if c.MaxGameLength < 0 {
+		v.error("maxGameLength", "can't be negative, got %d", c.MaxGameLength)
+	}
+
+	validateHealth(c, &v)
+	validateInventory(c, &v)
+	validateGame(c, &v)
+
+	for i, r := range c.CubeRecipes.EnabledRecipes {
+		if !slices.Contains(AvailableRecipes, r) {
+			v.error(fmt.Sprintf("cubing.enabledRecipes[%d]", i), "unknown recipe %q", r)
+		}
+	}
+
+	if c.Character.Class == "nova" {
+		threshold := c.Character.NovaSorceress.BossStaticThreshold
+		if minThreshold := novaMinStaticThreshold(c.Game.Difficulty); threshold < minThreshold || threshold > 100 {
+			v.warn("character.nova_sorceress.boss_static_threshold", "must be between %d and 100 in %s, %d will be used", minThreshold, c.Game.Difficulty, minThreshold)
+		}
+	}
+
+	return v
+}
+
+func validateHealth(c *CharacterCfg, v *Validation) {
+	percentages := []struct {
+		field string
+		value int
+	}{
+		{"healingPotionAt", c.Health.HealingPotionAt},
+		{"manaPotionAt", c.Health.ManaPotionAt},
+		{"rejuvPotionAtLife", c.Health.RejuvPotionAtLife},
+		{"rejuvPotionAtMana", c.Health.RejuvPotionAtMana},
+		{"mercHealingPotionAt", c.Health.MercHealingPotionAt},
+		{"mercRejuvPotionAt", c.Health.MercRejuvPotionAt},
+		{"chickenAt", c.Health.ChickenAt},
+		{"mercChickenAt", c.Health.MercChickenAt},
+	}
+	for _, p := range percentages {
+		if p.value < 0 || p.value > 100 {
+			v.error("health."+p.field, "must be a percentage between 0 and 100, got %d", p.value)
+		}
+	}
+
+	if c.Health.ChickenAt > 0 && c.Health.ChickenAt >= c.Health.HealingPotionAt {
+		v.warn("health.chickenAt", "is not lower than healingPotionAt, the game will be left before drinking a healing potion")
+	}
+}
+
+func validateInventory(c *CharacterCfg, v *Validation) {
+	for i, column := range c.Inventory.BeltColumns {
+		if !slices.ContainsFunc(beltColumnTypes, func(t string) bool { return strings.EqualFold(t, column) }) {
+			v.error(fmt.Sprintf("inventory.beltColumns[%d]", i), "must be one of %s, got %q", strings.Join(beltColumnTypes, ", "), column)
+		}
+	}
+
+	if len(c.Inventory.InventoryLock) != 4 {
+		v.error("inventory.inventoryLock", "must have 4 rows, got %d", len(c.Inventory.InventoryLock))
+	}
+	for y, row := range c.Inventory.InventoryLock {
+		if len(row) != 10 {
+			v.error(fmt.Sprintf("inventory.inventoryLock[%d]", y), "must have 10 columns, got %d", len(row))
+		}
+		for x, cell := range row {
+			if cell != 0 && cell != 1 {
+				v.error(fmt.Sprintf("inventory.inventoryLock[%d][%d]", y, x), "must be 0 (locked) or 1 (unlocked), got %d", cell)
+			}
+		}
+	}
+}
+
+func validateGame(c *CharacterCfg, v *Validation) {
+	switch c.Game.Difficulty {
+	case difficulty.Normal, difficulty.Nightmare, difficulty.Hell:
+	default:
+		v.error("game.difficulty", "must be one of normal, nightmare, hell, got %q", c.Game.Difficulty)
+	}
+
+	if len(c.Game.Runs) == 0 {
+		v.warn("game.runs", "no runs enabled, games will be created and left without doing anything")
+	}
+	for i, r := range c.Game.Runs {
+		field := fmt.Sprintf("game.runs[%d]", i)
+		if _, found := AvailableRuns[r]; !found {
+			v.error(field, "unknown run %q", r)
+		} else if slices.Index(c.Game.Runs, r) < i {
+			v.warn(field, "run %q is enabled more than once", r)
+		}
+	}
+
+	// Sorted, so the issues are always listed in the same order
+	for _, r := range slices.Sorted(maps.Keys(c.Game.RunConditions)) {
+		cond := c.Game.RunConditions[r]
+		field := "game.runConditions." + string(r)
+		if _, found := AvailableRuns[r]; !found {
+			v.error(field, "unknown run %q", r)
+			continue
+		}
+		if !slices.Contains(c.Game.Runs, r) {
+			v.warn(field, "run %q is not enabled, its conditions have no effect", r)
+		}
+		if cond.MinLevel < 0 || cond.MaxLevel < 0 || cond.EveryNthGame < 0 || cond.SkipAfterFailures < 0 ||
+			cond.FailureCooldownGames < 0 || cond.TimeBudget < 0 || cond.MaxLayoutDistance < 0 {
+			v.error(field, "values can't be negative")
+		}
+		if cond.MaxLevel > 0 && cond.MinLevel >= cond.MaxLevel {
+			v.error(field+".minLevel", "must be lower than maxLevel %d, got %d", cond.MaxLevel, cond.MinLevel)
+		}
+		if cond.FailureCooldownGames > 0 && cond.SkipAfterFailures == 0 {
+			v.warn(field+".failureCooldownGames", "has no effect without skipAfterFailures")
+		}
+		for i, tz := range cond.TerrorZones {
+			if !tz.Area().CanBeTerrorized() {
+				v.warn(fmt.Sprintf("%s.terrorZones[%d]", field, i), "area %d can't be terrorized", tz)
+			}
+		}
+	}
+
+	for i, tz := range c.Game.TerrorZone.Areas {
+		if !tz.Area().CanBeTerrorized() {
+			v.warn(fmt.Sprintf("game.terror_zone.areas[%d]", i), "area %d can't be terrorized", tz)
+		}
+	}
+
+	validateResists("game.pindleskin.skipOnImmunities", c.Game.Pindleskin.SkipOnImmunities, v)
+	validateResists("game.terror_zone.skipOnImmunities", c.Game.TerrorZone.SkipOnImmunities, v)
+}
+
+func validateResists(field string, resists []stat.Resist, v *Validation) {
+	for i, r := range resists {
+		switch r {
+		case stat.ColdImmune, stat.FireImmune, stat.LightImmune, stat.PoisonImmune, stat.MagicImmune:
+		default:
+			v.error(fmt.Sprintf("%s[%d]", field, i), "must be one of cold, fire, light, poison, magic, got %q", r)
+		}
+	}
+}
+
+// unknownFields walks the yaml node together with the type it's decoded into, reporting the keys that don't match any
+// field. They are ignored when decoding, usually a typo or a setting that doesn't exist anymore.
+func unknownFields(node *yaml.Node, t reflect.Type, path string, v *Validation) {
+	if node.Kind == yaml.DocumentNode {
+		for _, n := range node.Content {
+			unknownFields(n, t, path, v)
+		}
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode || t == reflect.TypeOf(time.Time{}) {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, found := fields[key]
+			if !found {
+				v.warn(joinField(path, key), "unknown field, it will be ignored")
+				continue
+			}
+			unknownFields(node.Content[i+1], field.Type, joinField(path, key), v)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			unknownFields(node.Content[i+1], t.Elem(), joinField(path, node.Content[i].Value), v)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, n := range node.Content {
+			unknownFields(n, t.Elem(), fmt.Sprintf("%s[%d]", path, i), v)
+		}
+	}
+}
+
+// yamlFields returns the fields of the struct by their yaml key, using the same naming rules as the yaml decoder
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+
+	return fields
+}
+
+func joinField(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func novaMinStaticThreshold(d difficulty.Difficulty) int {
+	switch d {
+	case difficulty.Normal:
+		return 1
+	case difficulty.Nightmare:
+		return 33
+	case difficulty.Hell:
+		return 50
+	}
+
+	return 65
+}
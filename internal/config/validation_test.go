@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func validateYAML(t *testing.T, text string) (CharacterCfg, Validation) {
+	t.Helper()
+
+	node := yaml.Node{}
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		t.Fatal(err)
+	}
+	cfg := CharacterCfg{}
+	if err := node.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	return cfg, ValidateCharacterConfig(&cfg, &node)
+}
+
+func fields(issues []ValidationIssue) []string {
+	f := make([]string, 0, len(issues))
+	for _, i := range issues {
+		f = append(f, i.Field)
+	}
+
+	return f
+}
+
+const validConfig = `
+character:
+  class: sorceress
+health:
+  healingPotionAt: 75
+  chickenAt: 30
+inventory:
+  inventoryLock:
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+  beltColumns: [healing, Healing, mana, rejuvenation]
+game:
+  difficulty: hell
+  runs: [ countess, pit ]
+  runConditions:
+    pit:
+      minLevel: 60
+      maxLevel: 90
+  pindleskin:
+    skipOnImmunities: [ fire ]
+cubing:
+  enabledRecipes: [ Perfect Amethyst ]
+`
+
+func TestValidConfig(t *testing.T) {
+	_, v := validateYAML(t, validConfig)
+	if !v.Valid() || len(v.Warnings) > 0 {
+		t.Errorf("Expected config to be valid without warnings, got errors %v and warnings %v", v.Errors, v.Warnings)
+	}
+	if v.Err() != nil {
+		t.Errorf("Expected no error for a valid config, got %s", v.Err())
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	_, v := validateYAML(t, `
+character:
+  class: sorceress
+health:
+  healingPotionAt: 120
+  chickenAt: -5
+inventory:
+  inventoryLock:
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 2 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+  beltColumns: [healing, healing, stamina, rejuvenation]
+game:
+  difficulty: hell
+  runs: [ countess, cowz ]
+  runConditions:
+    countess:
+      minLevel: 50
+      maxLevel: 40
+cubing:
+  enabledRecipes: [ Perfect Amethyst, Perfect Emeral ]
+`)
+
+	expected := []string{
+		"health.healingPotionAt",
+		"health.chickenAt",
+		"inventory.beltColumns[2]",
+		"inventory.inventoryLock[0][9]",
+		"game.runs[1]",
+		"game.runConditions.countess.minLevel",
+		"cubing.enabledRecipes[1]",
+	}
+	if got := fields(v.Errors); !slices.Equal(got, expected) {
+		t.Errorf("Expected errors in %v, got %v", expected, v.Errors)
+	}
+	if v.Err() == nil {
+		t.Errorf("Expected invalid config to return an error")
+	}
+}
+
+func TestValidationWarnings(t *testing.T) {
+	cfg, v := validateYAML(t, `
+maxGameLenght: 300
+character:
+  class: sorceress
+health:
+  healingPotionAt: 75
+  chickenAt: 30
+  chikenAt: 50
+inventory:
+  inventoryLock:
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+    - [ 1, 1, 1, 1, 1, 1, 1, 0, 0, 0 ]
+  beltColumns: [healing, healing, mana, rejuvenation]
+game:
+  difficulty: hell
+  runs: [ countess, countess ]
+  runConditions:
+    baal:
+      everyNthGame: 2
+`)
+
+	expected := []string{"maxGameLenght", "health.chikenAt", "game.runs[1]", "game.runConditions.baal"}
+	if got := fields(v.Warnings); !slices.Equal(got, expected) {
+		t.Errorf("Expected warnings in %v, got %v", expected, v.Warnings)
+	}
+	if !v.Valid() {
+		t.Errorf("Expected warnings not to make the config invalid, got %v", v.Errors)
+	}
+	if cfg.MaxGameLength != 0 {
+		t.Errorf("Expected unknown field to be ignored, got max game length %d", cfg.MaxGameLength)
+	}
+}
+
+func TestTemplateIsValid(t *testing.T) {
+	text, err := os.ReadFile("../../config/template/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, v := validateYAML(t, string(text))
+	if !v.Valid() || len(v.Warnings) > 0 {
+		t.Errorf("Expected template config to be valid without warnings, got errors %v and warnings %v", v.Errors, v.Warnings)
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// VaultFile is the file inside the config directory with the secrets
+const VaultFile = "secrets.vault"
+
+var errSecretNotFound = errors.New("secret not found")
+
+// Vault stores the secrets in a local file encrypted with AES-GCM, the key is derived from a master key with scrypt.
+// The file is only opened, and the master key requested, the first time it's needed. If it can't be opened the error
+// is kept, the master key is not asked again until Koolo is restarted.
+type Vault struct {
+	path      string
+	masterKey func(confirm bool) (string, error)
+
+	mu      sync.Mutex
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+	openErr error
+}
+
+// vaultFile is the content of the vault file, only the salt is stored in plain
+type vaultFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// NewVault creates a vault for the file in path, masterKey is asked to confirm the key when the vault is created
+func NewVault(path string, masterKey func(confirm bool) (string, error)) *Vault {
+	return &Vault{path: path, masterKey: masterKey}
+}
+
+func (v *Vault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Nothing can be found in a vault that doesn't exist yet, no need to ask for the master key
+	if _, err := os.Stat(v.path); os.IsNotExist(err) && v.secrets == nil {
+		return "", fmt.Errorf("%w: %s", errSecretNotFound, name)
+	}
+	if err := v.open(); err != nil {
+		return "", err
+	}
+
+	value, found := v.secrets[name]
+	if !found {
+		return "", fmt.Errorf("%w: %s", errSecretNotFound, name)
+	}
+
+	return value, nil
+}
+
+func (v *Vault) Set(name, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.open(); err != nil {
+		return err
+	}
+	if current, found := v.secrets[name]; found && current == value {
+		return nil
+	}
+
+	v.secrets[name] = value
+
+	return v.save()
+}
+
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := os.Stat(v.path); os.IsNotExist(err) && v.secrets == nil {
+		return nil
+	}
+	if err := v.open(); err != nil {
+		return err
+	}
+	if _, found := v.secrets[name]; !found {
+		return nil
+	}
+
+	delete(v.secrets, name)
+
+	return v.save()
+}
+
+// open reads the vault the first time it's needed, later calls return the same error if it couldn't be read
+func (v *Vault) open() error {
+	if v.secrets == nil && v.openErr == nil {
+		v.openErr = v.read()
+	}
+
+	return v.openErr
+}
+
+// read reads and decrypts the vault file, or creates an empty vault if there is no file yet
+func (v *Vault) read() error {
+	f := vaultFile{}
+	create := false
+	text, err := os.ReadFile(v.path)
+	switch {
+	case os.IsNotExist(err):
+		create = true
+		f.Salt = make([]byte, 16)
+		if _, err = rand.Read(f.Salt); err != nil {
+			return fmt.Errorf("error creating secrets vault: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("error reading secrets vault %s: %w", v.path, err)
+	default:
+		if err = json.Unmarshal(text, &f); err != nil {
+			return fmt.Errorf("error reading secrets vault %s: %w", v.path, err)
+		}
+	}
+
+	masterKey, err := v.masterKey(create)
+	if err != nil {
+		return fmt.Errorf("error getting the master key of the secrets vault: %w", err)
+	}
+	if masterKey == "" {
+		return errors.New("the master key of the secrets vault can not be empty")
+	}
+	key, err := scrypt.Key([]byte(masterKey), f.Salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return fmt.Errorf("error deriving the secrets vault key: %w", err)
+	}
+
+	secrets := make(map[string]string)
+	if f.Data != nil {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return err
+		}
+		plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+		if err != nil {
+			return fmt.Errorf("wrong master key or damaged secrets vault %s", v.path)
+		}
+		if err = json.Unmarshal(plain, &secrets); err != nil {
+			return fmt.Errorf("error reading secrets vault %s: %w", v.path, err)
+		}
+	}
+
+	v.key, v.salt, v.secrets = key, f.Salt, secrets
+
+	return nil
+}
+
+// save encrypts the secrets with a new nonce and replaces the vault file
+func (v *Vault) save() error {
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	f := vaultFile{Salt: v.salt, Nonce: make([]byte, gcm.NonceSize())}
+	if _, err = rand.Read(f.Nonce); err != nil {
+		return fmt.Errorf("error encrypting secrets vault: %w", err)
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+
+	text, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, the vault is never left half written
+	tmp := v.path + ".tmp"
+	if err = os.WriteFile(tmp, text, 0600); err != nil {
+		return fmt.Errorf("error writing secrets vault: %w", err)
+	}
+	if err = os.Rename(tmp, v.path); err != nil {
+		return fmt.Errorf("error writing secrets vault: %w", err)
+	}
+
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating secrets vault cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often the character config files are checked for changes
+const DefaultWatchInterval = 2 * time.Second
+
+// Watcher reloads a character config when any of its files changes: the config, the profiles it extends or the pickit
+// rules. Files are polled, and a change is only reloaded once the files stay the same for a whole interval, so files
+// being saved are not read half written.
+type Watcher struct {
+	loader   *Loader
+	name     string
+	interval time.Duration
+	// onReload receives the reloaded config, or the error if it can't be loaded or it's not valid
+	onReload func(cfg *CharacterCfg, err error)
+
+	layers  []string
+	current string
+	changed string
+}
+
+func NewWatcher(loader *Loader, name string, cfg *CharacterCfg, interval time.Duration, onReload func(cfg *CharacterCfg, err error)) *Watcher {
+	return &Watcher{
+		loader:   loader,
+		name:     name,
+		interval: interval,
+		onReload: onReload,
+		layers:   cfg.Runtime.Layers,
+		current:  fingerprint(cfg.Runtime.Layers),
+	}
+}
+
+// Start polls the files until the context is cancelled, it blocks
+func (w *Watcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+func (w *Watcher) check() {
+	fp := fingerprint(w.layers)
+	if fp == w.current {
+		w.changed = ""
+		return
+	}
+
+	// Wait until the files stop changing
+	if fp != w.changed {
+		w.changed = fp
+		return
+	}
+	w.current, w.changed = fp, ""
+
+	cfg, err := w.loader.LoadCharacter(w.name)
+	if err == nil {
+		err = cfg.Runtime.Validation.Err()
+	}
+	if err != nil {
+		w.onReload(nil, err)
+		return
+	}
+
+	// Profiles may have been added or removed
+	w.layers = cfg.Runtime.Layers
+	w.current = fingerprint(w.layers)
+	w.onReload(cfg, nil)
+}
+
+// fingerprint describes all the files in the directories of the layers, it changes when any of them is added, removed
+// or modified
+func fingerprint(layers []string) string {
+	var b strings.Builder
+	for _, l := range layers {
+		filepath.WalkDir(filepath.Dir(l), func(path string, d fs.DirEntry, err error) error {
+			// Missing files are not an error, they are just not part of the fingerprint
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+			}
+			return nil
+		})
+	}
+
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatcherReloadsChanges(t *testing.T) {
+	l := newTestLoader(t, profileConfigs)
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reloaded *CharacterCfg
+	var reloadErr error
+	calls := 0
+	w := NewWatcher(l, "sorc", cfg, DefaultWatchInterval, func(cfg *CharacterCfg, err error) {
+		reloaded, reloadErr = cfg, err
+		calls++
+	})
+
+	w.check()
+	if calls != 0 {
+		t.Fatalf("Expected no reload without changes, got %d", calls)
+	}
+
+	// Changes in the profiles are reloaded too, once the files stop changing
+	profile := filepath.Join(l.Dir(), "profiles", "common", "config.yaml")
+	text := strings.Replace(profileConfigs["profiles/common/config.yaml"], "healingPotionAt: 75", "healingPotionAt: 60", 1)
+	if err = os.WriteFile(profile, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	if calls != 0 {
+		t.Fatalf("Expected to wait until the files are stable, got %d reloads", calls)
+	}
+	w.check()
+	if calls != 1 || reloadErr != nil {
+		t.Fatalf("Expected config to be reloaded, got %d reloads: %v", calls, reloadErr)
+	}
+	if reloaded.Health.HealingPotionAt != 60 {
+		t.Errorf("Expected healing potion at 60, got %d", reloaded.Health.HealingPotionAt)
+	}
+
+	w.check()
+	if calls != 1 {
+		t.Errorf("Expected a single reload per change, got %d", calls)
+	}
+}
+
+func TestWatcherRejectsInvalidConfigs(t *testing.T) {
+	l := newTestLoader(t, profileConfigs)
+	cfg, err := l.LoadCharacter("sorc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reloaded *CharacterCfg
+	var reloadErr error
+	w := NewWatcher(l, "sorc", cfg, DefaultWatchInterval, func(cfg *CharacterCfg, err error) {
+		reloaded, reloadErr = cfg, err
+	})
+
+	char := filepath.Join(l.Dir(), "sorc", "config.yaml")
+	if err = os.WriteFile(char, []byte("profiles: [ sorceress ]\ngame:\n  runs: [ mefisto ]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w.check()
+	w.check()
+
+	if reloaded != nil || reloadErr == nil {
+		t.Errorf("Expected invalid config to be rejected, got %v", reloadErr)
+	}
+}
+
+func TestSetCharacter(t *testing.T) {
+	previous := Characters()
+	t.Cleanup(func() { SetCharacters(previous) })
+
+	old := &CharacterCfg{}
+	SetCharacters(map[string]*CharacterCfg{"sorc": old, "paladin": {}})
+	before := Characters()
+
+	reloaded := &CharacterCfg{}
+	SetCharacter("sorc", reloaded)
+	if Characters()["sorc"] != reloaded || len(Characters()) != 2 {
+		t.Errorf("Expected the reloaded config to replace the previous one, got %v", Characters())
+	}
+	if before["sorc"] != old {
+		t.Errorf("Expected the previous map not to change")
+	}
+}
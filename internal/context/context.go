@@ -1,11 +1,13 @@
 package context
 
 import (
+	"errors"
 	"log/slog"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
@@ -20,6 +22,9 @@ import (
 var mu sync.Mutex
 var botContexts = make(map[uint64]*Status)
 
+// ErrRunCancelled is the panic used to interrupt the run in progress, the game goes on with the next one
+var ErrRunCancelled = errors.New("run cancelled")
+
 type Priority int
 
 const (
@@ -53,6 +58,7 @@ type Context struct {
 	LastBuffAt        time.Time
 	ContextDebug      map[Priority]*Debug
 	CurrentGame       *CurrentGameHelper
+	runCancelled      atomic.Bool
 }
 
 type Debug struct {
@@ -122,6 +128,14 @@ func (ctx *Context) RefreshGameData() {
 	*ctx.Data = ctx.GameReader.GetData()
 }
 
+// SetCharacterCfg replaces the config used by the bot, it must be called between games as the runs expect the config
+// to stay the same while they are executed. The game data has the new config after the next refresh.
+func (ctx *Context) SetCharacterCfg(cfg *config.CharacterCfg) {
+	ctx.CharacterCfg = cfg
+	ctx.GameReader.SetCharacterCfg(cfg)
+	ctx.PathFinder.SetCharacterCfg(cfg)
+}
+
 func (ctx *Context) Detach() {
 	mu.Lock()
 	defer mu.Unlock()
@@ -138,6 +152,16 @@ func (ctx *Context) SwitchPriority(priority Priority) {
 	ctx.ExecutionPriority = priority
 }
 
+// CancelRun interrupts the run executed by the normal priority routine the next time it checks its priority
+func (ctx *Context) CancelRun() {
+	ctx.runCancelled.Store(true)
+}
+
+// ResetRunCancel forgets a previous CancelRun, it's called before starting every run
+func (ctx *Context) ResetRunCancel() {
+	ctx.runCancelled.Store(false)
+}
+
 func (ctx *Context) DisableItemPickup() {
 	ctx.CurrentGame.PickupItems = false
 }
@@ -152,7 +176,13 @@ func (s *Status) PauseIfNotPriority() {
 		time.Sleep(time.Millisecond * 5)
 	}
 
-	for s.Priority != s.ExecutionPriority {
+	for {
+		if s.Priority == PriorityNormal && s.runCancelled.Load() {
+			panic(ErrRunCancelled)
+		}
+		if s.Priority == s.ExecutionPriority {
+			return
+		}
 		if s.ExecutionPriority == PriorityStop {
 			panic("Bot is stopped")
 		}
@@ -73,12 +73,15 @@ func RunFinished(be BaseEvent, runName string, reason FinishReason) RunFinishedE
 type ItemStashedEvent struct {
 	BaseEvent
 	Item data.Drop
+	// ScreenPosition is where the item was hovered when the screenshot was taken, so the tooltip is around it
+	ScreenPosition data.Position
 }
 
-func ItemStashed(be BaseEvent, drop data.Drop) ItemStashedEvent {
+func ItemStashed(be BaseEvent, drop data.Drop, screenPosition data.Position) ItemStashedEvent {
 	return ItemStashedEvent{
-		BaseEvent: be,
-		Item:      drop,
+		BaseEvent:      be,
+		Item:           drop,
+		ScreenPosition: screenPosition,
 	}
 }
 
@@ -139,3 +142,18 @@ func GamePaused(be BaseEvent, paused bool) GamePausedEvent {
 		Paused:    paused,
 	}
 }
+
+type ConfigReloadedEvent struct {
+	BaseEvent
+	// Applied is false when the new config is rejected, the supervisor keeps running with the previous one
+	Applied bool
+	Errors  []string
+}
+
+func ConfigReloaded(be BaseEvent, applied bool, errs []string) ConfigReloadedEvent {
+	return ConfigReloadedEvent{
+		BaseEvent: be,
+		Applied:   applied,
+		Errors:    errs,
+	}
+}
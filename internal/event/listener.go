@@ -4,94 +4,361 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"math"
-	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
-var events = make(chan Event)
+const (
+	// OverflowDropNewest discards the incoming event when the subscriber queue is full
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued event to make room for the incoming one
+	OverflowDropOldest
+	// OverflowBlock waits until the subscriber has room, delaying delivery to every other subscriber. Only meant for
+	// fast in-memory handlers that can not lose events, like stats.
+	OverflowBlock
+)
+
+const (
+	busSize          = 1024
+	defaultQueueSize = 128
+	drainTimeout     = 5 * time.Second
+)
+
+var (
+	events        = make(chan Event, busSize)
+	droppedOnSend atomic.Uint64
+	// sendTimeout is how long Send waits for room in the bus before dropping an event without screenshot
+	sendTimeout = 5 * time.Second
+)
+
+type OverflowPolicy int
+
+type Handler func(ctx context.Context, e Event) error
 
 type Listener struct {
-	handlers         []Handler
-	deliveryHandlers map[int]Handler
+	mu               sync.Mutex
+	subscribers      map[int]*subscriber
+	deliveryHandlers map[int]chan Event
+	nextID           int
+	closed           bool
+	wg               sync.WaitGroup
+	handlerCtx       context.Context
+	cancelHandlers   context.CancelFunc
 	logger           *slog.Logger
 }
 
-type Handler func(ctx context.Context, e Event) error
+type subscriber struct {
+	mu        sync.RWMutex
+	closed    bool
+	name      string
+	handler   Handler
+	policy    OverflowPolicy
+	queue     chan Event
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// SubscriberMetrics contains the delivery counters of a single subscriber
+type SubscriberMetrics struct {
+	Name      string
+	Queued    int
+	Delivered uint64
+	Dropped   uint64
+	Failed    uint64
+}
+
+type SubscriberOption func(s *subscriber)
+
+// WithName sets the name used in logs and metrics
+func WithName(name string) SubscriberOption {
+	return func(s *subscriber) {
+		s.name = name
+	}
+}
+
+func WithQueueSize(size int) SubscriberOption {
+	return func(s *subscriber) {
+		if size > 0 {
+			s.queue = make(chan Event, size)
+		}
+	}
+}
+
+func WithOverflowPolicy(policy OverflowPolicy) SubscriberOption {
+	return func(s *subscriber) {
+		s.policy = policy
+	}
+}
 
 func NewListener(logger *slog.Logger) *Listener {
-	return &Listener{
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		subscribers:      make(map[int]*subscriber),
+		deliveryHandlers: make(map[int]chan Event),
+		handlerCtx:       handlerCtx,
+		cancelHandlers:   cancel,
 		logger:           logger,
-		deliveryHandlers: make(map[int]Handler),
 	}
+
+	// Saving screenshots to disk is slow, so it runs as any other subscriber instead of blocking the dispatch
+	l.Register(l.saveScreenshot, WithName("screenshots"), WithQueueSize(16))
+
+	return l
 }
 
-func (l *Listener) Register(h Handler) {
-	l.handlers = append(l.handlers, h)
+// Register adds a handler with its own bounded queue and goroutine, so a slow handler doesn't delay the rest.
+// The returned function removes the handler, pending events in its queue are still delivered.
+func (l *Listener) Register(h Handler, opts ...SubscriberOption) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := l.nextID
+	l.nextID++
+	s := &subscriber{
+		name:    fmt.Sprintf("handler-%d", id),
+		handler: h,
+		policy:  OverflowDropNewest,
+		queue:   make(chan Event, defaultQueueSize),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if l.closed {
+		return func() {}
+	}
+
+	l.subscribers[id] = s
+	l.wg.Add(1)
+	go l.consume(s)
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, found := l.subscribers[id]; found && !l.closed {
+			delete(l.subscribers, id)
+			s.close()
+		}
+	}
 }
 
+// Listen dispatches the sent events to every subscriber until ctx is done, then drains the pending events
 func (l *Listener) Listen(ctx context.Context) error {
 	for {
 		select {
 		case e := <-events:
-			if _, err := os.Stat("screenshots"); os.IsNotExist(err) {
-				err = os.MkdirAll("screenshots", os.ModePerm)
-				if err != nil {
-					l.logger.Error("error creating screenshots directory", slog.Any("error", err))
-				}
-			}
+			l.dispatch(e)
+		case <-ctx.Done():
+			l.drain()
+			return nil
+		}
+	}
+}
 
-			if e.Image() != nil && config.Koolo.Debug.Screenshots {
-				fileName := fmt.Sprintf("screenshots/error-%s.jpeg", time.Now().Format("2006-01-02 15_04_05"))
-				err := utils.SaveImageJPEG(e.Image(), fileName)
-				if err != nil {
-					l.logger.Error("error saving screenshot", slog.Any("error", err))
-				}
-			}
+// Metrics returns the delivery counters of every subscriber, including the events dropped on Send
+func (l *Listener) Metrics() []SubscriberMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	metrics := []SubscriberMetrics{{Name: "bus", Queued: len(events), Dropped: droppedOnSend.Load()}}
+	for _, s := range l.subscribers {
+		metrics = append(metrics, SubscriberMetrics{
+			Name:      s.name,
+			Queued:    len(s.queue),
+			Delivered: s.delivered.Load(),
+			Dropped:   s.dropped.Load(),
+			Failed:    s.failed.Load(),
+		})
+	}
+
+	return metrics
+}
+
+func (l *Listener) dispatch(e Event) {
+	l.mu.Lock()
+	subscribers := make([]*subscriber, 0, len(l.subscribers))
+	for _, s := range l.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	for _, ch := range l.deliveryHandlers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	l.mu.Unlock()
+
+	for _, s := range subscribers {
+		l.enqueue(s, e)
+	}
+}
+
+func (l *Listener) enqueue(s *subscriber, e Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-			for _, h := range l.handlers {
-				if err := h(ctx, e); err != nil && e.Message() != "" {
-					l.logger.Error("error running event handler", slog.Any("error", err))
-				}
+	// Subscriber can be unregistered after the dispatch took the subscriber list
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case OverflowBlock:
+		s.queue <- e
+		return
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.queue <- e:
+				return
+			default:
 			}
-			for _, h := range l.deliveryHandlers {
-				if err := h(ctx, e); err != nil {
-					l.logger.Error("error running event delivery handler", slog.Any("error", err))
-				}
+			select {
+			case <-s.queue:
+				l.dropped(s)
+			default:
 			}
+		}
+	default:
+		select {
+		case s.queue <- e:
+		default:
+			l.dropped(s)
+		}
+	}
+}
 
-		case <-ctx.Done():
-			return nil
+func (l *Listener) dropped(s *subscriber) {
+	if s.dropped.Add(1) == 1 {
+		l.logger.Warn("Event subscriber queue is full, dropping events", slog.String("subscriber", s.name))
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	close(s.queue)
+}
+
+func (l *Listener) consume(s *subscriber) {
+	defer l.wg.Done()
+
+	for e := range s.queue {
+		if err := s.handler(l.handlerCtx, e); err != nil {
+			s.failed.Add(1)
+			if e.Message() != "" {
+				l.logger.Error("error running event handler", slog.String("subscriber", s.name), slog.Any("error", err))
+			}
 		}
+		s.delivered.Add(1)
 	}
 }
 
-func (l *Listener) WaitForEvent(ctx context.Context) Event {
-	evtChan := make(chan Event)
-	idx := rand.Intn(math.MaxInt64)
-	l.deliveryHandlers[idx] = func(ctx context.Context, e Event) error {
-		evtChan <- e
+// drain delivers the events still in the bus, then waits for the subscribers to empty their queues
+func (l *Listener) drain() {
+	for len(events) > 0 {
+		l.dispatch(<-events)
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	for id, s := range l.subscribers {
+		s.close()
+		delete(l.subscribers, id)
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+		l.logger.Warn("Timeout draining event subscribers, some events were not delivered")
+	}
+	l.cancelHandlers()
+
+	if dropped := droppedOnSend.Load(); dropped > 0 {
+		l.logger.Warn("Events dropped because the event bus was full", slog.Uint64("dropped", dropped))
+	}
+}
+
+func (l *Listener) saveScreenshot(_ context.Context, e Event) error {
+	if e.Image() == nil || !config.Koolo.Debug.Screenshots {
 		return nil
 	}
+
+	if _, err := os.Stat("screenshots"); os.IsNotExist(err) {
+		err = os.MkdirAll("screenshots", os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("error creating screenshots directory: %w", err)
+		}
+	}
+
+	fileName := fmt.Sprintf("screenshots/error-%s.jpeg", e.OccurredAt().Format("2006-01-02 15_04_05"))
+	if err := utils.SaveImageJPEG(e.Image(), fileName); err != nil {
+		return fmt.Errorf("error saving screenshot: %w", err)
+	}
+
+	return nil
+}
+
+// WaitForEvent blocks until the next event is sent or ctx is done
+func (l *Listener) WaitForEvent(ctx context.Context) Event {
+	evtChan := make(chan Event, 1)
+
+	l.mu.Lock()
+	idx := l.nextID
+	l.nextID++
+	l.deliveryHandlers[idx] = evtChan
+	l.mu.Unlock()
+
 	// Clean up the handler when we're done
 	defer func() {
+		l.mu.Lock()
 		delete(l.deliveryHandlers, idx)
+		l.mu.Unlock()
 	}()
 
-	for {
-		select {
-		case e := <-evtChan:
-			return e
-		case <-ctx.Done():
-			return nil
-		}
+	select {
+	case e := <-evtChan:
+		return e
+	case <-ctx.Done():
+		return nil
 	}
 }
 
+// Send queues the event for dispatching. When the bus is full, events with a screenshot are dropped right away, the
+// rest wait up to sendTimeout for room, as stats need the game and run events in order.
 func Send(e Event) {
-	events <- e
+	select {
+	case events <- e:
+		return
+	default:
+	}
+
+	if e.Image() == nil {
+		timer := time.NewTimer(sendTimeout)
+		defer timer.Stop()
+
+		select {
+		case events <- e:
+			return
+		case <-timer.C:
+		}
+	}
+
+	dropped := droppedOnSend.Add(1)
+	slog.Warn("Event bus is full, event dropped", slog.String("supervisor", e.Supervisor()), slog.String("message", e.Message()), slog.Uint64("dropped", dropped))
 }
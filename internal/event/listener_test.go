@@ -0,0 +1,232 @@
+package event
+
+import (
+	"context"
+	"image"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a handler that keeps the messages of the events it receives, blocking while it's held
+type recorder struct {
+	mu       sync.Mutex
+	messages []string
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (r *recorder) handle(_ context.Context, e Event) error {
+	r.started <- struct{}{}
+	<-r.release
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, e.Message())
+
+	return nil
+}
+
+func (r *recorder) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.messages)
+}
+
+func newTestListener() *Listener {
+	return NewListener(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func metrics(l *Listener, name string) SubscriberMetrics {
+	for _, m := range l.Metrics() {
+		if m.Name == name {
+			return m
+		}
+	}
+
+	return SubscriberMetrics{}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for the condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		expected []string
+		dropped  uint64
+	}{
+		{name: "drop newest", policy: OverflowDropNewest, expected: []string{"1", "2"}, dropped: 1},
+		{name: "drop oldest", policy: OverflowDropOldest, expected: []string{"1", "3"}, dropped: 1},
+		{name: "block", policy: OverflowBlock, expected: []string{"1", "2", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestListener()
+			r := newRecorder()
+			l.Register(r.handle, WithName("recorder"), WithQueueSize(1), WithOverflowPolicy(tt.policy))
+
+			// First event is being handled, the second one fills the queue and the third one overflows
+			l.dispatch(Text("sorc", "1"))
+			<-r.started
+			l.dispatch(Text("sorc", "2"))
+
+			dispatched := make(chan struct{})
+			go func() {
+				l.dispatch(Text("sorc", "3"))
+				close(dispatched)
+			}()
+
+			if tt.policy == OverflowBlock {
+				select {
+				case <-dispatched:
+					t.Fatal("Expected the dispatch to block while the queue is full")
+				case <-time.After(50 * time.Millisecond):
+				}
+			} else {
+				<-dispatched
+			}
+
+			close(r.release)
+			<-dispatched
+			waitFor(t, func() bool { return len(r.received()) == len(tt.expected) })
+
+			if got := r.received(); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected events %v, got %v", tt.expected, got)
+			}
+			if m := metrics(l, "recorder"); m.Dropped != tt.dropped {
+				t.Errorf("Expected %d dropped events, got %d", tt.dropped, m.Dropped)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberDoesNotDelayOthers(t *testing.T) {
+	l := newTestListener()
+	slow := newRecorder()
+	fast := newRecorder()
+	close(fast.release)
+	l.Register(slow.handle, WithName("slow"))
+	l.Register(fast.handle, WithName("fast"))
+
+	for _, msg := range []string{"1", "2", "3"} {
+		l.dispatch(Text("sorc", msg))
+	}
+	waitFor(t, func() bool { return len(fast.received()) == 3 })
+
+	if got := slow.received(); len(got) != 0 {
+		t.Errorf("Expected slow subscriber to be still handling the first event, got %v", got)
+	}
+	if m := metrics(l, "slow"); m.Queued != 2 {
+		t.Errorf("Expected 2 events queued for the slow subscriber, got %d", m.Queued)
+	}
+	close(slow.release)
+}
+
+func TestUnregisterDeliversPendingEvents(t *testing.T) {
+	l := newTestListener()
+	r := newRecorder()
+	unregister := l.Register(r.handle, WithName("recorder"))
+
+	l.dispatch(Text("sorc", "1"))
+	<-r.started
+	l.dispatch(Text("sorc", "2"))
+	unregister()
+	l.dispatch(Text("sorc", "3"))
+	close(r.release)
+
+	waitFor(t, func() bool { return len(r.received()) == 2 })
+	if got := r.received(); !slices.Equal(got, []string{"1", "2"}) {
+		t.Errorf("Expected the events queued before unregistering, got %v", got)
+	}
+	if m := metrics(l, "recorder"); m.Name != "" {
+		t.Errorf("Expected unregistered subscriber not to be in the metrics")
+	}
+}
+
+func TestListenDrainsPendingEvents(t *testing.T) {
+	l := newTestListener()
+	r := newRecorder()
+	close(r.release)
+	l.Register(r.handle, WithName("recorder"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, msg := range []string{"1", "2", "3"} {
+		Send(Text("sorc", msg))
+	}
+
+	// Context is already done, so every event is delivered by the drain
+	if err := l.Listen(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.received(); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("Expected every pending event to be delivered before Listen returns, got %v", got)
+	}
+
+	// Handlers registered after closing the listener never receive events
+	late := newRecorder()
+	l.Register(late.handle)
+	if len(l.Metrics()) != 1 {
+		t.Errorf("Expected only the bus metrics after closing the listener, got %v", l.Metrics())
+	}
+}
+
+func TestSendWhenBusIsFull(t *testing.T) {
+	previous := sendTimeout
+	sendTimeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		sendTimeout = previous
+		for len(events) > 0 {
+			<-events
+		}
+	})
+
+	for len(events) < busSize {
+		Send(Text("sorc", "filler"))
+	}
+	dropped := droppedOnSend.Load()
+
+	// Events with screenshots are dropped right away
+	Send(WithScreenshot("sorc", "error", image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	if droppedOnSend.Load() != dropped+1 {
+		t.Errorf("Expected the screenshot event to be dropped")
+	}
+
+	// The rest wait for room
+	sent := make(chan struct{})
+	go func() {
+		Send(RunStarted(Text("sorc", ""), "pit"))
+		close(sent)
+	}()
+	<-events
+	<-sent
+	if droppedOnSend.Load() != dropped+1 {
+		t.Errorf("Expected the run event to wait for room instead of being dropped")
+	}
+
+	// Until the timeout
+	Send(RunFinished(Text("sorc", ""), "pit", FinishedOK))
+	if droppedOnSend.Load() != dropped+2 {
+		t.Errorf("Expected the run event to be dropped after the timeout")
+	}
+}
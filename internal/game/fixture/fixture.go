@@ -0,0 +1,207 @@
+// Package fixture stores area snapshots to test the map related logic without the game running.
+//
+// A fixture is a gzip compressed JSON document (.json.gz) with the following fields:
+//
+//	version         format version, loading a fixture with a different version fails
+//	area, name      area ID and name
+//	offsetX/Y       position of the top left tile of the grid in game coordinates
+//	width, height   size of the grid
+//	grid            one string per row, one character per tile (see tileChars)
+//	player          player position in game coordinates when the snapshot was taken
+//	rooms, objects, npcs, adjacentLevels, monsters
+//	                same as game.Data, positions in game coordinates
+//
+// The grid is stored after the tiles close to walls have been lowered in priority, so a loaded grid is the same
+// grid the pather used, objects and monsters are not added to it.
+package fixture
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const Version = 1
+
+// tileChars maps every collision type to the character used in the grid rows
+var tileChars = map[game.CollisionType]byte{
+	game.CollisionTypeNonWalkable: '#',
+	game.CollisionTypeWalkable:    '.',
+	game.CollisionTypeLowPriority: '-',
+	game.CollisionTypeMonster:     'm',
+	game.CollisionTypeObject:      'o',
+}
+
+type Fixture struct {
+	Version        int           `json:"version"`
+	Area           area.ID       `json:"area"`
+	Name           string        `json:"name"`
+	OffsetX        int           `json:"offsetX"`
+	OffsetY        int           `json:"offsetY"`
+	Width          int           `json:"width"`
+	Height         int           `json:"height"`
+	Rows           []string      `json:"grid"`
+	Player         data.Position `json:"player"`
+	Rooms          []data.Room   `json:"rooms"`
+	Objects        data.Objects  `json:"objects"`
+	NPCs           data.NPCs     `json:"npcs"`
+	AdjacentLevels []data.Level  `json:"adjacentLevels"`
+	Monsters       data.Monsters `json:"monsters"`
+}
+
+// FromData takes a snapshot of the current area
+func FromData(d game.Data) (Fixture, error) {
+	a := d.AreaData
+	if a.Grid == nil {
+		return Fixture{}, fmt.Errorf("area data is not loaded")
+	}
+
+	rows := make([]string, a.Height)
+	for y, row := range a.CollisionGrid {
+		var sb strings.Builder
+		sb.Grow(len(row))
+		for x, tile := range row {
+			c, found := tileChars[tile]
+			if !found {
+				return Fixture{}, fmt.Errorf("unknown collision type %d at %d,%d", tile, x, y)
+			}
+			sb.WriteByte(c)
+		}
+		rows[y] = sb.String()
+	}
+
+	return Fixture{
+		Version:        Version,
+		Area:           a.Area,
+		Name:           a.Name,
+		OffsetX:        a.OffsetX,
+		OffsetY:        a.OffsetY,
+		Width:          a.Width,
+		Height:         a.Height,
+		Rows:           rows,
+		Player:         d.PlayerUnit.Position,
+		Rooms:          a.Rooms,
+		Objects:        d.Objects,
+		NPCs:           a.NPCs,
+		AdjacentLevels: a.AdjacentLevels,
+		Monsters:       d.Monsters,
+	}, nil
+}
+
+// Grid decodes the grid rows
+func (f Fixture) Grid() (*game.Grid, error) {
+	if len(f.Rows) != f.Height {
+		return nil, fmt.Errorf("expected %d grid rows, got %d", f.Height, len(f.Rows))
+	}
+
+	tiles := make(map[byte]game.CollisionType, len(tileChars))
+	for tile, c := range tileChars {
+		tiles[c] = tile
+	}
+
+	cg := make([][]game.CollisionType, f.Height)
+	for y, row := range f.Rows {
+		if len(row) != f.Width {
+			return nil, fmt.Errorf("expected %d tiles in row %d, got %d", f.Width, y, len(row))
+		}
+		cg[y] = make([]game.CollisionType, f.Width)
+		for x := 0; x < len(row); x++ {
+			tile, found := tiles[row[x]]
+			if !found {
+				return nil, fmt.Errorf("unknown tile %q at %d,%d", row[x], x, y)
+			}
+			cg[y][x] = tile
+		}
+	}
+
+	// Grid is built directly, NewGrid would lower the priority of the tiles again
+	return &game.Grid{OffsetX: f.OffsetX, OffsetY: f.OffsetY, Width: f.Width, Height: f.Height, CollisionGrid: cg}, nil
+}
+
+// Data rebuilds the game data with the player in the snapshot area, only the fields stored in the fixture are set
+func (f Fixture) Data() (game.Data, error) {
+	grid, err := f.Grid()
+	if err != nil {
+		return game.Data{}, err
+	}
+
+	ad := game.AreaData{
+		Area:           f.Area,
+		Name:           f.Name,
+		NPCs:           f.NPCs,
+		AdjacentLevels: f.AdjacentLevels,
+		Objects:        f.Objects,
+		Rooms:          f.Rooms,
+		Grid:           grid,
+	}
+
+	d := game.Data{AreaData: ad, Areas: map[area.ID]game.AreaData{f.Area: ad}}
+	d.PlayerUnit.Area = f.Area
+	d.PlayerUnit.Position = f.Player
+	d.AreaOrigin = data.Position{X: f.OffsetX, Y: f.OffsetY}
+	d.NPCs = f.NPCs
+	d.AdjacentLevels = f.AdjacentLevels
+	d.Rooms = f.Rooms
+	d.Objects = f.Objects
+	d.Monsters = f.Monsters
+
+	return d, nil
+}
+
+func Write(w io.Writer, f Fixture) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(f); err != nil {
+		return fmt.Errorf("error encoding fixture: %w", err)
+	}
+
+	return gz.Close()
+}
+
+func Read(r io.Reader) (Fixture, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("error decompressing fixture: %w", err)
+	}
+	defer gz.Close()
+
+	var f Fixture
+	if err = json.NewDecoder(gz).Decode(&f); err != nil {
+		return Fixture{}, fmt.Errorf("error decoding fixture: %w", err)
+	}
+	if f.Version != Version {
+		return Fixture{}, fmt.Errorf("unsupported fixture version %d, expected %d", f.Version, Version)
+	}
+
+	return f, nil
+}
+
+func Save(path string, f Fixture) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = Write(file, f); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func Load(path string) (Fixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Fixture{}, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
@@ -0,0 +1,98 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func TestRoundTrip(t *testing.T) {
+	cg := [][]game.CollisionType{
+		{game.CollisionTypeNonWalkable, game.CollisionTypeNonWalkable, game.CollisionTypeNonWalkable},
+		{game.CollisionTypeNonWalkable, game.CollisionTypeWalkable, game.CollisionTypeObject},
+		{game.CollisionTypeLowPriority, game.CollisionTypeMonster, game.CollisionTypeWalkable},
+	}
+	d := game.Data{AreaData: game.AreaData{
+		Area:           area.ColdPlains,
+		Name:           "Cold Plains",
+		AdjacentLevels: []data.Level{{Area: area.CaveLevel1, Position: data.Position{X: 101, Y: 201}, IsEntrance: true}},
+		Rooms:          []data.Room{{Position: data.Position{X: 100, Y: 200}, Width: 3, Height: 3}},
+		Grid:           &game.Grid{OffsetX: 100, OffsetY: 200, Width: 3, Height: 3, CollisionGrid: cg},
+	}}
+	d.PlayerUnit.Position = data.Position{X: 101, Y: 201}
+	d.Monsters = data.Monsters{{Name: npc.Zombie, Position: data.Position{X: 101, Y: 202}, Type: data.MonsterTypeChampion}}
+
+	f, err := FromData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = Write(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, loaded) {
+		t.Fatalf("Expected the same fixture after reading it, got %+v", loaded)
+	}
+
+	loadedData, err := loaded.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loadedData.AreaData.CollisionGrid, cg) {
+		t.Errorf("Expected grid %v, got %v", cg, loadedData.AreaData.CollisionGrid)
+	}
+	if loadedData.PlayerUnit.Position != d.PlayerUnit.Position || loadedData.AreaOrigin != (data.Position{X: 100, Y: 200}) {
+		t.Errorf("Expected player at %v and origin at 100,200, got %v and %v", d.PlayerUnit.Position, loadedData.PlayerUnit.Position, loadedData.AreaOrigin)
+	}
+	if !reflect.DeepEqual(loadedData.Monsters, d.Monsters) || !reflect.DeepEqual(loadedData.AdjacentLevels, d.AreaData.AdjacentLevels) {
+		t.Errorf("Expected monsters and adjacent levels to be loaded")
+	}
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Fixture{Version: Version + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Errorf("Expected error reading a fixture with a different version")
+	}
+}
+
+func TestDuranceOfHateFixture(t *testing.T) {
+	f, err := Load("testdata/durance_of_hate_level_3.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid, err := f.Grid()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same grid as the gob encoded one used by the astar tests
+	file, err := os.Open("../../pather/astar/durance_of_hate_grid.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var expected game.Grid
+	if err = gob.NewDecoder(file).Decode(&expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(grid, &expected) {
+		t.Errorf("Expected fixture grid to match durance_of_hate_grid.bin")
+	}
+}
@@ -0,0 +1,134 @@
+package map_client
+
+import (
+	"cmp"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
+)
+
+// cacheVersion is increased every time serverLevel changes, cached files with another version are ignored
+const cacheVersion = 1
+
+type cachedMapData struct {
+	Version int     `json:"version"`
+	Levels  MapData `json:"levels"`
+}
+
+// Cache stores the map data on disk, the map of a seed and difficulty is always the same so it can be reused by any
+// game with the same seed
+type Cache struct {
+	dir        string
+	maxEntries int
+}
+
+// NewCache returns a cache stored in dir, keeping the last maxEntries maps, zero keeps all of them
+func NewCache(dir string, maxEntries int) *Cache {
+	return &Cache{dir: dir, maxEntries: maxEntries}
+}
+
+func (c *Cache) path(seed string, d difficulty.Difficulty) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s_%s.json.gz", strings.ToLower(string(d)), seed))
+}
+
+// Load returns the cached map data, false if the map is not cached or the cached file is outdated
+func (c *Cache) Load(seed string, d difficulty.Difficulty) (MapData, bool, error) {
+	file, err := os.Open(c.path(seed, d))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("error decompressing cached map data: %w", err)
+	}
+	defer gz.Close()
+
+	var cached cachedMapData
+	if err = json.NewDecoder(gz).Decode(&cached); err != nil {
+		return nil, false, fmt.Errorf("error decoding cached map data: %w", err)
+	}
+	if cached.Version != cacheVersion {
+		return nil, false, nil
+	}
+
+	return cached.Levels, true, nil
+}
+
+// Store saves the map data, the file is written to a temporary file first so a partial file is never loaded
+func (c *Cache) Store(seed string, d difficulty.Difficulty, md MapData) error {
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating map cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating map cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	if err = json.NewEncoder(gz).Encode(cachedMapData{Version: cacheVersion, Levels: md}); err != nil {
+		return fmt.Errorf("error encoding map data: %w", err)
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), c.path(seed, d)); err != nil {
+		return fmt.Errorf("error storing map data: %w", err)
+	}
+
+	return c.prune()
+}
+
+// prune removes the oldest maps over the limit
+func (c *Cache) prune() error {
+	if c.maxEntries <= 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json.gz"))
+	if err != nil || len(files) <= c.maxEntries {
+		return err
+	}
+
+	type entry struct {
+		path    string
+		modTime int64
+	}
+	entries := make([]entry, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{path: f, modTime: info.ModTime().UnixNano()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.modTime, b.modTime)
+	})
+
+	for _, e := range entries[:max(0, len(entries)-c.maxEntries)] {
+		if err = os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing cached map data: %w", err)
+		}
+	}
+
+	return nil
+}
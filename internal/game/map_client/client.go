@@ -1,41 +1,59 @@
 package map_client
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
-	"os/exec"
-	"strings"
-	"syscall"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
 	"github.com/hectorgimenez/d2go/pkg/data/npc"
 	"github.com/hectorgimenez/d2go/pkg/data/object"
-	"github.com/hectorgimenez/koolo/internal/config"
 )
 
-func GetMapData(seed string, difficulty difficulty.Difficulty) (MapData, error) {
-	cmd := exec.Command("./tools/koolo-map.exe", config.Koolo.D2LoDPath, "-s", seed, "-d", getDifficultyAsNum(difficulty))
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	stdout, err := cmd.Output()
+// Generator returns the raw koolo-map output for the seed and difficulty, one JSON document per line
+type Generator interface {
+	Generate(seed string, difficulty difficulty.Difficulty) ([]byte, error)
+}
+
+// Client returns the map data of a game, loading it from the cache when the same seed was already played
+type Client struct {
+	generator Generator
+	cache     *Cache
+}
+
+// NewClient returns a client using the generator for the maps not cached, cache can be nil to disable it
+func NewClient(generator Generator, cache *Cache) *Client {
+	return &Client{generator: generator, cache: cache}
+}
+
+// GetMapData returns the levels of the game, and the levels discarded because they are malformed
+func (c *Client) GetMapData(seed string, difficulty difficulty.Difficulty) (MapData, []LevelError, error) {
+	if c.cache != nil {
+		// A broken cache file is not a problem, the map is generated and cached again
+		if lvls, found, err := c.cache.Load(seed, difficulty); err == nil && found {
+			return lvls, nil, nil
+		}
+	}
+
+	stdout, err := c.generator.Generate(seed, difficulty)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching Map data from Diablo II: LoD 1.13c game: %w", err)
+		return nil, nil, err
 	}
 
-	stdoutLines := strings.Split(string(stdout), "\r\n")
+	lvls, invalid, err := Parse(bytes.NewReader(stdout))
+	if err != nil {
+		return nil, invalid, err
+	}
 
-	lvls := make([]serverLevel, 0)
-	for _, line := range stdoutLines {
-		var lvl serverLevel
-		err = json.Unmarshal([]byte(line), &lvl)
-		// Discard empty lines or lines that don't contain level information
-		if err == nil && lvl.Type != "" && len(lvl.Map) > 0 {
-			lvls = append(lvls, lvl)
+	// Maps with malformed levels are not cached, next time we may be luckier
+	if c.cache != nil && len(invalid) == 0 {
+		if err = c.cache.Store(seed, difficulty, lvls); err != nil {
+			return lvls, invalid, fmt.Errorf("error caching map data: %w", err)
 		}
 	}
 
-	return lvls, nil
+	return lvls, invalid, nil
 }
 
 func getDifficultyAsNum(df difficulty.Difficulty) string {
@@ -0,0 +1,176 @@
+package map_client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
+)
+
+// fileGenerator returns koolo-map output stored in testdata, counting how many times the map was generated
+type fileGenerator struct {
+	file  string
+	calls int
+}
+
+func (g *fileGenerator) Generate(_ string, _ difficulty.Difficulty) ([]byte, error) {
+	g.calls++
+	return os.ReadFile(g.file)
+}
+
+func parseFile(t *testing.T, file string) (MapData, []LevelError) {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lvls, invalid, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lvls, invalid
+}
+
+func TestParse(t *testing.T) {
+	lvls, invalid := parseFile(t, "testdata/normal_1234.txt")
+	if len(invalid) != 0 {
+		t.Errorf("Expected no malformed levels, got %v", invalid)
+	}
+	if len(lvls) != 2 {
+		t.Fatalf("Expected 2 levels, got %d", len(lvls))
+	}
+
+	town := lvls[0]
+	if town.ID != int(area.RogueEncampment) || town.Name != "Rogue Encampment" {
+		t.Errorf("Expected Rogue Encampment, got %d %s", town.ID, town.Name)
+	}
+
+	expectedRow := []bool{false, false, true, true, true, true, true, true, false, false}
+	if cg := town.CollisionGrid(); !reflect.DeepEqual(cg[1], expectedRow) {
+		t.Errorf("Expected collision row %v, got %v", expectedRow, cg[1])
+	}
+
+	npcs, exits, objects, rooms := town.NPCsExitsAndObjects()
+	if len(npcs) != 1 || npcs[0].Positions[0] != (data.Position{X: 5003, Y: 6001}) {
+		t.Errorf("Expected Akara at 5003,6001, got %v", npcs)
+	}
+	if len(exits) != 1 || exits[0].Area != area.BloodMoor || exits[0].IsEntrance {
+		t.Errorf("Expected walkable exit to Blood Moor, got %v", exits)
+	}
+	if len(objects) != 1 || len(rooms) != 1 {
+		t.Errorf("Expected 1 object and 1 room, got %d and %d", len(objects), len(rooms))
+	}
+}
+
+func TestParseMalformedLevels(t *testing.T) {
+	lvls, invalid := parseFile(t, "testdata/malformed.txt")
+	if len(lvls) != 2 {
+		t.Errorf("Expected 2 valid levels, got %d", len(lvls))
+	}
+
+	lines := make([]int, 0, len(invalid))
+	for _, e := range invalid {
+		lines = append(lines, e.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 6}) {
+		t.Errorf("Expected malformed levels in lines [3 4 6], got %v: %v", lines, invalid)
+	}
+	if invalid[0].ID != int(area.ColdPlains) {
+		t.Errorf("Expected Cold Plains to be malformed, got %d", invalid[0].ID)
+	}
+}
+
+func TestParseWithoutLevels(t *testing.T) {
+	if _, _, err := Parse(errorReader{}); err == nil {
+		t.Errorf("Expected read errors to be returned")
+	}
+
+	if _, _, err := Parse(strings.NewReader("Koolo map generator, using game files from C:\\Diablo II\r\n\r\n")); err == nil {
+		t.Errorf("Expected error when there are no levels")
+	}
+}
+
+func TestClientCachesMapData(t *testing.T) {
+	dir := t.TempDir()
+	generator := &fileGenerator{file: "testdata/normal_1234.txt"}
+	client := NewClient(generator, NewCache(dir, 0))
+
+	generated, _, err := client.GetMapData("1234", difficulty.Hell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, _, err := client.GetMapData("1234", difficulty.Hell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if generator.calls != 1 {
+		t.Errorf("Expected map to be generated once, got %d", generator.calls)
+	}
+	if !reflect.DeepEqual(generated, cached) {
+		t.Errorf("Expected cached map data to be the same as the generated one")
+	}
+
+	// Other difficulty is a different map
+	if _, _, err = client.GetMapData("1234", difficulty.Normal); err != nil {
+		t.Fatal(err)
+	}
+	if generator.calls != 2 {
+		t.Errorf("Expected map to be generated for the other difficulty, got %d calls", generator.calls)
+	}
+}
+
+func TestClientDoesNotCacheMalformedMaps(t *testing.T) {
+	dir := t.TempDir()
+	generator := &fileGenerator{file: "testdata/malformed.txt"}
+	client := NewClient(generator, NewCache(dir, 0))
+
+	for i := 0; i < 2; i++ {
+		if _, invalid, err := client.GetMapData("1234", difficulty.Normal); err != nil || len(invalid) != 3 {
+			t.Fatalf("Expected 3 malformed levels, got %d: %v", len(invalid), err)
+		}
+	}
+	if generator.calls != 2 {
+		t.Errorf("Expected map to be generated every time, got %d calls", generator.calls)
+	}
+}
+
+func TestCacheKeepsLastEntries(t *testing.T) {
+	dir := t.TempDir()
+	lvls, _ := parseFile(t, "testdata/normal_1234.txt")
+	cache := NewCache(dir, 2)
+
+	for _, seed := range []string{"1", "2", "3"} {
+		if err := cache.Store(seed, difficulty.Normal, lvls); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 2 {
+		t.Errorf("Expected 2 cached maps, got %v", files)
+	}
+
+	// A broken file is reported and not returned
+	if err := os.WriteFile(cache.path("4", difficulty.Normal), []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := cache.Load("4", difficulty.Normal); found || err == nil {
+		t.Errorf("Expected broken cache file to fail, got %v", err)
+	}
+}
+
+type errorReader struct{}
+
+func (errorReader) Read([]byte) (int, error) {
+	return 0, errors.New("read error")
+}
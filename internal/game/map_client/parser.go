@@ -0,0 +1,121 @@
+package map_client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxLineSize is the size of the longest line koolo-map can print, a whole level is printed in a single line
+const maxLineSize = 64 * 1024 * 1024
+
+// LevelError is a level that was discarded because it's malformed
+type LevelError struct {
+	// Line is the line of the koolo-map output, starting from 1
+	Line int
+	ID   int
+	Name string
+	Err  error
+}
+
+func (e LevelError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d, level %d (%s): %s", e.Line, e.ID, e.Name, e.Err)
+}
+
+func (e LevelError) Unwrap() error {
+	return e.Err
+}
+
+// Parse reads the koolo-map output, one JSON document per line. Lines that are not levels are skipped, and malformed
+// levels are discarded and returned as LevelError, so a single broken level doesn't break the whole game.
+func Parse(r io.Reader) (MapData, []LevelError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), maxLineSize)
+
+	lvls := make(MapData, 0)
+	invalid := make([]LevelError, 0)
+	seen := make(map[int]bool)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		// Discard empty lines or lines that are not JSON, like the koolo-map logs
+		if len(raw) == 0 || raw[0] != '{' {
+			continue
+		}
+
+		var lvl serverLevel
+		if err := json.Unmarshal(raw, &lvl); err != nil {
+			invalid = append(invalid, LevelError{Line: line, Err: fmt.Errorf("invalid JSON: %w", err)})
+			continue
+		}
+		// Discard lines that don't contain level information
+		if lvl.Type == "" || lvl.Map == nil {
+			continue
+		}
+
+		err := lvl.validate()
+		if err == nil && seen[lvl.ID] {
+			err = errors.New("duplicated level")
+		}
+		if err != nil {
+			invalid = append(invalid, LevelError{Line: line, ID: lvl.ID, Name: lvl.Name, Err: err})
+			continue
+		}
+
+		seen[lvl.ID] = true
+		lvls = append(lvls, lvl)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, invalid, fmt.Errorf("error reading map data: %w", err)
+	}
+
+	if len(lvls) == 0 {
+		return nil, invalid, errors.New("map data doesn't contain any level")
+	}
+
+	return lvls, invalid, nil
+}
+
+// validate checks that the level can be converted to a collision grid and the map data, positions outside the level
+// are allowed since exits can be placed in the adjacent level
+func (lvl serverLevel) validate() error {
+	if lvl.ID <= 0 {
+		return fmt.Errorf("invalid level id %d", lvl.ID)
+	}
+	if lvl.Size.Width <= 0 || lvl.Size.Height <= 0 {
+		return fmt.Errorf("invalid size %dx%d", lvl.Size.Width, lvl.Size.Height)
+	}
+	if len(lvl.Map) == 0 {
+		return errors.New("empty collision map")
+	}
+	if len(lvl.Map) > lvl.Size.Height {
+		return fmt.Errorf("collision map has %d rows, level height is %d", len(lvl.Map), lvl.Size.Height)
+	}
+
+	for y, row := range lvl.Map {
+		width := 0
+		for _, xs := range row {
+			if xs < 0 {
+				return fmt.Errorf("negative tile count in row %d", y)
+			}
+			width += xs
+		}
+		if width > lvl.Size.Width {
+			return fmt.Errorf("collision map row %d has %d tiles, level width is %d", y, width, lvl.Size.Width)
+		}
+	}
+
+	for _, r := range lvl.Rooms {
+		if r.Width < 0 || r.Height < 0 {
+			return fmt.Errorf("invalid room size %dx%d at %d,%d", r.Width, r.Height, r.X, r.Y)
+		}
+	}
+
+	return nil
+}
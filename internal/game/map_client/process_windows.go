@@ -0,0 +1,30 @@
+package map_client
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+
+	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
+)
+
+// ProcessGenerator runs koolo-map, it needs the Diablo II: LoD 1.13c game files to generate the maps
+type ProcessGenerator struct {
+	exePath   string
+	d2LoDPath string
+}
+
+func NewProcessGenerator(exePath, d2LoDPath string) ProcessGenerator {
+	return ProcessGenerator{exePath: exePath, d2LoDPath: d2LoDPath}
+}
+
+func (g ProcessGenerator) Generate(seed string, difficulty difficulty.Difficulty) ([]byte, error) {
+	cmd := exec.Command(g.exePath, g.d2LoDPath, "-s", seed, "-d", getDifficultyAsNum(difficulty))
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	stdout, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching Map data from Diablo II: LoD 1.13c game: %w", err)
+	}
+
+	return stdout, nil
+}
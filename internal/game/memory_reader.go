@@ -4,12 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
 	"github.com/hectorgimenez/d2go/pkg/memory"
 	"github.com/hectorgimenez/d2go/pkg/utils"
 	"github.com/hectorgimenez/koolo/internal/config"
@@ -18,6 +20,9 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// mapCacheSize is the amount of maps kept on disk, every map is reused for the games with the same seed
+const mapCacheSize = 200
+
 type MemoryReader struct {
 	cfg *config.CharacterCfg
 	*memory.GameReader
@@ -29,7 +34,11 @@ type MemoryReader struct {
 	GameAreaSizeY  int
 	supervisorName string
 	cachedMapData  map[area.ID]AreaData
-	logger         *slog.Logger
+	// cachedMapSeed and cachedMapDifficulty identify the map of cachedMapData
+	cachedMapSeed       uint
+	cachedMapDifficulty difficulty.Difficulty
+	mapClient           *map_client.Client
+	logger              *slog.Logger
 }
 
 func NewGameReader(cfg *config.CharacterCfg, supervisorName string, pid uint32, window win.HWND, logger *slog.Logger) (*MemoryReader, error) {
@@ -43,7 +52,11 @@ func NewGameReader(cfg *config.CharacterCfg, supervisorName string, pid uint32,
 		HWND:           window,
 		supervisorName: supervisorName,
 		cfg:            cfg,
-		logger:         logger,
+		mapClient: map_client.NewClient(
+			map_client.NewProcessGenerator("./tools/koolo-map.exe", config.Koolo.D2LoDPath),
+			map_client.NewCache(filepath.Join("cache", "maps"), mapCacheSize),
+		),
+		logger: logger,
 	}
 
 	gr.updateWindowPositionData()
@@ -51,20 +64,37 @@ func NewGameReader(cfg *config.CharacterCfg, supervisorName string, pid uint32,
 	return gr, nil
 }
 
+// SetCharacterCfg replaces the config returned in the game data
+func (gd *MemoryReader) SetCharacterCfg(cfg *config.CharacterCfg) {
+	gd.cfg = cfg
+}
+
 func (gd *MemoryReader) MapSeed() uint {
 	return gd.mapSeed
 }
 
 func (gd *MemoryReader) FetchMapData() error {
 	d := gd.GameReader.GetData()
-	gd.mapSeed, _ = gd.getMapSeed(d.PlayerUnit.Address)
+	seed, _ := gd.getMapSeed(d.PlayerUnit.Address)
+	mapDifficulty := gd.cfg.Game.Difficulty
+	// Map data may be fetched before planning the game runs, there is no need to load it again
+	gd.mapSeed = seed
+	if gd.cachedMapData != nil && seed == gd.cachedMapSeed && mapDifficulty == gd.cachedMapDifficulty {
+		return nil
+	}
 	t := time.Now()
-	gd.logger.Debug("Fetching map data...", slog.Uint64("seed", uint64(gd.mapSeed)), slog.String("difficulty", string(config.Characters[gd.supervisorName].Game.Difficulty)))
+	gd.logger.Debug("Fetching map data...", slog.Uint64("seed", uint64(gd.mapSeed)), slog.String("difficulty", string(mapDifficulty)))
 
-	mapData, err := map_client.GetMapData(strconv.Itoa(int(gd.mapSeed)), config.Characters[gd.supervisorName].Game.Difficulty)
-	if err != nil {
+	mapData, invalid, err := gd.mapClient.GetMapData(strconv.Itoa(int(gd.mapSeed)), mapDifficulty)
+	for _, lvlErr := range invalid {
+		gd.logger.Warn("Discarded malformed level from map data", slog.String("error", lvlErr.Error()))
+	}
+	if mapData == nil {
 		return fmt.Errorf("error fetching map data: %w", err)
 	}
+	if err != nil {
+		gd.logger.Warn("Map data could not be cached", slog.Any("error", err))
+	}
 
 	areas := make(map[area.ID]AreaData)
 	var mu sync.Mutex
@@ -108,6 +138,8 @@ func (gd *MemoryReader) FetchMapData() error {
 	_ = g.Wait()
 
 	gd.cachedMapData = areas
+	gd.cachedMapSeed = seed
+	gd.cachedMapDifficulty = mapDifficulty
 	gd.logger.Debug("Fetch completed", slog.Int64("ms", time.Since(t).Milliseconds()))
 
 	return nil
@@ -1,7 +1,6 @@
 package astar
 
 import (
-	"container/heap"
 	"math"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
@@ -19,57 +18,60 @@ var directions = []data.Position{
 	{-1, -1}, // Up-Left (Northwest)
 }
 
-type Node struct {
-	data.Position
-	Cost     int
-	Priority int
-	Index    int
-}
-
 func direction(from, to data.Position) (dx, dy int) {
 	dx = to.X - from.X
 	dy = to.Y - from.Y
 	return
 }
 
+// CostFunc returns the cost of moving into the tile at x, y. Non walkable tiles are never passed to it.
+type CostFunc func(x, y int, tile game.CollisionType) int
+
+// TileCost is the default cost of each collision type
+func TileCost(_, _ int, tile game.CollisionType) int {
+	return getCost(tile)
+}
+
 func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, int, bool) {
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-
-	// Use a 2D slice to store the cost of each node
-	costSoFar := make([][]int, g.Width)
-	cameFrom := make([][]data.Position, g.Width)
-	for i := range costSoFar {
-		costSoFar[i] = make([]int, g.Height)
-		cameFrom[i] = make([]data.Position, g.Height)
-		for j := range costSoFar[i] {
-			costSoFar[i][j] = math.MaxInt32
-		}
+	return CalculatePathWithCost(g, start, goal, TileCost)
+}
+
+// CalculatePathWithCost is CalculatePath using the given cost function instead of the default tile costs. It's a plain
+// A* over every tile, jump point search can not be used since it assumes every walkable tile has the same cost. A
+// hierarchical search isn't used either, its abstract graph would have to be built again every time the obstacles
+// change, and building it likely costs as much as the search itself.
+func CalculatePathWithCost(g *game.Grid, start, goal data.Position, cost CostFunc) ([]data.Position, int, bool) {
+	if !inBounds(g, start) || !inBounds(g, goal) {
+		return nil, 0, false
 	}
 
-	startNode := &Node{Position: start, Cost: 0, Priority: heuristic(start, goal)}
-	heap.Push(&pq, startNode)
-	costSoFar[start.X][start.Y] = 0
+	b := getBuffers(g)
+	defer putBuffers(b)
+
+	startIdx := b.index(start)
+	b.setCost(startIdx, 0)
+	b.push(node{idx: int32(startIdx), priority: int32(heuristic(start, goal))})
 
 	neighbors := make([]data.Position, 0, 8)
 
-	for pq.Len() > 0 {
-		current := heap.Pop(&pq).(*Node)
+	for b.len() > 0 {
+		current := b.pop()
+		currentPos := b.position(int(current.idx))
 
 		// Let's build the path if we reached the goal
-		if current.Position == goal {
-			var path []data.Position
-			for p := goal; p != start; p = cameFrom[p.X][p.Y] {
-				path = append([]data.Position{p}, path...)
-			}
-			path = append([]data.Position{start}, path...)
+		if currentPos == goal {
+			path := b.buildPath(startIdx, int(current.idx))
 			return path, len(path), true
 		}
 
-		updateNeighbors(g, current, &neighbors)
+		updateNeighbors(g, currentPos, &neighbors)
 
 		for _, neighbor := range neighbors {
-			newCost := costSoFar[current.X][current.Y] + getCost(g.CollisionGrid[neighbor.Y][neighbor.X])
+			tile := g.CollisionGrid[neighbor.Y][neighbor.X]
+			if tile == game.CollisionTypeNonWalkable {
+				continue
+			}
+			newCost := b.cost(int(current.idx)) + cost(neighbor.X, neighbor.Y, tile)
 
 			// Handicap for changing direction, this prevents zig-zagging around obstacles
 			//curDirX, curDirY := direction(cameFrom[current.X][current.Y], current.Position)
@@ -78,11 +80,12 @@ func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, in
 			//	newCost++
 			//}
 
-			if newCost < costSoFar[neighbor.X][neighbor.Y] {
-				costSoFar[neighbor.X][neighbor.Y] = newCost
+			neighborIdx := b.index(neighbor)
+			if newCost < b.cost(neighborIdx) {
+				b.setCost(neighborIdx, newCost)
+				b.cameFrom[neighborIdx] = current.idx
 				priority := newCost + int(0.5*float64(heuristic(neighbor, goal)))
-				heap.Push(&pq, &Node{Position: neighbor, Cost: newCost, Priority: priority})
-				cameFrom[neighbor.X][neighbor.Y] = current.Position
+				b.push(node{idx: int32(neighborIdx), priority: int32(priority)})
 			}
 		}
 	}
@@ -91,10 +94,10 @@ func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, in
 }
 
 // Get walkable neighbors of a given node
-func updateNeighbors(grid *game.Grid, node *Node, neighbors *[]data.Position) {
+func updateNeighbors(grid *game.Grid, pos data.Position, neighbors *[]data.Position) {
 	*neighbors = (*neighbors)[:0]
 
-	x, y := node.X, node.Y
+	x, y := pos.X, pos.Y
 	gridWidth, gridHeight := grid.Width, grid.Height
 
 	for _, d := range directions {
@@ -106,6 +109,10 @@ func updateNeighbors(grid *game.Grid, node *Node, neighbors *[]data.Position) {
 	}
 }
 
+func inBounds(g *game.Grid, p data.Position) bool {
+	return p.X >= 0 && p.X < g.Width && p.Y >= 0 && p.Y < g.Height
+}
+
 func getCost(tileType game.CollisionType) int {
 	switch tileType {
 	case game.CollisionTypeWalkable:
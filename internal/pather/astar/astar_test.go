@@ -54,3 +54,23 @@ func loadGrid() *game.Grid {
 
 	return &grid
 }
+
+func TestAstarReusesBuffers(t *testing.T) {
+	grid := loadGrid()
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	first, firstDist, _ := CalculatePath(grid, start, goal)
+	// A shorter search in between leaves stale values in the pooled buffers
+	CalculatePath(grid, start, data.Position{X: 330, Y: 690})
+	second, secondDist, found := CalculatePath(grid, start, goal)
+	if !found || firstDist != secondDist || len(first) != len(second) {
+		t.Fatalf("Expected the same path on every call, got distances %d and %d", firstDist, secondDist)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same path on every call, paths differ at %d", i)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package astar
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// buffers contains the search state, reused between searches to avoid allocating grid sized slices on every call.
+// Instead of clearing the slices, every search uses a new generation and nodes from older generations are ignored.
+type buffers struct {
+	width      int
+	generation uint32
+	visited    []uint32
+	costs      []int
+	cameFrom   []int32
+	open       []node
+}
+
+type node struct {
+	idx      int32
+	priority int32
+}
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return &buffers{}
+	},
+}
+
+func getBuffers(g *game.Grid) *buffers {
+	b := bufferPool.Get().(*buffers)
+
+	size := g.Width * g.Height
+	if len(b.visited) < size {
+		b.visited = make([]uint32, size)
+		b.costs = make([]int, size)
+		b.cameFrom = make([]int32, size)
+		b.generation = 0
+	}
+
+	b.width = g.Width
+	b.generation++
+	// Generation overflowed, old values could be taken as current ones
+	if b.generation == 0 {
+		clear(b.visited)
+		b.generation = 1
+	}
+	b.open = b.open[:0]
+
+	return b
+}
+
+func putBuffers(b *buffers) {
+	bufferPool.Put(b)
+}
+
+func (b *buffers) index(p data.Position) int {
+	return p.Y*b.width + p.X
+}
+
+func (b *buffers) position(idx int) data.Position {
+	return data.Position{X: idx % b.width, Y: idx / b.width}
+}
+
+func (b *buffers) cost(idx int) int {
+	if b.visited[idx] != b.generation {
+		return math.MaxInt32
+	}
+
+	return b.costs[idx]
+}
+
+func (b *buffers) setCost(idx, cost int) {
+	b.visited[idx] = b.generation
+	b.costs[idx] = cost
+}
+
+// buildPath follows cameFrom from the goal to the start, cameFrom has to be set for every node except the start
+func (b *buffers) buildPath(startIdx, goalIdx int) []data.Position {
+	length := 1
+	for idx := goalIdx; idx != startIdx; idx = int(b.cameFrom[idx]) {
+		length++
+	}
+
+	path := make([]data.Position, length)
+	for idx, i := goalIdx, length-1; i >= 0; i-- {
+		path[i] = b.position(idx)
+		idx = int(b.cameFrom[idx])
+	}
+
+	return path
+}
+
+// Binary heap on values, same algorithm as container/heap so nodes are popped in the same order it used to be
+func (b *buffers) len() int {
+	return len(b.open)
+}
+
+func (b *buffers) push(n node) {
+	b.open = append(b.open, n)
+	b.up(len(b.open) - 1)
+}
+
+func (b *buffers) pop() node {
+	n := len(b.open) - 1
+	b.open[0], b.open[n] = b.open[n], b.open[0]
+	b.down(0, n)
+
+	popped := b.open[n]
+	b.open = b.open[:n]
+
+	return popped
+}
+
+func (b *buffers) up(j int) {
+	for {
+		i := (j - 1) / 2 // parent
+		if i == j || b.open[j].priority >= b.open[i].priority {
+			break
+		}
+		b.open[i], b.open[j] = b.open[j], b.open[i]
+		j = i
+	}
+}
+
+func (b *buffers) down(i0, n int) {
+	i := i0
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 {
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && b.open[j2].priority < b.open[j1].priority {
+			j = j2 // right child
+		}
+		if b.open[j].priority >= b.open[i].priority {
+			break
+		}
+		b.open[i], b.open[j] = b.open[j], b.open[i]
+		i = j
+	}
+}
@@ -1,25 +0,0 @@
-package astar
-
-type PriorityQueue []*Node
-
-func (pq PriorityQueue) Len() int           { return len(pq) }
-func (pq PriorityQueue) Less(i, j int) bool { return pq[i].Priority < pq[j].Priority }
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].Index = i
-	pq[j].Index = j
-}
-func (pq *PriorityQueue) Push(x interface{}) {
-	n := x.(*Node)
-	n.Index = len(*pq)
-	*pq = append(*pq, n)
-}
-func (pq *PriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	node := old[n-1]
-	old[n-1] = nil
-	node.Index = -1
-	*pq = old[0 : n-1]
-	return node
-}
@@ -0,0 +1,125 @@
+package pather
+
+import (
+	"math"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	monsterDensityRadius = 4
+	eliteProximityRadius = 12
+)
+
+// DangerZone is a circle that paths should avoid, like a boss spawn or a spot full of traps
+type DangerZone struct {
+	Center data.Position
+	Radius int
+	// Weight is added to the tiles inside the zone, the configured dangerZone weight is used when it's 0
+	Weight int
+}
+
+// DefaultPathCosts returns the weights used when they are not configured: the base costs of the collision types, the
+// same ones the astar package uses, and no extra costs. Monster density, elite proximity and danger zones are opt-in
+// per character, so paths only change for the builds configuring them.
+func DefaultPathCosts() config.PathCosts {
+	return config.PathCosts{
+		Monster:       16,
+		Object:        4,
+		WallProximity: 20,
+	}
+}
+
+// PathCosts returns the configured weights, using the defaults for the ones not set
+func PathCosts(cfg *config.CharacterCfg) config.PathCosts {
+	costs := DefaultPathCosts()
+	custom := cfg.Character.PathCosts
+	for _, w := range []struct{ custom, costs *int }{
+		{&custom.Monster, &costs.Monster},
+		{&custom.Object, &costs.Object},
+		{&custom.WallProximity, &costs.WallProximity},
+		{&custom.MonsterDensity, &costs.MonsterDensity},
+		{&custom.EliteProximity, &costs.EliteProximity},
+		{&custom.DangerZone, &costs.DangerZone},
+		{&custom.MonsterFootprint, &costs.MonsterFootprint},
+	} {
+		if *w.custom > 0 {
+			*w.costs = *w.custom
+		}
+	}
+
+	return costs
+}
+
+// WeightedCost calculates the tile costs for a grid with obstacles: the base cost of the collision type plus the
+// cost of the tiles around monsters, elites and danger zones, calculated once for the whole grid.
+type WeightedCost struct {
+	weights config.PathCosts
+	width   int
+	// extra is indexed by y*width+x, nil when there is nothing around
+	extra []int32
+}
+
+func NewWeightedCost(g *game.Grid, weights config.PathCosts, monsters []data.Monster, zones []DangerZone) *WeightedCost {
+	wc := &WeightedCost{weights: weights, width: g.Width}
+
+	add := func(center data.Position, radius int, cost func(distance float64) int) {
+		if wc.extra == nil {
+			wc.extra = make([]int32, g.Width*g.Height)
+		}
+		for y := max(0, center.Y-radius); y <= min(g.Height-1, center.Y+radius); y++ {
+			for x := max(0, center.X-radius); x <= min(g.Width-1, center.X+radius); x++ {
+				distance := math.Sqrt(float64(squaredDistance(center, data.Position{X: x, Y: y})))
+				if distance <= float64(radius) {
+					wc.extra[y*g.Width+x] += int32(cost(distance))
+				}
+			}
+		}
+	}
+
+	for _, m := range monsters {
+		pos := g.RelativePosition(m.Position)
+		if weights.MonsterDensity > 0 {
+			add(pos, monsterDensityRadius, func(float64) int { return weights.MonsterDensity })
+		}
+		if weights.EliteProximity > 0 && m.IsElite() {
+			// Cost grows linearly from the edge of the radius to the elite
+			add(pos, eliteProximityRadius, func(distance float64) int {
+				return int(float64(weights.EliteProximity) * (1 - distance/(eliteProximityRadius+1)))
+			})
+		}
+	}
+
+	for _, z := range zones {
+		weight := z.Weight
+		if weight == 0 {
+			weight = weights.DangerZone
+		}
+		if weight > 0 {
+			add(g.RelativePosition(z.Center), z.Radius, func(float64) int { return weight })
+		}
+	}
+
+	return wc
+}
+
+// Cost is an astar.CostFunc
+func (wc *WeightedCost) Cost(x, y int, tile game.CollisionType) int {
+	cost := 1
+	switch tile {
+	case game.CollisionTypeMonster:
+		cost = wc.weights.Monster
+	case game.CollisionTypeObject:
+		cost = wc.weights.Object
+	case game.CollisionTypeLowPriority:
+		cost = wc.weights.WallProximity
+	}
+
+	if wc.extra != nil {
+		cost += int(wc.extra[y*wc.width+x])
+	}
+
+	return cost
+}
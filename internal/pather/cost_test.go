@@ -0,0 +1,133 @@
+package pather
+
+import (
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+func openGrid(width, height int) *game.Grid {
+	cg := make([][]game.CollisionType, height)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, width)
+		for x := range cg[y] {
+			cg[y][x] = game.CollisionTypeWalkable
+		}
+	}
+
+	return game.NewGrid(cg, 0, 0)
+}
+
+// closestDistanceTo returns how close the path goes to the position
+func closestDistanceTo(path []data.Position, p data.Position) int {
+	closest := -1
+	for _, pos := range path {
+		if d := DistanceFromPoint(pos, p); closest == -1 || d < closest {
+			closest = d
+		}
+	}
+
+	return closest
+}
+
+func TestMeleeAndCasterRoutes(t *testing.T) {
+	grid := openGrid(80, 60)
+	start := data.Position{X: 2, Y: 30}
+	goal := data.Position{X: 77, Y: 30}
+
+	// Elite pack right in the middle of the straight line
+	pack := data.Position{X: 40, Y: 30}
+	monsters := []data.Monster{{Position: pack, Type: data.MonsterTypeChampion}}
+	for _, d := range directions() {
+		monsters = append(monsters, data.Monster{Position: data.Position{X: pack.X + d.X*2, Y: pack.Y + d.Y*2}})
+	}
+	for _, m := range monsters {
+		grid.CollisionGrid[m.Position.Y][m.Position.X] = game.CollisionTypeMonster
+	}
+
+	// Melee builds walk through the packs, casters go around them and keep away from the elites
+	meleeCosts := DefaultPathCosts()
+	meleeCosts.Monster = 8
+	melee := NewWeightedCost(grid, meleeCosts, monsters, nil)
+	meleePath, _, found := astar.CalculatePathWithCost(grid, start, goal, melee.Cost)
+	if !found {
+		t.Fatalf("Expected melee path to be found")
+	}
+
+	casterCosts := DefaultPathCosts()
+	casterCosts.MonsterDensity = 3
+	casterCosts.EliteProximity = 8
+	caster := NewWeightedCost(grid, casterCosts, monsters, nil)
+	casterPath, _, found := astar.CalculatePathWithCost(grid, start, goal, caster.Cost)
+	if !found {
+		t.Fatalf("Expected caster path to be found")
+	}
+
+	if d := closestDistanceTo(meleePath, pack); d > 3 {
+		t.Errorf("Expected melee path to go through the pack, closest distance is %d", d)
+	}
+	if d := closestDistanceTo(casterPath, pack); d < eliteProximityRadius/2 {
+		t.Errorf("Expected caster path to keep away from the pack, closest distance is %d", d)
+	}
+}
+
+func TestDangerZone(t *testing.T) {
+	grid := openGrid(80, 60)
+	start := data.Position{X: 2, Y: 30}
+	goal := data.Position{X: 77, Y: 30}
+	zone := DangerZone{Center: data.Position{X: 40, Y: 30}, Radius: 8}
+
+	costs := DefaultPathCosts()
+	costs.DangerZone = 50
+
+	// Zones use the configured weight, unless they have their own
+	for _, z := range []DangerZone{zone, {Center: zone.Center, Radius: zone.Radius, Weight: blockerCost}} {
+		cost := NewWeightedCost(grid, costs, nil, []DangerZone{z})
+		path, _, _ := astar.CalculatePathWithCost(grid, start, goal, cost.Cost)
+		for _, p := range path {
+			if squaredDistance(p, z.Center) <= z.Radius*z.Radius {
+				t.Fatalf("Expected path to go around the danger zone, %v is inside", p)
+			}
+		}
+	}
+
+	// Danger zones are opt-in
+	cost := NewWeightedCost(grid, DefaultPathCosts(), nil, []DangerZone{zone})
+	if cost.extra != nil {
+		t.Errorf("Expected no extra costs without the danger zone weight")
+	}
+}
+
+func TestDefaultCostsMatchTileCosts(t *testing.T) {
+	// Default costs are the same as the astar package ones, even with monsters around
+	cost := NewWeightedCost(openGrid(1, 1), DefaultPathCosts(), []data.Monster{{Type: data.MonsterTypeChampion}}, nil)
+	for _, tile := range []game.CollisionType{game.CollisionTypeWalkable, game.CollisionTypeLowPriority, game.CollisionTypeMonster, game.CollisionTypeObject} {
+		if got, expected := cost.Cost(0, 0, tile), astar.TileCost(0, 0, tile); got != expected {
+			t.Errorf("Expected cost %d for tile %d, got %d", expected, tile, got)
+		}
+	}
+}
+
+func TestPathCostsOverrides(t *testing.T) {
+	cfg := &config.CharacterCfg{}
+	cfg.Character.Class = "sorceress"
+	cfg.Character.PathCosts.Monster = 8
+	cfg.Character.PathCosts.EliteProximity = 30
+	cfg.Character.PathCosts.MonsterFootprint = 1
+
+	costs := PathCosts(cfg)
+	expected := DefaultPathCosts()
+	expected.Monster = 8
+	expected.EliteProximity = 30
+	expected.MonsterFootprint = 1
+	if costs != expected {
+		t.Errorf("Expected %+v, got %+v", expected, costs)
+	}
+}
+
+func directions() []data.Position {
+	return []data.Position{{X: 0, Y: 1}, {X: 1, Y: 0}, {X: 0, Y: -1}, {X: -1, Y: 0}, {X: 1, Y: 1}, {X: -1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: -1}}
+}
@@ -0,0 +1,59 @@
+package pather
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// snapRadius is how far from a non walkable position we look for a walkable tile, exits and objects are usually
+// placed on non walkable tiles
+const snapRadius = 10
+
+// WalkDistances returns the walking distance, in tiles, from the position to every target, -1 for the targets that
+// can't be reached. Positions are in game coordinates, a single flood of the grid is used for all the targets.
+func WalkDistances(g *game.Grid, from data.Position, targets []data.Position) []int {
+	relative := make([]data.Position, len(targets))
+	indexes := make(map[int32]bool, len(targets))
+	for i, t := range targets {
+		relative[i] = nearestWalkable(g, g.RelativePosition(t))
+		if inGrid(g, relative[i]) {
+			indexes[int32(relative[i].Y*g.Width+relative[i].X)] = true
+		}
+	}
+
+	flood := make([]int32, g.Width*g.Height)
+	queue := make([]int32, 0, 1024)
+	walkDistances(g, nearestWalkable(g, g.RelativePosition(from)), indexes, flood, &queue)
+
+	distances := make([]int, len(targets))
+	for i, p := range relative {
+		distances[i] = -1
+		if inGrid(g, p) {
+			distances[i] = int(flood[p.Y*g.Width+p.X])
+		}
+	}
+
+	return distances
+}
+
+// nearestWalkable returns the closest walkable tile to the position, or the position itself if there is none close
+func nearestWalkable(g *game.Grid, p data.Position) data.Position {
+	if inGrid(g, p) && g.CollisionGrid[p.Y][p.X] != game.CollisionTypeNonWalkable {
+		return p
+	}
+
+	best, bestDistance := p, snapRadius*snapRadius+1
+	for y := p.Y - snapRadius; y <= p.Y+snapRadius; y++ {
+		for x := p.X - snapRadius; x <= p.X+snapRadius; x++ {
+			pos := data.Position{X: x, Y: y}
+			if !inGrid(g, pos) || g.CollisionGrid[y][x] == game.CollisionTypeNonWalkable {
+				continue
+			}
+			if d := squaredDistance(p, pos); d < bestDistance {
+				best, bestDistance = pos, d
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,35 @@
+package pather
+
+import (
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+)
+
+func TestWalkDistances(t *testing.T) {
+	g, _ := serpentineMaze()
+	g.OffsetX, g.OffsetY = 1000, 2000
+
+	from := data.Position{X: 1010, Y: 2005}
+	distances := WalkDistances(g, from, []data.Position{
+		{X: 1020, Y: 2005},
+		// Next corridor, close in straight line but the join is on the other side of the map
+		{X: 1010, Y: 2015},
+		// Wall between the corridors, the closest walkable tile is used
+		{X: 1010, Y: 2009},
+		{X: 1500, Y: 2500},
+	})
+
+	if distances[0] != 10 {
+		t.Errorf("Expected distance 10 in the same corridor, got %d", distances[0])
+	}
+	if distances[1] < 150 {
+		t.Errorf("Expected walking distance to the next corridor to go through the join, got %d", distances[1])
+	}
+	if distances[2] != 2 {
+		t.Errorf("Expected distance 2 to the wall, got %d", distances[2])
+	}
+	if distances[3] != -1 {
+		t.Errorf("Expected unreachable position outside the grid, got %d", distances[3])
+	}
+}
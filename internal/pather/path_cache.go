@@ -0,0 +1,173 @@
+package pather
+
+import (
+	"hash/fnv"
+	"slices"
+	"sync"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// Paths are cached until the obstacles change, this limit keeps the cache small while moving around a crowded area
+const maxCachedPaths = 256
+
+// gridKey identifies a grid with objects and monsters already added as obstacles
+type gridKey struct {
+	area area.ID
+	// source is the collision grid of the area, a new one is loaded every time the area data is refreshed
+	source *game.Grid
+	// merged is the adjacent area merged to the grid when the destination is outside the current area
+	merged    area.ID
+	obstacles uint64
+	weights   config.PathCosts
+}
+
+type pathKey struct {
+	from data.Position
+	to   data.Position
+}
+
+type cachedPath struct {
+	path     Path
+	distance int
+	found    bool
+}
+
+// pathCache keeps the last grid with obstacles and the paths returned on it, both are discarded as soon as the area,
+// the objects or the monsters change. A cached path is the one returned the first time for the same grid and
+// endpoints, which may be the active path repaired by repairActive instead of the path a new search would return.
+type pathCache struct {
+	mu    sync.Mutex
+	key   gridKey
+	grid  *game.Grid
+	cost  *WeightedCost
+	paths map[pathKey]cachedPath
+	// hops is the last teleport path, it's followed until the destination or the map change
+	hops Path
+	// active is the last walking path, it's repaired instead of calculated again when the obstacles change
+	active  Path
+	repairs int
+}
+
+// getGrid returns the cached grid and tile costs for the key, or stores the ones built by the given function
+func (c *pathCache) getGrid(key gridKey, build func() (*game.Grid, *WeightedCost, bool)) (*game.Grid, *WeightedCost, bool) {
+	if c.grid != nil && c.key == key {
+		return c.grid, c.cost, true
+	}
+
+	grid, cost, ok := build()
+	if !ok {
+		return nil, nil, false
+	}
+
+	// Hops don't need to be recalculated every time a monster moves, only when the map changes
+	if c.key.area != key.area || c.key.source != key.source || c.key.merged != key.merged {
+		c.hops = nil
+		c.active = nil
+	}
+	c.key = key
+	c.grid = grid
+	c.cost = cost
+	c.paths = make(map[pathKey]cachedPath)
+
+	return grid, cost, true
+}
+
+func (c *pathCache) getPath(from, to data.Position) (cachedPath, bool) {
+	p, found := c.paths[pathKey{from: from, to: to}]
+	if !found {
+		return cachedPath{}, false
+	}
+
+	// Path is returned as a copy, callers are free to modify it
+	p.path = slices.Clone(p.path)
+
+	return p, true
+}
+
+func (c *pathCache) setPath(from, to data.Position, p cachedPath) {
+	if len(c.paths) >= maxCachedPaths {
+		clear(c.paths)
+	}
+	p.path = slices.Clone(p.path)
+	c.paths[pathKey{from: from, to: to}] = p
+}
+
+// repairActive returns the last walking path, repaired for the current grid, if it goes to the same destination and
+// we are still following it
+func (c *pathCache) repairActive(from, to data.Position) (Path, bool) {
+	if len(c.active) == 0 || c.active.To() != to || c.repairs >= maxPathRepairs {
+		return nil, false
+	}
+
+	path, found := RepairPath(c.grid, c.active, from, c.cost.Cost)
+	if !found {
+		return nil, false
+	}
+	c.active = path
+	c.repairs++
+
+	return slices.Clone(path), true
+}
+
+func (c *pathCache) setActive(path Path) {
+	c.active = slices.Clone(path)
+	c.repairs = 0
+}
+
+// invalidate discards the paths calculated on the current grid, so they are calculated again from scratch
+func (c *pathCache) invalidate() {
+	c.active = nil
+	c.hops = nil
+	if c.paths != nil {
+		clear(c.paths)
+	}
+}
+
+// remainingHops returns the rest of the last teleport path if it goes to the same destination and we landed close
+// to one of its hops
+func (c *pathCache) remainingHops(from, to data.Position) (Path, bool) {
+	if len(c.hops) == 0 || c.hops.To() != to {
+		return nil, false
+	}
+
+	for i := len(c.hops) - 1; i >= 0; i-- {
+		if squaredDistance(c.hops[i], from) <= hopLandingTolerance*hopLandingTolerance {
+			remaining := append(Path{from}, c.hops[i+1:]...)
+			return remaining, true
+		}
+	}
+
+	return nil, false
+}
+
+// obstaclesFingerprint hashes the positions of everything that is added to the grid as an obstacle or has a cost
+func obstaclesFingerprint(objects []data.Object, monsters data.Monsters, zones []DangerZone) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 0, 8)
+	write := func(p data.Position) {
+		buf = buf[:0]
+		buf = append(buf, byte(p.X), byte(p.X>>8), byte(p.X>>16), byte(p.X>>24))
+		buf = append(buf, byte(p.Y), byte(p.Y>>8), byte(p.Y>>16), byte(p.Y>>24))
+		h.Write(buf)
+	}
+
+	for _, o := range objects {
+		write(o.Position)
+	}
+	// Separator, so an object can not be taken as a monster in the same position
+	h.Write([]byte{0xff})
+	for _, m := range monsters {
+		write(m.Position)
+	}
+	h.Write([]byte{0xff})
+	for _, z := range zones {
+		write(z.Center)
+		write(data.Position{X: z.Radius, Y: z.Weight})
+	}
+
+	return h.Sum64()
+}
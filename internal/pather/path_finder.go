@@ -3,12 +3,14 @@ package pather
 import (
 	"fmt"
 	"math"
+	"path/filepath"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/game"
 	"github.com/hectorgimenez/koolo/internal/pather/astar"
+	"github.com/hectorgimenez/koolo/internal/pather/render"
 )
 
 type PathFinder struct {
@@ -16,18 +18,27 @@ type PathFinder struct {
 	data *game.Data
 	hid  *game.HID
 	cfg  *config.CharacterCfg
+
+	cache       pathCache
+	dangerZones map[area.ID][]DangerZone
+	renderer    *render.Renderer
 }
 
-func NewPathFinder(gr *game.MemoryReader, data *game.Data, hid *game.HID, cfg *config.CharacterCfg) *PathFinder {
+func NewPathFinder(supervisorName string, gr *game.MemoryReader, data *game.Data, hid *game.HID, cfg *config.CharacterCfg) *PathFinder {
 	return &PathFinder{
 		gr:   gr,
 		data: data,
 		hid:  hid,
 		cfg:  cfg,
+
+		dangerZones: make(map[area.ID][]DangerZone),
+		renderer:    render.New(filepath.Join("debug", "maps", supervisorName), render.DefaultHistory, render.DefaultInterval),
 	}
 }
 
 func (pf *PathFinder) GetPath(to data.Position) (Path, int, bool) {
+	pf.trackPlayer()
+
 	// First try direct path
 	if path, distance, found := pf.GetPathFrom(pf.data.PlayerUnit.Position, to); found {
 		return path, distance, true
@@ -42,27 +53,94 @@ func (pf *PathFinder) GetPath(to data.Position) (Path, int, bool) {
 }
 
 func (pf *PathFinder) GetPathFrom(from, to data.Position) (Path, int, bool) {
-	a := pf.data.AreaData
+	// Deferred before locking, so the map is rendered once the cache lock is released
+	var frame *render.Frame
+	defer func() { pf.renderMap(frame) }()
+
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
+
+	grid, cost, ok := pf.gridFor(to)
+	if !ok {
+		return nil, 0, false
+	}
 
-	// We don't want to modify the original grid
-	grid := a.Grid.Copy()
+	from = grid.RelativePosition(from)
+	to = grid.RelativePosition(to)
+
+	if cached, found := pf.cache.getPath(from, to); found {
+		return cached.path, cached.distance, cached.found
+	}
+
+	// Following the same destination, only the segments blocked by the new obstacles are calculated again
+	path, found := pf.cache.repairActive(from, to)
+	if !found {
+		path, _, found = astar.CalculatePathWithCost(grid, from, to, cost.Cost)
+		if found {
+			pf.cache.setActive(path)
+		}
+	}
+	distance := len(path)
+	pf.cache.setPath(from, to, cachedPath{path: path, distance: distance, found: found})
+
+	frame = pf.mapFrame(grid, from, to, path, nil)
+
+	return path, distance, found
+}
+
+// gridFor returns the grid with obstacles and the tile costs used to path to the destination, it has to be called
+// holding the cache lock
+func (pf *PathFinder) gridFor(to data.Position) (*game.Grid, *WeightedCost, bool) {
+	a := pf.data.AreaData
 
 	// Lut Gholein map is a bit bugged, we should close this fake path to avoid pathing issues
 	if a.Area == area.LutGholein {
 		a.CollisionGrid[13][210] = game.CollisionTypeNonWalkable
 	}
 
+	zones := pf.dangerZones[a.Area]
+	key := gridKey{
+		area:      a.Area,
+		source:    a.Grid,
+		obstacles: obstaclesFingerprint(a.Objects, pf.data.Monsters, zones),
+		weights:   PathCosts(pf.cfg),
+	}
 	if !a.IsInside(to) {
+		destination, found := pf.adjacentAreaContaining(to)
+		if !found {
+			return nil, nil, false
+		}
+		key.merged = destination
+	}
+
+	// Objects and monsters are only added to the grid when they changed since the last search
+	return pf.cache.getGrid(key, func() (*game.Grid, *WeightedCost, bool) {
+		grid, ok := pf.gridWithObstacles(to, key.weights.MonsterFootprint)
+		if !ok {
+			return nil, nil, false
+		}
+
+		return grid, NewWeightedCost(grid, key.weights, pf.data.Monsters.Enemies(), zones), true
+	})
+}
+
+// gridWithObstacles returns a copy of the area grid, merged with the adjacent area if the destination is outside,
+// with objects and monsters added as obstacles. Every monster takes footprint tiles around its position.
+func (pf *PathFinder) gridWithObstacles(to data.Position, footprint int) (*game.Grid, bool) {
+	a := pf.data.AreaData
+
+	var grid *game.Grid
+	if a.IsInside(to) {
+		// We don't want to modify the original grid
+		grid = a.Grid.Copy()
+	} else {
 		expandedGrid, err := pf.mergeGrids(to)
 		if err != nil {
-			return nil, 0, false
+			return nil, false
 		}
 		grid = expandedGrid
 	}
 
-	from = grid.RelativePosition(from)
-	to = grid.RelativePosition(to)
-
 	// Add objects to the collision grid as obstacles
 	for _, o := range pf.data.AreaData.Objects {
 		if !grid.IsWalkable(o.Position) {
@@ -92,16 +170,60 @@ func (pf *PathFinder) GetPathFrom(from, to data.Position) (Path, int, bool) {
 			continue
 		}
 		relativePos := grid.RelativePosition(m.Position)
-		grid.CollisionGrid[relativePos.Y][relativePos.X] = game.CollisionTypeMonster
+		for y := relativePos.Y - footprint; y <= relativePos.Y+footprint; y++ {
+			for x := relativePos.X - footprint; x <= relativePos.X+footprint; x++ {
+				if !inGrid(grid, data.Position{X: x, Y: y}) || grid.CollisionGrid[y][x] == game.CollisionTypeNonWalkable {
+					continue
+				}
+				grid.CollisionGrid[y][x] = game.CollisionTypeMonster
+			}
+		}
 	}
 
-	path, distance, found := astar.CalculatePath(grid, from, to)
+	return grid, true
+}
+
+// AddDangerZone makes the paths of the current area go around the zone, when possible, until the zones are cleared
+func (pf *PathFinder) AddDangerZone(z DangerZone) {
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
 
-	if config.Koolo.Debug.RenderMap {
-		pf.renderMap(grid, from, to, path)
+	a := pf.data.AreaData.Area
+	pf.dangerZones[a] = append(pf.dangerZones[a], z)
+}
+
+// InvalidatePath forgets the paths calculated on the current grid, the next search starts from scratch
+func (pf *PathFinder) InvalidatePath() {
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
+
+	pf.cache.invalidate()
+}
+
+// SetCharacterCfg replaces the config, grids built with the previous path costs are discarded as the costs are part
+// of the cache key
+func (pf *PathFinder) SetCharacterCfg(cfg *config.CharacterCfg) {
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
+
+	pf.cfg = cfg
+}
+
+func (pf *PathFinder) ClearDangerZones() {
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
+
+	clear(pf.dangerZones)
+}
+
+func (pf *PathFinder) adjacentAreaContaining(to data.Position) (area.ID, bool) {
+	for _, a := range pf.data.AreaData.AdjacentLevels {
+		if pf.data.Areas[a.Area].IsInside(to) {
+			return a.Area, true
+		}
 	}
 
-	return path, distance, found
+	return 0, false
 }
 
 func (pf *PathFinder) mergeGrids(to data.Position) (*game.Grid, error) {
@@ -0,0 +1,310 @@
+// Package render draws the collision grid used by the pather with the game state on top of it, to debug the paths
+// taken by the bot. Every supervisor has its own Renderer, keeping the latest image in memory and a limited history of
+// timestamped images on disk.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	// DefaultHistory is the amount of images kept on disk per supervisor, older ones are removed
+	DefaultHistory = 200
+	// DefaultInterval is the minimum time between two rendered images, paths are calculated many times per second
+	DefaultInterval = 500 * time.Millisecond
+	// maxTrail is the amount of player positions kept for the trail
+	maxTrail = 2000
+	// fileTimeFormat is used to name the images, sorting by name sorts them by time
+	fileTimeFormat = "2006-01-02_15-04-05.000"
+)
+
+var (
+	colorNonWalkable = color.RGBA{A: 255}
+	colorWalkable    = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	colorLowPriority = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	colorObstacle    = color.RGBA{R: 255, G: 170, B: 170, A: 255}
+	colorRoom        = color.RGBA{R: 204, G: 204, A: 255}
+	colorObject      = color.RGBA{R: 160, G: 32, B: 240, A: 255}
+	colorMonster     = color.RGBA{R: 255, A: 255}
+	colorElite       = color.RGBA{R: 255, B: 255, A: 255}
+	colorTrail       = color.RGBA{R: 120, G: 170, B: 255, A: 255}
+	colorPath        = color.RGBA{R: 36, G: 255, A: 255}
+	colorHop         = color.RGBA{R: 255, G: 140, A: 255}
+	colorFrom        = color.RGBA{R: 158, A: 255}
+	colorTo          = color.RGBA{B: 255, A: 255}
+
+	// immunityColors are drawn as small marks above the monsters, in this order
+	immunityColors = []struct {
+		resist stat.Resist
+		color  color.RGBA
+	}{
+		{stat.FireImmune, color.RGBA{R: 255, G: 80, A: 255}},
+		{stat.ColdImmune, color.RGBA{G: 200, B: 255, A: 255}},
+		{stat.LightImmune, color.RGBA{R: 255, G: 220, A: 255}},
+		{stat.PoisonImmune, color.RGBA{G: 160, A: 255}},
+		{stat.MagicImmune, color.RGBA{R: 255, G: 120, B: 200, A: 255}},
+	}
+)
+
+// Frame is everything drawn in one image. Grid, Path, Hops, From and To are relative to the grid, as returned by the
+// pather, the rest of positions are in game coordinates.
+type Frame struct {
+	Grid     *game.Grid
+	Area     area.ID
+	From     data.Position
+	To       data.Position
+	Path     []data.Position
+	Hops     []data.Position
+	Rooms    []data.Room
+	Objects  data.Objects
+	Monsters data.Monsters
+}
+
+type Renderer struct {
+	dir        string
+	maxHistory int
+	interval   time.Duration
+
+	mu         sync.Mutex
+	latest     []byte
+	latestAt   time.Time
+	history    []string
+	trailArea  area.ID
+	trail      []data.Position
+	renderedAt time.Time
+}
+
+// New returns a renderer writing the images to dir, the directory is created with the first image. A maxHistory of
+// zero only keeps the latest image in memory. Images left in dir by previous sessions count as history, so they are
+// removed as new ones are written.
+func New(dir string, maxHistory int, interval time.Duration) *Renderer {
+	return &Renderer{dir: dir, maxHistory: maxHistory, interval: interval, history: existingImages(dir)}
+}
+
+// existingImages returns the images already in dir, oldest first. Files not named like the rendered images are left
+// alone.
+func existingImages(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var images []string
+	for _, e := range entries {
+		name, found := strings.CutSuffix(e.Name(), ".png")
+		if !found || e.IsDir() {
+			continue
+		}
+		if _, err = time.Parse(fileTimeFormat, name); err == nil {
+			images = append(images, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	// ReadDir already sorts by name, and sorting by name sorts them by time
+	return images
+}
+
+// Track adds the player position to the trail, the trail starts again when the area changes
+func (r *Renderer) Track(a area.ID, pos data.Position) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a != r.trailArea {
+		r.trailArea = a
+		r.trail = r.trail[:0]
+	}
+	if len(r.trail) > 0 && r.trail[len(r.trail)-1] == pos {
+		return
+	}
+	if len(r.trail) == maxTrail {
+		r.trail = append(r.trail[:0], r.trail[1:]...)
+	}
+	r.trail = append(r.trail, pos)
+}
+
+// Render draws the frame and stores it as the latest image, frames rendered too close to the previous one are
+// skipped, returning false
+func (r *Renderer) Render(f Frame) (bool, error) {
+	r.mu.Lock()
+	now := time.Now()
+	if now.Sub(r.renderedAt) < r.interval {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.renderedAt = now
+
+	var trail []data.Position
+	if f.Area == r.trailArea {
+		trail = append(trail, r.trail...)
+	}
+	r.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Draw(f, trail)); err != nil {
+		return false, fmt.Errorf("error encoding map: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latest, r.latestAt = buf.Bytes(), now
+
+	return true, r.save(buf.Bytes(), now)
+}
+
+// Latest returns the last rendered image encoded as PNG
+func (r *Renderer) Latest() ([]byte, time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.latest, r.latestAt, r.latest != nil
+}
+
+// save writes the image to the history directory, removing the oldest images over the limit. It has to be called
+// holding the lock.
+func (r *Renderer) save(img []byte, at time.Time) error {
+	if r.maxHistory <= 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(r.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating map directory: %w", err)
+	}
+
+	path := filepath.Join(r.dir, at.Format(fileTimeFormat)+".png")
+	if err := os.WriteFile(path, img, 0o644); err != nil {
+		return fmt.Errorf("error writing map: %w", err)
+	}
+	r.history = append(r.history, path)
+
+	for len(r.history) > r.maxHistory {
+		if err := os.Remove(r.history[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing old map: %w", err)
+		}
+		r.history = r.history[1:]
+	}
+
+	return nil
+}
+
+// Draw draws the frame, one pixel per tile
+func Draw(f Frame, trail []data.Position) *image.RGBA {
+	g := f.Grid
+	img := image.NewRGBA(image.Rect(0, 0, g.Width, g.Height))
+
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			img.SetRGBA(x, y, tileColor(g.CollisionGrid[y][x]))
+		}
+	}
+
+	for _, room := range f.Rooms {
+		origin := g.RelativePosition(room.Position)
+		for x := origin.X; x <= origin.X+room.Width; x++ {
+			img.SetRGBA(x, origin.Y, colorRoom)
+			img.SetRGBA(x, origin.Y+room.Height, colorRoom)
+		}
+		for y := origin.Y; y <= origin.Y+room.Height; y++ {
+			img.SetRGBA(origin.X, y, colorRoom)
+			img.SetRGBA(origin.X+room.Width, y, colorRoom)
+		}
+	}
+
+	for _, p := range trail {
+		p = g.RelativePosition(p)
+		img.SetRGBA(p.X, p.Y, colorTrail)
+	}
+
+	for _, o := range f.Objects {
+		square(img, g.RelativePosition(o.Position), 1, colorObject)
+	}
+
+	for _, m := range f.Monsters.Enemies() {
+		p := g.RelativePosition(m.Position)
+		c := colorMonster
+		if m.IsElite() {
+			c = colorElite
+		}
+		square(img, p, 1, c)
+
+		// One mark per immunity, above the monster
+		x := p.X - 2
+		for _, ic := range immunityColors {
+			if m.IsImmune(ic.resist) {
+				img.SetRGBA(x, p.Y-3, ic.color)
+				img.SetRGBA(x, p.Y-4, ic.color)
+				x += 2
+			}
+		}
+	}
+
+	for _, p := range f.Path {
+		img.SetRGBA(p.X, p.Y, colorPath)
+	}
+
+	for i, p := range f.Hops {
+		if i > 0 {
+			line(img, f.Hops[i-1], p, colorHop)
+		}
+		square(img, p, 1, colorHop)
+	}
+
+	square(img, f.From, 2, colorFrom)
+	square(img, f.To, 2, colorTo)
+
+	return img
+}
+
+func tileColor(t game.CollisionType) color.RGBA {
+	switch t {
+	case game.CollisionTypeWalkable:
+		return colorWalkable
+	case game.CollisionTypeLowPriority:
+		return colorLowPriority
+	case game.CollisionTypeMonster, game.CollisionTypeObject:
+		return colorObstacle
+	default:
+		return colorNonWalkable
+	}
+}
+
+// square draws a filled square centered on the position, pixels outside the image are ignored
+func square(img *image.RGBA, p data.Position, radius int, c color.RGBA) {
+	for y := p.Y - radius; y <= p.Y+radius; y++ {
+		for x := p.X - radius; x <= p.X+radius; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// line draws a straight line between both positions
+func line(img *image.RGBA, from, to data.Position, c color.RGBA) {
+	steps := max(abs(to.X-from.X), abs(to.Y-from.Y))
+	for i := 0; i <= steps; i++ {
+		x := from.X + (to.X-from.X)*i/max(steps, 1)
+		y := from.Y + (to.Y-from.Y)*i/max(steps, 1)
+		img.SetRGBA(x, y, c)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
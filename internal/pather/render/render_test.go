@@ -0,0 +1,148 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func testFrame() Frame {
+	const size = 40
+
+	cg := make([][]game.CollisionType, size)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, size)
+		for x := range cg[y] {
+			cg[y][x] = game.CollisionTypeWalkable
+		}
+	}
+
+	return Frame{
+		Grid: &game.Grid{OffsetX: 100, OffsetY: 200, Width: size, Height: size, CollisionGrid: cg},
+		Area: area.BloodMoor,
+		From: data.Position{X: 2, Y: 2},
+		To:   data.Position{X: 37, Y: 37},
+		Path: []data.Position{{X: 5, Y: 5}, {X: 6, Y: 6}},
+		Hops: []data.Position{{X: 20, Y: 5}, {X: 30, Y: 5}},
+		Monsters: data.Monsters{{
+			Position: data.Position{X: 110, Y: 230},
+			Stats:    map[stat.ID]int{stat.Life: 100, stat.FireResist: 100, stat.ColdResist: 100},
+		}},
+	}
+}
+
+func TestDraw(t *testing.T) {
+	f := testFrame()
+	trail := []data.Position{{X: 125, Y: 215}}
+	img := Draw(f, trail)
+
+	expected := map[data.Position]struct {
+		name  string
+		color any
+	}{
+		{X: 5, Y: 5}:   {"path", colorPath},
+		{X: 25, Y: 5}:  {"line between hops", colorHop},
+		{X: 25, Y: 15}: {"trail", colorTrail},
+		{X: 10, Y: 30}: {"monster", colorMonster},
+		{X: 8, Y: 27}:  {"fire immunity", immunityColors[0].color},
+		{X: 10, Y: 27}: {"cold immunity", immunityColors[1].color},
+		{X: 2, Y: 2}:   {"from", colorFrom},
+		{X: 37, Y: 37}: {"to", colorTo},
+		{X: 15, Y: 15}: {"walkable tile", colorWalkable},
+	}
+	for p, e := range expected {
+		if got := img.RGBAAt(p.X, p.Y); got != e.color {
+			t.Errorf("Expected %s color %v at %v, got %v", e.name, e.color, p, got)
+		}
+	}
+}
+
+func TestRendererKeepsHistory(t *testing.T) {
+	dir := t.TempDir()
+	r := New(dir, 2, 0)
+
+	for i := 0; i < 3; i++ {
+		rendered, err := r.Render(testFrame())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rendered {
+			t.Fatalf("Expected frame %d to be rendered", i)
+		}
+		// Images are named after the time, with millisecond precision
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 images in the history, got %d", len(files))
+	}
+
+	img, _, found := r.Latest()
+	if !found {
+		t.Fatalf("Expected latest image to be found")
+	}
+	if _, err = png.Decode(bytes.NewReader(img)); err != nil {
+		t.Errorf("Expected latest image to be a valid PNG, got %s", err)
+	}
+}
+
+func TestRendererSkipsFramesWithinInterval(t *testing.T) {
+	r := New(t.TempDir(), 0, time.Hour)
+
+	if rendered, _ := r.Render(testFrame()); !rendered {
+		t.Fatalf("Expected first frame to be rendered")
+	}
+	if rendered, _ := r.Render(testFrame()); rendered {
+		t.Errorf("Expected second frame to be skipped")
+	}
+}
+
+func TestRendererTrailRestartsOnAreaChange(t *testing.T) {
+	r := New(t.TempDir(), 0, 0)
+
+	r.Track(area.BloodMoor, data.Position{X: 1, Y: 1})
+	r.Track(area.BloodMoor, data.Position{X: 1, Y: 1})
+	r.Track(area.BloodMoor, data.Position{X: 2, Y: 2})
+	if len(r.trail) != 2 {
+		t.Errorf("Expected 2 positions in the trail, got %d", len(r.trail))
+	}
+
+	r.Track(area.ColdPlains, data.Position{X: 3, Y: 3})
+	if len(r.trail) != 1 {
+		t.Errorf("Expected trail to restart in the new area, got %d positions", len(r.trail))
+	}
+}
+
+func TestRendererPrunesPreviousSessions(t *testing.T) {
+	dir := t.TempDir()
+	previous := []string{"2024-01-01_10-00-00.000.png", "2024-01-01_10-00-01.000.png", "2024-01-01_10-00-02.000.png"}
+	for _, name := range append(previous, "notes.png") {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(dir, 2, 0)
+	if _, err := r.Render(testFrame()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The newest image from the previous session is kept with the new one, unrelated files are not removed
+	for name, expected := range map[string]bool{previous[0]: false, previous[1]: false, previous[2]: true, "notes.png": true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != expected {
+			t.Errorf("Expected %s to be kept %v, got %v", name, expected, err)
+		}
+	}
+}
@@ -1,57 +1,54 @@
 package pather
 
 import (
-	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
-	"os"
+	"log/slog"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/render"
 )
 
-func (pf *PathFinder) renderMap(grid *game.Grid, from, to data.Position, path Path) {
-	img := image.NewRGBA(image.Rect(0, 0, grid.Width, grid.Height))
-	draw.Draw(img, img.Bounds(), img, image.Point{}, draw.Over)
+// MapRenderer returns the renderer with the latest map, images are only rendered when Debug.RenderMap is enabled
+func (pf *PathFinder) MapRenderer() *render.Renderer {
+	return pf.renderer
+}
 
-	pathLocs := map[string]bool{}
-	for _, p := range path {
-		pathLocs[fmt.Sprintf("%d,%d", p.X, p.Y)] = true
+// trackPlayer adds the player position to the trail drawn on the map
+func (pf *PathFinder) trackPlayer() {
+	if config.Koolo.Debug.RenderMap {
+		pf.renderer.Track(pf.data.PlayerUnit.Area, pf.data.PlayerUnit.Position)
 	}
+}
 
-	for x := 0; x < grid.Width; x++ {
-		for y := 0; y < grid.Height; y++ {
-			if pathLocs[fmt.Sprintf("%d,%d", x, y)] {
-				img.Set(x, y, color.RGBA{R: 36, G: 255, B: 0, A: 255})
-			} else {
-				switch grid.CollisionGrid[y][x] {
-				case game.CollisionTypeNonWalkable:
-					img.Set(x, y, color.Black)
-				case game.CollisionTypeWalkable:
-					img.Set(x, y, color.White)
-				case game.CollisionTypeLowPriority:
-					img.Set(x, y, color.RGBA{R: 200, G: 200, B: 200, A: 255}) // Gray
-				case game.CollisionTypeMonster:
-					img.Set(x, y, color.RGBA{R: 255, A: 255}) // Red
-				case game.CollisionTypeObject:
-					img.Set(x, y, color.RGBA{R: 160, G: 32, B: 240, A: 255}) // Purple
-				}
-			}
-		}
+// mapFrame returns the frame with the walking path, or the teleport hops, over the grid used to calculate it, or nil
+// when maps aren't rendered. Positions are relative to the grid.
+func (pf *PathFinder) mapFrame(grid *game.Grid, from, to data.Position, path, hops Path) *render.Frame {
+	if !config.Koolo.Debug.RenderMap {
+		return nil
 	}
 
-	for _, r := range pf.data.Rooms {
-		pos := grid.RelativePosition(r.GetCenter())
-		img.Set(pos.X, pos.Y, color.RGBA{R: 204, G: 204, A: 255}) // Dark yellow
+	return &render.Frame{
+		Grid:     grid,
+		Area:     pf.data.PlayerUnit.Area,
+		From:     from,
+		To:       to,
+		Path:     path,
+		Hops:     hops,
+		Rooms:    pf.data.Rooms,
+		Objects:  pf.data.Objects,
+		Monsters: pf.data.Monsters,
 	}
+}
 
-	img.Set(from.X, from.Y, color.RGBA{R: 158, G: 0, B: 0, A: 255}) // Garnet
-
-	img.Set(to.X, to.Y, color.RGBA{R: 0, G: 0, B: 255, A: 255}) // Blue
+// renderMap draws the frame, if any. Encoding and writing the image is slow, so it's called without holding the cache
+// lock.
+func (pf *PathFinder) renderMap(f *render.Frame) {
+	if f == nil {
+		return
+	}
 
-	outFile, _ := os.Create("cg.png")
-	defer outFile.Close()
-	png.Encode(outFile, img)
+	if _, err := pf.renderer.Render(*f); err != nil {
+		slog.Warn("Failed to render the map", slog.Any("error", err))
+	}
 }
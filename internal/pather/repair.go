@@ -0,0 +1,105 @@
+package pather
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+const (
+	// pathRejoinTolerance is how far from the previous path we can be to keep following it, further away a new path
+	// is calculated
+	pathRejoinTolerance = 4
+	// repairBacktrack is the amount of tiles before the blocker where the detour starts, so it doesn't start glued to it
+	repairBacktrack = 3
+	// repairClearTiles is the amount of free tiles needed after the blocker to join the previous path again
+	repairClearTiles = 3
+	// maxPathRepairs is the amount of times a path is repaired before calculating it again from scratch, repairs keep
+	// the path valid but it may not be the shortest one anymore
+	maxPathRepairs = 10
+)
+
+// RepairPath updates a path calculated on an older grid. The part already walked is dropped, and if a monster or a
+// wall blocks the rest of the path only the blocked segment is calculated again. Positions are relative to the grid,
+// it returns false when from is too far from the path or the blocked segment can't be repaired.
+func RepairPath(g *game.Grid, path Path, from data.Position, cost astar.CostFunc) (Path, bool) {
+	current, found := closestPathIndex(path, from)
+	if !found {
+		return nil, false
+	}
+
+	remaining := make(Path, 0, len(path)-current+1)
+	if path[current] != from {
+		remaining = append(remaining, from)
+	}
+	remaining = append(remaining, path[current:]...)
+
+	blocked := -1
+	for i, p := range remaining {
+		if i > 0 && isBlocked(g, p) {
+			blocked = i
+			break
+		}
+	}
+	if blocked == -1 {
+		return remaining, true
+	}
+
+	// The destination itself is blocked, nothing to repair
+	rejoin := rejoinIndex(g, remaining, blocked)
+	if rejoin == -1 {
+		return nil, false
+	}
+
+	start := max(0, blocked-repairBacktrack)
+	detour, _, found := astar.CalculatePathWithCost(g, remaining[start], remaining[rejoin], cost)
+	if !found {
+		return nil, false
+	}
+
+	repaired := make(Path, 0, start+len(detour)+len(remaining)-rejoin)
+	repaired = append(repaired, remaining[:start]...)
+	repaired = append(repaired, detour...)
+	repaired = append(repaired, remaining[rejoin+1:]...)
+
+	return repaired, true
+}
+
+// closestPathIndex returns the index of the path position closest to from, the latest one on a tie
+func closestPathIndex(path Path, from data.Position) (int, bool) {
+	closest, closestDistance := -1, pathRejoinTolerance*pathRejoinTolerance+1
+	for i, p := range path {
+		if d := squaredDistance(p, from); d <= closestDistance {
+			closest, closestDistance = i, d
+		}
+	}
+
+	return closest, closest != -1
+}
+
+// rejoinIndex returns the first position after the blocker followed by enough free tiles, or the destination
+func rejoinIndex(g *game.Grid, path Path, blocked int) int {
+	clearTiles := 0
+	for i := blocked + 1; i < len(path); i++ {
+		if isBlocked(g, path[i]) {
+			clearTiles = 0
+			continue
+		}
+		clearTiles++
+		if clearTiles == repairClearTiles || i == len(path)-1 {
+			return i - clearTiles + 1
+		}
+	}
+
+	return -1
+}
+
+// isBlocked returns true for the tiles we can't walk through, monsters included
+func isBlocked(g *game.Grid, p data.Position) bool {
+	if !inGrid(g, p) {
+		return true
+	}
+	tile := g.CollisionGrid[p.Y][p.X]
+
+	return tile == game.CollisionTypeNonWalkable || tile == game.CollisionTypeMonster
+}
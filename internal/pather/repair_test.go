@@ -0,0 +1,99 @@
+package pather
+
+import (
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+func assertWalkablePath(t *testing.T, g *game.Grid, path Path, from, to data.Position) {
+	t.Helper()
+	if len(path) == 0 || path.From() != from || path.To() != to {
+		t.Fatalf("Expected path from %v to %v, got %v", from, to, path)
+	}
+	for i, p := range path {
+		if isBlocked(g, p) {
+			t.Errorf("Expected free tiles, got blocked tile %v", p)
+		}
+		if i > 0 && (abs(p.X-path[i-1].X) > 1 || abs(p.Y-path[i-1].Y) > 1) {
+			t.Errorf("Expected adjacent tiles, got %v after %v", p, path[i-1])
+		}
+	}
+}
+
+// crossingPath is the path from the left to the right side of the grid
+func crossingPath(g *game.Grid) Path {
+	path, _, _ := astar.CalculatePath(g, data.Position{X: 2, Y: g.Height / 2}, data.Position{X: g.Width - 3, Y: g.Height / 2})
+
+	return path
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func TestRepairPathDropsWalkedPart(t *testing.T) {
+	grid := openGrid(60, 30)
+	path := crossingPath(grid)
+
+	// A bit off the path, it goes back to it
+	from := data.Position{X: 20, Y: 17}
+	repaired, found := RepairPath(grid, path, from, astar.TileCost)
+	if !found {
+		t.Fatalf("Expected path to be repaired")
+	}
+	assertWalkablePath(t, grid, repaired, from, path.To())
+	if len(repaired) >= len(path) {
+		t.Errorf("Expected walked part to be dropped, got %d positions of %d", len(repaired), len(path))
+	}
+}
+
+func TestRepairPathAroundMonster(t *testing.T) {
+	grid := openGrid(60, 30)
+	path := crossingPath(grid)
+
+	// A monster appears in the middle of the path
+	monster := path[30]
+	for y := monster.Y - 1; y <= monster.Y+1; y++ {
+		for x := monster.X - 1; x <= monster.X+1; x++ {
+			grid.CollisionGrid[y][x] = game.CollisionTypeMonster
+		}
+	}
+
+	from := path[5]
+	repaired, found := RepairPath(grid, path, from, astar.TileCost)
+	if !found {
+		t.Fatalf("Expected path to be repaired")
+	}
+	assertWalkablePath(t, grid, repaired, from, path.To())
+
+	// Only the blocked segment changes
+	for i := 0; i < 20; i++ {
+		if repaired[i] != path[5+i] {
+			t.Errorf("Expected position %d not to change, got %v instead of %v", i, repaired[i], path[5+i])
+		}
+	}
+	if tail := repaired[len(repaired)-20:]; tail[0] != path[len(path)-20] {
+		t.Errorf("Expected the end of the path not to change, got %v", tail)
+	}
+}
+
+func TestRepairPathNotRepaired(t *testing.T) {
+	grid := openGrid(60, 30)
+	path := crossingPath(grid)
+
+	if _, found := RepairPath(grid, path, data.Position{X: 20, Y: 25}, astar.TileCost); found {
+		t.Errorf("Expected no repair when we are far from the path")
+	}
+
+	grid.CollisionGrid[15][57] = game.CollisionTypeMonster
+	if _, found := RepairPath(grid, path, path[5], astar.TileCost); found {
+		t.Errorf("Expected no repair when the destination is blocked")
+	}
+}
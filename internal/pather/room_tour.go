@@ -0,0 +1,319 @@
+package pather
+
+import (
+	"math"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	// Distance used for rooms that can not be reached walking, high enough to leave them for the end of the tour
+	unreachableRoomPenalty = 10
+	// maxTourPasses limits the 2-opt and Or-opt passes, so the tour is improved a bounded time without a time budget
+	maxTourPasses = 50
+)
+
+type TourOptions struct {
+	// TimeBudget stops improving the tour after this time, zero improves it until no better tour is found or after
+	// maxTourPasses
+	TimeBudget time.Duration
+	// Priority rooms are visited before the rest
+	Priority func(data.Room) bool
+}
+
+// RoomTour returns the order to visit all the rooms starting from the given position, using walking distances
+// between rooms. It starts from the nearest neighbour tour and improves it with 2-opt and Or-opt moves.
+func RoomTour(g *game.Grid, start data.Position, rooms []data.Room, opts TourOptions) []data.Room {
+	if len(rooms) < 2 {
+		return rooms
+	}
+
+	var deadline time.Time
+	if opts.TimeBudget > 0 {
+		deadline = time.Now().Add(opts.TimeBudget)
+	}
+
+	dist := roomDistances(g, start, rooms)
+
+	// Index 0 is the start position, rooms are 1..n
+	priority, rest := make([]int, 0), make([]int, 0, len(rooms))
+	for i, r := range rooms {
+		if opts.Priority != nil && opts.Priority(r) {
+			priority = append(priority, i+1)
+		} else {
+			rest = append(rest, i+1)
+		}
+	}
+
+	order := make([]int, 0, len(rooms))
+	from := 0
+	for _, group := range [][]int{priority, rest} {
+		if len(group) == 0 {
+			continue
+		}
+		tour := nearestNeighbourTour(dist, from, group)
+		improveTour(dist, tour, deadline)
+		order = append(order, tour[1:]...)
+		from = tour[len(tour)-1]
+	}
+
+	result := make([]data.Room, 0, len(rooms))
+	for _, idx := range order {
+		result = append(result, rooms[idx-1])
+	}
+
+	return result
+}
+
+// TourLength is the walking distance of the tour from the start position, useful to compare the tour optimizers
+func TourLength(g *game.Grid, start data.Position, rooms []data.Room) int {
+	if len(rooms) == 0 {
+		return 0
+	}
+
+	dist := roomDistances(g, start, rooms)
+	tour := make([]int, len(rooms)+1)
+	for i := range tour {
+		tour[i] = i
+	}
+
+	return tourCost(dist, tour)
+}
+
+// roomDistances returns the walking distance matrix between the start position (index 0) and the rooms
+func roomDistances(g *game.Grid, start data.Position, rooms []data.Room) [][]int {
+	points := make([]data.Position, 0, len(rooms)+1)
+	points = append(points, g.RelativePosition(start))
+	for _, r := range rooms {
+		points = append(points, closestWalkable(g, r, g.RelativePosition(r.GetCenter())))
+	}
+
+	n := len(points)
+	dist := make([][]int, n)
+	for i := range dist {
+		dist[i] = make([]int, n)
+	}
+
+	flood := make([]int32, g.Width*g.Height)
+	queue := make([]int32, 0, 1024)
+	targets := make(map[int32]bool, n)
+	for _, p := range points {
+		if inGrid(g, p) {
+			targets[int32(p.Y*g.Width+p.X)] = true
+		}
+	}
+	for i := 0; i < n; i++ {
+		walkDistances(g, points[i], targets, flood, &queue)
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+
+			p := points[j]
+			if inGrid(g, p) && flood[p.Y*g.Width+p.X] >= 0 {
+				dist[i][j] = int(flood[p.Y*g.Width+p.X])
+			} else {
+				dist[i][j] = DistanceFromPoint(points[i], p) * unreachableRoomPenalty
+			}
+		}
+	}
+
+	// Distances are symmetric on the grid, but unreachable penalties may not be
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := max(dist[i][j], dist[j][i])
+			dist[i][j], dist[j][i] = d, d
+		}
+	}
+
+	return dist
+}
+
+// walkDistances floods the grid from the position, leaving the amount of steps to every tile in flood, -1 for the
+// tiles that can't be reached. It stops as soon as all the targets are reached.
+func walkDistances(g *game.Grid, from data.Position, targets map[int32]bool, flood []int32, queue *[]int32) {
+	for i := range flood {
+		flood[i] = -1
+	}
+	if !inGrid(g, from) || g.CollisionGrid[from.Y][from.X] == game.CollisionTypeNonWalkable {
+		return
+	}
+
+	q := (*queue)[:0]
+	startIdx := int32(from.Y*g.Width + from.X)
+	flood[startIdx] = 0
+	q = append(q, startIdx)
+	pending := len(targets)
+	for head := 0; head < len(q) && pending > 0; head++ {
+		idx := q[head]
+		if targets[idx] {
+			pending--
+		}
+		x, y := int(idx)%g.Width, int(idx)/g.Width
+		for _, d := range [8][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}} {
+			nx, ny := x+d[0], y+d[1]
+			if nx < 0 || ny < 0 || nx >= g.Width || ny >= g.Height || g.CollisionGrid[ny][nx] == game.CollisionTypeNonWalkable {
+				continue
+			}
+			nIdx := int32(ny*g.Width + nx)
+			if flood[nIdx] >= 0 {
+				continue
+			}
+			flood[nIdx] = flood[idx] + 1
+			q = append(q, nIdx)
+		}
+	}
+	*queue = q
+}
+
+// closestWalkable returns the walkable tile inside the room closest to the position, or the position itself
+func closestWalkable(g *game.Grid, r data.Room, p data.Position) data.Position {
+	best, bestDistance := p, math.MaxInt
+	origin := g.RelativePosition(r.Position)
+	for y := origin.Y; y <= origin.Y+r.Height; y++ {
+		for x := origin.X; x <= origin.X+r.Width; x++ {
+			pos := data.Position{X: x, Y: y}
+			if !inGrid(g, pos) || g.CollisionGrid[y][x] == game.CollisionTypeNonWalkable {
+				continue
+			}
+			if d := squaredDistance(p, pos); d < bestDistance {
+				best, bestDistance = pos, d
+			}
+		}
+	}
+
+	return best
+}
+
+func inGrid(g *game.Grid, p data.Position) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < g.Width && p.Y < g.Height
+}
+
+// nearestNeighbourTour returns a tour starting at from and visiting all the nodes, always going to the closest one
+func nearestNeighbourTour(dist [][]int, from int, nodes []int) []int {
+	tour := []int{from}
+	visited := make(map[int]bool, len(nodes))
+	current := from
+	for len(tour) <= len(nodes) {
+		next, nextDistance := -1, math.MaxInt
+		for _, n := range nodes {
+			if !visited[n] && dist[current][n] < nextDistance {
+				next, nextDistance = n, dist[current][n]
+			}
+		}
+		visited[next] = true
+		tour = append(tour, next)
+		current = next
+	}
+
+	return tour
+}
+
+// improveTour applies 2-opt and Or-opt passes until the tour can't be improved, the deadline is reached or after
+// maxTourPasses, the first node of the tour is never moved
+func improveTour(dist [][]int, tour []int, deadline time.Time) {
+	for pass := 0; pass < maxTourPasses; pass++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+
+		// Both passes run even if the first one improves the tour, each one can unblock the other
+		improved := twoOpt(dist, tour, deadline)
+		if orOpt(dist, tour, deadline) || improved {
+			continue
+		}
+
+		return
+	}
+}
+
+// twoOpt reverses every segment that makes the tour shorter, in a single pass over the tour
+func twoOpt(dist [][]int, tour []int, deadline time.Time) bool {
+	improved := false
+	n := len(tour)
+	for i := 1; i < n-1; i++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return improved
+		}
+
+		for j := i + 1; j < n; j++ {
+			a, b, c := tour[i-1], tour[i], tour[j]
+			delta := dist[a][c] - dist[a][b]
+			if j+1 < n {
+				d := tour[j+1]
+				delta += dist[b][d] - dist[c][d]
+			}
+			if delta < 0 {
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					tour[l], tour[r] = tour[r], tour[l]
+				}
+				improved = true
+			}
+		}
+	}
+
+	return improved
+}
+
+// orOpt moves every segment of up to 3 nodes that makes the tour shorter somewhere else, in a single pass over the
+// tour. The gain of each move is calculated from the few edges it changes, not from the whole tour.
+func orOpt(dist [][]int, tour []int, deadline time.Time) bool {
+	improved := false
+	n := len(tour)
+	segment := make([]int, 0, 3)
+	for length := 1; length <= 3; length++ {
+		for i := 1; i+length <= n; i++ {
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return improved
+			}
+
+			first, last := tour[i], tour[i+length-1]
+			prev := tour[i-1]
+			// Cost saved removing the segment, joining its previous and next nodes
+			removed := dist[prev][first]
+			if i+length < n {
+				next := tour[i+length]
+				removed += dist[last][next] - dist[prev][next]
+			}
+
+			// Insert the segment after the node at k, any position out of the segment and its current place
+			for k := 0; k < n; k++ {
+				if k >= i-1 && k < i+length {
+					continue
+				}
+
+				added := dist[tour[k]][first]
+				if k+1 < n {
+					added += dist[last][tour[k+1]] - dist[tour[k]][tour[k+1]]
+				}
+				if added-removed >= 0 {
+					continue
+				}
+
+				segment = append(segment[:0], tour[i:i+length]...)
+				if k < i {
+					copy(tour[k+1+length:i+length], tour[k+1:i])
+					copy(tour[k+1:], segment)
+				} else {
+					copy(tour[i:], tour[i+length:k+1])
+					copy(tour[k+1-length:], segment)
+				}
+				improved = true
+				break
+			}
+		}
+	}
+
+	return improved
+}
+
+func tourCost(dist [][]int, tour []int) int {
+	cost := 0
+	for i := 1; i < len(tour); i++ {
+		cost += dist[tour[i-1]][tour[i]]
+	}
+
+	return cost
+}
@@ -0,0 +1,194 @@
+package pather
+
+import (
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/game/fixture"
+)
+
+// serpentineMaze is a grid with horizontal corridors joined alternately on the right and left sides, rooms along
+// the corridors are close in straight line but far walking
+func serpentineMaze() (*game.Grid, []data.Room) {
+	const width, height, corridors = 100, 50, 5
+
+	cg := make([][]game.CollisionType, height)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, width)
+	}
+
+	rooms := make([]data.Room, 0)
+	for c := 0; c < corridors; c++ {
+		y := 5 + c*10
+		for x := 2; x < width-2; x++ {
+			for dy := -2; dy <= 2; dy++ {
+				cg[y+dy][x] = game.CollisionTypeWalkable
+			}
+		}
+		// Join with the next corridor
+		if c < corridors-1 {
+			x := width - 4
+			if c%2 == 1 {
+				x = 3
+			}
+			for yy := y; yy <= y+10; yy++ {
+				cg[yy][x] = game.CollisionTypeWalkable
+			}
+		}
+		// 4 rooms per corridor
+		for x := 10; x < width-10; x += 20 {
+			rooms = append(rooms, data.Room{Position: data.Position{X: x - 2, Y: y - 2}, Width: 4, Height: 4})
+		}
+	}
+
+	return &game.Grid{Width: width, Height: height, CollisionGrid: cg}, rooms
+}
+
+// straightLineOrder is the previous order, nearest neighbour using straight line distances
+func straightLineOrder(start data.Position, rooms []data.Room) []data.Room {
+	order := make([]data.Room, 0, len(rooms))
+	visited := make(map[int]bool)
+	current := start
+	for len(order) < len(rooms) {
+		next, nextDistance := -1, math.MaxInt
+		for i, r := range rooms {
+			if d := DistanceFromPoint(current, r.GetCenter()); !visited[i] && d < nextDistance {
+				next, nextDistance = i, d
+			}
+		}
+		visited[next] = true
+		order = append(order, rooms[next])
+		current = rooms[next].GetCenter()
+	}
+
+	return order
+}
+
+func TestRoomTourIsShorterThanStraightLine(t *testing.T) {
+	grid, rooms := serpentineMaze()
+	start := data.Position{X: 10, Y: 5}
+
+	tour := RoomTour(grid, start, rooms, TourOptions{})
+	if len(tour) != len(rooms) {
+		t.Fatalf("Expected %d rooms in the tour, got %d", len(rooms), len(tour))
+	}
+
+	tourLength := TourLength(grid, start, tour)
+	straightLength := TourLength(grid, start, straightLineOrder(start, rooms))
+	if tourLength >= straightLength {
+		t.Errorf("Expected tour shorter than the straight line order, got %d and %d", tourLength, straightLength)
+	}
+
+	// Following the corridors is the optimal tour, odd corridors are walked from right to left
+	serpentine := make([]data.Room, 0, len(rooms))
+	for c := 0; c < len(rooms)/4; c++ {
+		corridor := append([]data.Room{}, rooms[c*4:c*4+4]...)
+		if c%2 == 1 {
+			slices.Reverse(corridor)
+		}
+		serpentine = append(serpentine, corridor...)
+	}
+	if optimal := TourLength(grid, start, serpentine); tourLength != optimal {
+		t.Errorf("Expected tour length %d, got %d", optimal, tourLength)
+	}
+}
+
+func TestRoomTourPriority(t *testing.T) {
+	grid, rooms := serpentineMaze()
+	start := data.Position{X: 10, Y: 5}
+	last := rooms[len(rooms)-1]
+
+	tour := RoomTour(grid, start, rooms, TourOptions{Priority: func(r data.Room) bool { return r == last }})
+	if tour[0] != last {
+		t.Errorf("Expected priority room %v first, got %v", last, tour[0])
+	}
+}
+
+func TestRoomTourFixtures(t *testing.T) {
+	files, _ := filepath.Glob("../game/fixture/testdata/*.json.gz")
+	for _, file := range files {
+		f, err := fixture.Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(f.Rooms) < 2 {
+			continue
+		}
+
+		t.Run(f.Name, func(t *testing.T) {
+			grid, err := f.Grid()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tourLength := TourLength(grid, f.Player, RoomTour(grid, f.Player, f.Rooms, TourOptions{}))
+			straightLength := TourLength(grid, f.Player, straightLineOrder(f.Player, f.Rooms))
+			t.Logf("%d rooms, tour length %d, straight line order length %d", len(f.Rooms), tourLength, straightLength)
+			if tourLength > straightLength {
+				t.Errorf("Expected tour not longer than the straight line order, got %d and %d", tourLength, straightLength)
+			}
+		})
+	}
+}
+
+// orOptImproves tells whether any segment move makes the tour shorter, recalculating the whole tour for every move
+func orOptImproves(dist [][]int, tour []int) bool {
+	current := tourCost(dist, tour)
+	for length := 1; length <= 3; length++ {
+		for i := 1; i+length <= len(tour); i++ {
+			rest := append(slices.Clone(tour[:i]), tour[i+length:]...)
+			for j := 1; j <= len(rest); j++ {
+				candidate := slices.Concat(rest[:j], tour[i:i+length], rest[j:])
+				if tourCost(dist, candidate) < current {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func TestImproveTourDeltaCosts(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for run := 0; run < 50; run++ {
+		n := 4 + r.IntN(20)
+		points := make([]data.Position, n)
+		for i := range points {
+			points[i] = data.Position{X: r.IntN(100), Y: r.IntN(100)}
+		}
+		dist := make([][]int, n)
+		for i := range dist {
+			dist[i] = make([]int, n)
+			for j := range dist[i] {
+				dist[i][j] = DistanceFromPoint(points[i], points[j])
+			}
+		}
+
+		tour := make([]int, n)
+		for i := range tour {
+			tour[i] = i
+		}
+		before := tourCost(dist, tour)
+		improveTour(dist, tour, time.Time{})
+
+		if tour[0] != 0 {
+			t.Fatalf("Expected the tour to start at the first node, got %v", tour)
+		}
+		if sorted := slices.Sorted(slices.Values(tour)); sorted[0] != 0 || sorted[n-1] != n-1 || len(slices.Compact(sorted)) != n {
+			t.Fatalf("Expected every node once in the tour, got %v", tour)
+		}
+		if after := tourCost(dist, tour); after > before {
+			t.Errorf("Expected tour not to be longer, got %d from %d", after, before)
+		}
+		if orOptImproves(dist, tour) {
+			t.Errorf("Expected no Or-opt move improving the tour %v", tour)
+		}
+	}
+}
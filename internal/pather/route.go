@@ -0,0 +1,231 @@
+package pather
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// Costs of the route steps, in tiles, so they can be compared with the walking distances
+const (
+	// townPortalCost is the cost of going back to town to use the waypoint
+	townPortalCost = 120
+	// waypointCost is the cost of interacting with the waypoint and loading the destination area
+	waypointCost = 80
+	// entranceCost is the cost of clicking an entrance and loading the next area
+	entranceCost = 20
+)
+
+type RouteStepKind int
+
+const (
+	// RouteStepWaypoint uses the waypoint (going to town first if needed) to go to the area
+	RouteStepWaypoint RouteStepKind = iota
+	// RouteStepWalk moves to the adjacent area, walking or using the entrance
+	RouteStepWalk
+)
+
+func (k RouteStepKind) String() string {
+	if k == RouteStepWaypoint {
+		return "waypoint"
+	}
+
+	return "walk"
+}
+
+type RouteStep struct {
+	Kind RouteStepKind
+	Area area.ID
+}
+
+// Route is the list of steps to reach the destination area, in order
+type Route struct {
+	Steps []RouteStep
+	// Cost is the estimated cost of the whole route, in tiles
+	Cost int
+}
+
+func (r Route) String() string {
+	s := ""
+	for i, step := range r.Steps {
+		if i > 0 {
+			s += " -> "
+		}
+		s += fmt.Sprintf("%s (%s)", step.Area.Area().Name, step.Kind)
+	}
+
+	return s
+}
+
+// LevelGraph connects the areas through their adjacent levels and the waypoints
+type LevelGraph struct {
+	areas     map[area.ID]game.AreaData
+	waypoints []area.ID
+}
+
+// NewLevelGraph builds the graph from the map data, waypoints are the waypoints already discovered
+func NewLevelGraph(areas map[area.ID]game.AreaData, waypoints []area.ID) *LevelGraph {
+	return &LevelGraph{areas: areas, waypoints: waypoints}
+}
+
+type routeNode struct {
+	area area.ID
+	// pos is where we are inside the area, the waypoint or the entrance we came from
+	pos data.Position
+}
+
+type routeItem struct {
+	node routeNode
+	cost int
+}
+
+// routeVisit is the cheapest known way to reach a node
+type routeVisit struct {
+	prev routeNode
+	step RouteStep
+	cost int
+}
+
+// Route calculates the cheapest route from the position to the destination area. Walking distances are estimated as
+// the straight line between the entrance and the exit of every area.
+func (lg *LevelGraph) Route(from area.ID, fromPos data.Position, to area.ID) (Route, bool) {
+	if from == to {
+		return Route{}, true
+	}
+
+	start := routeNode{area: from, pos: fromPos}
+	visited := map[routeNode]routeVisit{start: {}}
+	open := &routeQueue{}
+	heap.Push(open, routeItem{node: start})
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(routeItem)
+		if v := visited[current.node]; current.cost > v.cost {
+			continue
+		}
+
+		if current.node.area == to {
+			return buildRoute(visited, start, current.node), true
+		}
+
+		for _, next := range lg.neighbors(current.node) {
+			cost := current.cost + next.cost
+			if v, found := visited[next.node]; found && v.cost <= cost {
+				continue
+			}
+			visited[next.node] = routeVisit{prev: current.node, step: next.step, cost: cost}
+			heap.Push(open, routeItem{node: next.node, cost: cost})
+		}
+	}
+
+	return Route{}, false
+}
+
+func buildRoute(visited map[routeNode]routeVisit, start, goal routeNode) Route {
+	steps := make([]RouteStep, 0)
+	for n := goal; n != start; n = visited[n].prev {
+		steps = append(steps, visited[n].step)
+	}
+	slices.Reverse(steps)
+
+	return Route{Steps: steps, Cost: visited[goal].cost}
+}
+
+type routeEdge struct {
+	node routeNode
+	step RouteStep
+	cost int
+}
+
+func (lg *LevelGraph) neighbors(n routeNode) []routeEdge {
+	edges := make([]routeEdge, 0)
+
+	// Adjacent levels, we appear next to the exit of the next area that leads back to this one
+	current, found := lg.areas[n.area]
+	if found {
+		for _, lvl := range current.AdjacentLevels {
+			next, found := lg.areas[lvl.Area]
+			if !found {
+				continue
+			}
+
+			cost := DistanceFromPoint(n.pos, lvl.Position)
+			if lvl.IsEntrance {
+				cost += entranceCost
+			}
+
+			edges = append(edges, routeEdge{
+				node: routeNode{area: lvl.Area, pos: EntryPosition(next, n.area, lvl.Position)},
+				step: RouteStep{Kind: RouteStepWalk, Area: lvl.Area},
+				cost: cost,
+			})
+		}
+	}
+
+	// Waypoints, we have to go back to town first unless we are already there
+	wpCost := waypointCost
+	if !n.area.IsTown() {
+		wpCost += townPortalCost
+	}
+	for _, wp := range lg.waypoints {
+		if wp == n.area {
+			continue
+		}
+		if _, found := area.WPAddresses[wp]; !found {
+			continue
+		}
+
+		edges = append(edges, routeEdge{
+			node: routeNode{area: wp, pos: WaypointPosition(lg.areas[wp])},
+			step: RouteStep{Kind: RouteStepWaypoint, Area: wp},
+			cost: wpCost,
+		})
+	}
+
+	return edges
+}
+
+// EntryPosition is where we appear when coming from the previous area, the exit leading back to it
+func EntryPosition(a game.AreaData, prev area.ID, fallback data.Position) data.Position {
+	for _, lvl := range a.AdjacentLevels {
+		if lvl.Area == prev {
+			return lvl.Position
+		}
+	}
+
+	return fallback
+}
+
+// WaypointPosition returns the position of the waypoint of the area, or the center of the area if it's unknown
+func WaypointPosition(a game.AreaData) data.Position {
+	for _, o := range a.Objects {
+		if o.IsWaypoint() {
+			return o.Position
+		}
+	}
+
+	if a.Grid == nil {
+		return data.Position{}
+	}
+
+	return data.Position{X: a.OffsetX + a.Width/2, Y: a.OffsetY + a.Height/2}
+}
+
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int           { return len(q) }
+func (q routeQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q routeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x any)        { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+
+	return item
+}
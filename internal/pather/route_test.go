@@ -0,0 +1,91 @@
+package pather
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// act1Areas is a simplified Act 1: Rogue Encampment -> Blood Moor -> Cold Plains -> Stony Field -> Dark Wood, with
+// Den of Evil as a cave entrance in Blood Moor
+func act1Areas() map[area.ID]game.AreaData {
+	link := func(a area.ID, x, y int, entrance bool) data.Level {
+		return data.Level{Area: a, Position: data.Position{X: x, Y: y}, IsEntrance: entrance}
+	}
+
+	return map[area.ID]game.AreaData{
+		area.RogueEncampment: {Area: area.RogueEncampment, AdjacentLevels: []data.Level{link(area.BloodMoor, 100, 0, false)}},
+		area.BloodMoor: {Area: area.BloodMoor, AdjacentLevels: []data.Level{
+			link(area.RogueEncampment, 100, 0, false),
+			link(area.ColdPlains, 100, 400, false),
+			link(area.DenOfEvil, 300, 200, true),
+		}},
+		area.DenOfEvil: {Area: area.DenOfEvil, AdjacentLevels: []data.Level{link(area.BloodMoor, 300, 200, true)}},
+		area.ColdPlains: {Area: area.ColdPlains, AdjacentLevels: []data.Level{
+			link(area.BloodMoor, 100, 400, false),
+			link(area.StonyField, 100, 800, false),
+		}},
+		area.StonyField: {Area: area.StonyField, AdjacentLevels: []data.Level{
+			link(area.ColdPlains, 100, 800, false),
+			link(area.DarkWood, 500, 800, false),
+		}},
+		area.DarkWood: {Area: area.DarkWood, AdjacentLevels: []data.Level{link(area.StonyField, 500, 800, false)}},
+	}
+}
+
+func assertRoute(t *testing.T, r Route, expected ...RouteStep) {
+	t.Helper()
+	if !slices.Equal(r.Steps, expected) {
+		t.Errorf("Expected route %v, got %v", Route{Steps: expected}, r)
+	}
+}
+
+func TestRouteWalksToCloseAreas(t *testing.T) {
+	g := NewLevelGraph(act1Areas(), []area.ID{area.RogueEncampment, area.StonyField})
+
+	// Stony Field exit is close, walking is cheaper than going back to town
+	r, found := g.Route(area.ColdPlains, data.Position{X: 100, Y: 750}, area.StonyField)
+	if !found {
+		t.Fatalf("Expected route to be found")
+	}
+	assertRoute(t, r, RouteStep{Kind: RouteStepWalk, Area: area.StonyField})
+
+	r, _ = g.Route(area.RogueEncampment, data.Position{X: 100, Y: 10}, area.DenOfEvil)
+	assertRoute(t, r, RouteStep{Kind: RouteStepWalk, Area: area.BloodMoor}, RouteStep{Kind: RouteStepWalk, Area: area.DenOfEvil})
+}
+
+func TestRouteUsesWaypoints(t *testing.T) {
+	g := NewLevelGraph(act1Areas(), []area.ID{area.RogueEncampment, area.StonyField})
+
+	r, found := g.Route(area.RogueEncampment, data.Position{X: 100, Y: 10}, area.DarkWood)
+	if !found {
+		t.Fatalf("Expected route to be found")
+	}
+	assertRoute(t, r, RouteStep{Kind: RouteStepWaypoint, Area: area.StonyField}, RouteStep{Kind: RouteStepWalk, Area: area.DarkWood})
+
+	// Without the waypoint we have to walk all the way
+	g = NewLevelGraph(act1Areas(), []area.ID{area.RogueEncampment})
+	r, _ = g.Route(area.RogueEncampment, data.Position{X: 100, Y: 10}, area.DarkWood)
+	assertRoute(t, r,
+		RouteStep{Kind: RouteStepWalk, Area: area.BloodMoor},
+		RouteStep{Kind: RouteStepWalk, Area: area.ColdPlains},
+		RouteStep{Kind: RouteStepWalk, Area: area.StonyField},
+		RouteStep{Kind: RouteStepWalk, Area: area.DarkWood},
+	)
+}
+
+func TestRouteNotFound(t *testing.T) {
+	g := NewLevelGraph(act1Areas(), []area.ID{area.RogueEncampment})
+
+	if _, found := g.Route(area.RogueEncampment, data.Position{}, area.LutGholein); found {
+		t.Errorf("Expected no route to an area without waypoint nor adjacent levels")
+	}
+
+	r, found := g.Route(area.ColdPlains, data.Position{}, area.ColdPlains)
+	if !found || len(r.Steps) != 0 {
+		t.Errorf("Expected empty route when already in the destination, got %v", r)
+	}
+}
@@ -0,0 +1,158 @@
+package pather
+
+import (
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// RecoveryAction is what to do when the character is stuck, every time it keeps stuck after a recovery the next
+// action is a bit more aggressive
+type RecoveryAction int
+
+const (
+	RecoveryNone RecoveryAction = iota
+	// RecoveryReplan calculates the path again from scratch
+	RecoveryReplan
+	// RecoveryAvoidBlocker makes the paths go around the next tile of the path, something not in the map is blocking it
+	RecoveryAvoidBlocker
+	// RecoverySidestep moves a few tiles to the side of the path
+	RecoverySidestep
+	// RecoveryRandomMovement moves to a random position around the character
+	RecoveryRandomMovement
+	// RecoveryGiveUp means nothing worked, the movement should stop as if it timed out
+	RecoveryGiveUp
+)
+
+func (a RecoveryAction) String() string {
+	switch a {
+	case RecoveryReplan:
+		return "replan"
+	case RecoveryAvoidBlocker:
+		return "avoid blocker"
+	case RecoverySidestep:
+		return "sidestep"
+	case RecoveryRandomMovement:
+		return "random movement"
+	case RecoveryGiveUp:
+		return "give up"
+	default:
+		return "none"
+	}
+}
+
+type timedPosition struct {
+	pos data.Position
+	at  time.Time
+}
+
+// StuckDetector keeps the recent positions of the character, it's stuck when it didn't move far enough during the
+// whole window while it was trying to move
+type StuckDetector struct {
+	window      time.Duration
+	minDistance int
+
+	history []timedPosition
+	level   RecoveryAction
+	// anchor is the position of the last recovery, moving away from it means the recovery worked
+	anchor data.Position
+}
+
+func NewStuckDetector(window time.Duration, minDistance int) *StuckDetector {
+	return &StuckDetector{window: window, minDistance: minDistance}
+}
+
+// Record adds the current position to the history, positions older than the window are discarded
+func (s *StuckDetector) Record(pos data.Position, at time.Time) {
+	if s.level != RecoveryNone && DistanceFromPoint(s.anchor, pos) >= s.minDistance*2 {
+		s.level = RecoveryNone
+	}
+
+	s.history = append(s.history, timedPosition{pos: pos, at: at})
+	expired := 0
+	// Keep the last expired position, it's needed to know if the history covers the whole window
+	for expired+1 < len(s.history) && at.Sub(s.history[expired+1].at) >= s.window {
+		expired++
+	}
+	s.history = s.history[expired:]
+}
+
+// Stuck returns true if all the positions recorded during the window are close to each other
+func (s *StuckDetector) Stuck() bool {
+	if len(s.history) < 2 {
+		return false
+	}
+
+	last := s.history[len(s.history)-1]
+	if last.at.Sub(s.history[0].at) < s.window {
+		return false
+	}
+
+	for _, p := range s.history {
+		if DistanceFromPoint(p.pos, last.pos) >= s.minDistance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NextRecovery returns the recovery action to apply when stuck, RecoveryNone otherwise. The history is cleared after
+// every recovery, so the next one is not returned until the character is stuck for a whole window again.
+func (s *StuckDetector) NextRecovery() RecoveryAction {
+	if !s.Stuck() {
+		return RecoveryNone
+	}
+
+	if s.level < RecoveryGiveUp {
+		s.level++
+	}
+	s.anchor = s.history[len(s.history)-1].pos
+	s.history = s.history[:0]
+
+	return s.level
+}
+
+// Reset forgets the history and the recovery level, to be used when the destination changes
+func (s *StuckDetector) Reset() {
+	s.history = s.history[:0]
+	s.level = RecoveryNone
+}
+
+// SidestepPosition returns a walkable position at the given distance to one side of the path, perpendicular to the
+// direction we are moving. Positions are relative to the grid.
+func SidestepPosition(g *game.Grid, path Path, distance int) (data.Position, bool) {
+	if len(path) < 2 {
+		return data.Position{}, false
+	}
+
+	from := path.From()
+	ahead := path[min(len(path)-1, 3)]
+	dx, dy := sign(ahead.X-from.X), sign(ahead.Y-from.Y)
+	if dx == 0 && dy == 0 {
+		return data.Position{}, false
+	}
+
+	for d := distance; d <= distance*2; d++ {
+		for _, side := range [2]int{1, -1} {
+			p := data.Position{X: from.X - dy*d*side, Y: from.Y + dx*d*side}
+			if !isBlocked(g, p) {
+				return p, true
+			}
+		}
+	}
+
+	return data.Position{}, false
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,70 @@
+package pather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func TestStuckDetector(t *testing.T) {
+	s := NewStuckDetector(2*time.Second, 3)
+	now := time.Now()
+	pos := data.Position{X: 100, Y: 100}
+
+	// Moving is never stuck
+	for i := 0; i < 10; i++ {
+		now = now.Add(500 * time.Millisecond)
+		s.Record(data.Position{X: pos.X + i*2, Y: pos.Y}, now)
+	}
+	if s.Stuck() {
+		t.Fatalf("Expected not stuck while moving")
+	}
+
+	// Standing still for the whole window, every recovery is more aggressive
+	expected := []RecoveryAction{RecoveryReplan, RecoveryAvoidBlocker, RecoverySidestep, RecoveryRandomMovement, RecoveryGiveUp, RecoveryGiveUp}
+	for _, e := range expected {
+		if got := s.NextRecovery(); got != RecoveryNone {
+			t.Fatalf("Expected no recovery before the window is complete, got %s", got)
+		}
+		for i := 0; i <= 4; i++ {
+			now = now.Add(500 * time.Millisecond)
+			s.Record(pos, now)
+		}
+		if got := s.NextRecovery(); got != e {
+			t.Errorf("Expected recovery %s, got %s", e, got)
+		}
+	}
+
+	// Moving away from where we were stuck starts again from the first recovery
+	for i := 0; i <= 4; i++ {
+		now = now.Add(500 * time.Millisecond)
+		s.Record(data.Position{X: pos.X + 10, Y: pos.Y}, now)
+	}
+	if got := s.NextRecovery(); got != RecoveryReplan {
+		t.Errorf("Expected recovery %s after moving, got %s", RecoveryReplan, got)
+	}
+}
+
+func TestSidestepPosition(t *testing.T) {
+	grid := openGrid(30, 30)
+	path := Path{{X: 10, Y: 15}, {X: 11, Y: 15}, {X: 12, Y: 15}, {X: 13, Y: 15}}
+
+	p, found := SidestepPosition(grid, path, 4)
+	if !found || p.X != 10 || abs(p.Y-15) != 4 {
+		t.Errorf("Expected position 4 tiles to the side of the path, got %v", p)
+	}
+
+	// One side is a wall, the other side is used
+	for x := 0; x < 30; x++ {
+		for y := 0; y < 15; y++ {
+			grid.CollisionGrid[y][x] = game.CollisionTypeNonWalkable
+		}
+	}
+	grid.CollisionGrid[15+4][10] = game.CollisionTypeNonWalkable
+	p, found = SidestepPosition(grid, path, 4)
+	if !found || p != (data.Position{X: 10, Y: 20}) {
+		t.Errorf("Expected position %v, got %v", data.Position{X: 10, Y: 20}, p)
+	}
+}
@@ -0,0 +1,203 @@
+package pather
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/render"
+)
+
+const (
+	// TeleportRange is the max distance of a single hop, short enough to keep the landing position inside the game
+	// area (and out of the HUD) in any direction
+	TeleportRange = 16
+
+	// Landing positions are taken from a lattice instead of every tile, it keeps the search fast on big areas
+	// while the hops are still close to the max range
+	teleportLatticeStep = 2
+
+	// Cost of a hop, the travelled distance is added on top of it, so the fewest hops are preferred and the shortest
+	// distance is used to break the ties
+	hopCost = 1000
+
+	// Teleport doesn't always land on the exact tile, a planned path is still followed if we landed this close to a hop
+	hopLandingTolerance = 3
+)
+
+// GetTeleportPath returns the hops to teleport from the player position to the destination, see TeleportPath
+func (pf *PathFinder) GetTeleportPath(to data.Position) (Path, int, bool) {
+	pf.trackPlayer()
+
+	return pf.GetTeleportPathFrom(pf.data.PlayerUnit.Position, to)
+}
+
+func (pf *PathFinder) GetTeleportPathFrom(from, to data.Position) (Path, int, bool) {
+	// Deferred before locking, so the map is rendered once the cache lock is released
+	var frame *render.Frame
+	defer func() { pf.renderMap(frame) }()
+
+	pf.cache.mu.Lock()
+	defer pf.cache.mu.Unlock()
+
+	grid, _, found := pf.gridFor(to)
+	if !found {
+		return nil, 0, false
+	}
+
+	from = grid.RelativePosition(from)
+	to = grid.RelativePosition(to)
+
+	if hops, found := pf.cache.remainingHops(from, to); found {
+		return hops, len(hops) - 1, true
+	}
+
+	path, found := TeleportPath(grid, from, to, TeleportRange)
+	if !found {
+		return nil, 0, false
+	}
+	pf.cache.hops = path
+	frame = pf.mapFrame(grid, from, to, nil, path)
+
+	return slices.Clone(path), len(path) - 1, true
+}
+
+// TeleportPath calculates the minimum amount of hops to go from start to goal, every hop is at most teleportRange
+// tiles away from the previous one. Non walkable tiles between hops are ignored, but hops only land on walkable
+// tiles. Positions are relative to the grid, and the returned path contains start and goal, so the amount of hops is
+// len(path)-1.
+func TeleportPath(g *game.Grid, start, goal data.Position, teleportRange int) (Path, bool) {
+	if !isLandable(g, start, start) || !isLandable(g, goal, goal) || teleportRange <= 0 {
+		return nil, false
+	}
+	if start == goal {
+		return Path{start}, true
+	}
+
+	costs := map[data.Position]int{start: 0}
+	cameFrom := make(map[data.Position]data.Position)
+	open := &hopQueue{}
+	heap.Push(open, hop{pos: start, priority: hopHeuristic(start, goal, teleportRange)})
+
+	rangeSquared := teleportRange * teleportRange
+	for open.Len() > 0 {
+		current := heap.Pop(open).(hop)
+		if current.pos == goal {
+			return buildHops(cameFrom, start, goal), true
+		}
+
+		currentCost := costs[current.pos]
+		if current.priority != currentCost+hopHeuristic(current.pos, goal, teleportRange) {
+			// Outdated entry, a cheaper way to this position has been found after pushing it
+			continue
+		}
+
+		visit := func(next data.Position, squared int) {
+			newCost := currentCost + hopCost + int(math.Sqrt(float64(squared)))
+			if cost, found := costs[next]; found && cost <= newCost {
+				return
+			}
+			costs[next] = newCost
+			cameFrom[next] = current.pos
+			heap.Push(open, hop{pos: next, priority: newCost + hopHeuristic(next, goal, teleportRange)})
+		}
+
+		if squared := squaredDistance(current.pos, goal); squared <= rangeSquared {
+			visit(goal, squared)
+		}
+
+		// Lattice positions in range, starting from the first multiple of the step
+		minX := ceilToStep(current.pos.X - teleportRange)
+		minY := ceilToStep(current.pos.Y - teleportRange)
+		for y := minY; y <= current.pos.Y+teleportRange; y += teleportLatticeStep {
+			for x := minX; x <= current.pos.X+teleportRange; x += teleportLatticeStep {
+				next := data.Position{X: x, Y: y}
+				squared := squaredDistance(current.pos, next)
+				if squared == 0 || squared > rangeSquared || !isLandable(g, next, goal) {
+					continue
+				}
+				visit(next, squared)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// isLandable returns true for tiles where we want to land, monsters and objects are avoided unless they are the
+// destination itself
+func isLandable(g *game.Grid, p, goal data.Position) bool {
+	if p.X < 0 || p.Y < 0 || p.X >= g.Width || p.Y >= g.Height {
+		return false
+	}
+
+	switch g.CollisionGrid[p.Y][p.X] {
+	case game.CollisionTypeWalkable, game.CollisionTypeLowPriority:
+		return true
+	case game.CollisionTypeNonWalkable:
+		return false
+	default:
+		return p == goal
+	}
+}
+
+// hopHeuristic never overestimates: at least ceil(distance/range) hops are needed to cover the straight line distance
+func hopHeuristic(from, to data.Position, teleportRange int) int {
+	distance := math.Sqrt(float64(squaredDistance(from, to)))
+	hops := int(math.Ceil(distance / float64(teleportRange)))
+
+	return hops*hopCost + int(distance)
+}
+
+func squaredDistance(a, b data.Position) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return dx*dx + dy*dy
+}
+
+func ceilToStep(v int) int {
+	r := v % teleportLatticeStep
+	if r < 0 {
+		r += teleportLatticeStep
+	}
+	if r == 0 {
+		return v
+	}
+
+	return v + teleportLatticeStep - r
+}
+
+func buildHops(cameFrom map[data.Position]data.Position, start, goal data.Position) Path {
+	path := Path{goal}
+	for p := goal; p != start; {
+		p = cameFrom[p]
+		path = append(path, p)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+}
+
+type hop struct {
+	pos      data.Position
+	priority int
+}
+
+type hopQueue []hop
+
+func (q hopQueue) Len() int           { return len(q) }
+func (q hopQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q hopQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *hopQueue) Push(x any)        { *q = append(*q, x.(hop)) }
+func (q *hopQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+
+	return item
+}
@@ -0,0 +1,107 @@
+package pather
+
+import (
+	"encoding/gob"
+	"os"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+func TestTeleportPath(t *testing.T) {
+	grid := loadGrid(t, "astar/durance_of_hate_grid.bin")
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	hops, found := TeleportPath(grid, start, goal, TeleportRange)
+	if !found {
+		t.Fatalf("Expected teleport path to be found")
+	}
+	assertValidHops(t, grid, hops, start, goal, TeleportRange)
+
+	// Straight line distance is the lower bound, walking path is the upper one
+	minHops := (DistanceFromPoint(start, goal) + TeleportRange - 1) / TeleportRange
+	_, walkDistance, _ := astar.CalculatePath(grid, start, goal)
+	if len(hops)-1 < minHops || len(hops)-1 > walkDistance/TeleportRange+1 {
+		t.Errorf("Expected between %d and %d hops, got %d", minHops, walkDistance/TeleportRange+1, len(hops)-1)
+	}
+}
+
+func TestTeleportPathOverWall(t *testing.T) {
+	// Two rooms split by a wall without doors, there is no walking path
+	cg := make([][]game.CollisionType, 20)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, 60)
+		for x := range cg[y] {
+			if x < 28 || x >= 33 {
+				cg[y][x] = game.CollisionTypeWalkable
+			}
+		}
+	}
+	grid := game.NewGrid(cg, 0, 0)
+
+	start := data.Position{X: 2, Y: 10}
+	goal := data.Position{X: 57, Y: 10}
+
+	if _, _, found := astar.CalculatePath(grid, start, goal); found {
+		t.Fatalf("Expected no walking path between both rooms")
+	}
+
+	hops, found := TeleportPath(grid, start, goal, TeleportRange)
+	if !found {
+		t.Fatalf("Expected teleport path to be found")
+	}
+	assertValidHops(t, grid, hops, start, goal, TeleportRange)
+	if len(hops)-1 != 4 {
+		t.Errorf("Expected 4 hops, got %d: %v", len(hops)-1, hops)
+	}
+
+	// The wall is wider than the teleport range
+	if _, found = TeleportPath(grid, start, goal, 4); found {
+		t.Errorf("Expected no teleport path with a range shorter than the wall")
+	}
+}
+
+func TestTeleportPathToNonWalkable(t *testing.T) {
+	grid := loadGrid(t, "astar/durance_of_hate_grid.bin")
+
+	if _, found := TeleportPath(grid, data.Position{X: 336, Y: 701}, data.Position{X: 0, Y: 0}, TeleportRange); found {
+		t.Errorf("Expected no teleport path to a non walkable tile")
+	}
+}
+
+func assertValidHops(t *testing.T, grid *game.Grid, hops Path, start, goal data.Position, teleportRange int) {
+	t.Helper()
+
+	if hops.From() != start || hops.To() != goal {
+		t.Fatalf("Expected path from %v to %v, got from %v to %v", start, goal, hops.From(), hops.To())
+	}
+	for i, p := range hops {
+		if grid.CollisionGrid[p.Y][p.X] == game.CollisionTypeNonWalkable {
+			t.Errorf("Hop %d lands on a non walkable tile %v", i, p)
+		}
+		if i > 0 && squaredDistance(hops[i-1], p) > teleportRange*teleportRange {
+			t.Errorf("Hop %d from %v to %v is longer than %d", i, hops[i-1], p, teleportRange)
+		}
+	}
+}
+
+func loadGrid(t *testing.T, file string) *game.Grid {
+	t.Helper()
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var grid game.Grid
+	if err = gob.NewDecoder(f).Decode(&grid); err != nil {
+		t.Fatal(err)
+	}
+
+	return &grid
+}
@@ -21,55 +21,63 @@ func (pf *PathFinder) RandomMovement() {
 	utils.Sleep(50)
 }
 
-func (pf *PathFinder) DistanceFromMe(p data.Position) int {
-	return DistanceFromPoint(pf.data.PlayerUnit.Position, p)
-}
+const (
+	// sidestepDistance is how far to the side of the path the character moves to get unstuck
+	sidestepDistance = 4
+	// blockerCost is added around the blockers found while stuck, it doesn't depend on the configured weights since
+	// it's part of the stuck recovery
+	blockerCost = 50
+)
 
-func (pf *PathFinder) OptimizeRoomsTraverseOrder() []data.Room {
-	distanceMatrix := make(map[data.Room]map[data.Room]int)
-
-	for _, room1 := range pf.data.Rooms {
-		distanceMatrix[room1] = make(map[data.Room]int)
-		for _, room2 := range pf.data.Rooms {
-			if room1 != room2 {
-				distance := DistanceFromPoint(room1.GetCenter(), room2.GetCenter())
-				distanceMatrix[room1][room2] = distance
-			} else {
-				distanceMatrix[room1][room2] = 0
-			}
-		}
+// Sidestep moves the character to one side of the path, it returns false if there is no walkable position there
+func (pf *PathFinder) Sidestep(path Path) bool {
+	pf.cache.mu.Lock()
+	grid := pf.cache.grid
+	pf.cache.mu.Unlock()
+	if grid == nil {
+		return false
 	}
 
-	currentRoom := data.Room{}
-	for _, r := range pf.data.Rooms {
-		if r.IsInside(pf.data.PlayerUnit.Position) {
-			currentRoom = r
-		}
+	to, found := SidestepPosition(grid, path, sidestepDistance)
+	if !found {
+		return false
 	}
 
-	visited := make(map[data.Room]bool)
-	order := []data.Room{currentRoom}
-	visited[currentRoom] = true
+	screenX, screenY := pf.gameCoordsToScreenCords(path.From().X, path.From().Y, to.X, to.Y)
+	pf.MoveCharacter(screenX, screenY)
 
-	for len(order) < len(pf.data.Rooms) {
-		nextRoom := data.Room{}
-		minDistance := math.MaxInt
+	return true
+}
 
-		// Find the nearest unvisited room
-		for _, room := range pf.data.Rooms {
-			if !visited[room] && distanceMatrix[currentRoom][room] < minDistance {
-				nextRoom = room
-				minDistance = distanceMatrix[currentRoom][room]
-			}
-		}
+// AvoidBlocker makes the paths go around the next tile of the path, for the blockers that are not in the map data
+func (pf *PathFinder) AvoidBlocker(path Path) {
+	pf.cache.mu.Lock()
+	grid := pf.cache.grid
+	pf.cache.mu.Unlock()
+	if grid == nil || len(path) < 2 {
+		return
+	}
+
+	next := path[min(len(path)-1, 2)]
+	pf.AddDangerZone(DangerZone{Center: data.Position{X: next.X + grid.OffsetX, Y: next.Y + grid.OffsetY}, Radius: 2, Weight: blockerCost})
+	pf.InvalidatePath()
+}
+
+func (pf *PathFinder) DistanceFromMe(p data.Position) int {
+	return DistanceFromPoint(pf.data.PlayerUnit.Position, p)
+}
 
-		// Add the next room to the order of visit
-		order = append(order, nextRoom)
-		visited[nextRoom] = true
-		currentRoom = nextRoom
+func (pf *PathFinder) OptimizeRoomsTraverseOrder() []data.Room {
+	return pf.OptimizeRoomsTraverseOrderWith(TourOptions{TimeBudget: pf.cfg.Game.RoomTour.TimeBudget})
+}
+
+// OptimizeRoomsTraverseOrderWith returns the rooms of the current area in the order to visit them, see RoomTour
+func (pf *PathFinder) OptimizeRoomsTraverseOrderWith(opts TourOptions) []data.Room {
+	if pf.data.AreaData.Grid == nil {
+		return pf.data.Rooms
 	}
 
-	return order
+	return RoomTour(pf.data.AreaData.Grid, pf.data.PlayerUnit.Position, pf.data.Rooms, opts)
 }
 
 func (pf *PathFinder) MoveThroughPath(p Path, walkDuration time.Duration) {
@@ -101,6 +109,24 @@ func (pf *PathFinder) MoveThroughPath(p Path, walkDuration time.Duration) {
 	pf.MoveCharacter(screenCords.X, screenCords.Y)
 }
 
+// TeleportThroughPath teleports to the next hop of a path returned by GetTeleportPath, it returns false without
+// moving if the hop doesn't fit in the game area, so the caller can fall back to the walking path
+func (pf *PathFinder) TeleportThroughPath(hops Path) bool {
+	if len(hops) < 2 {
+		return false
+	}
+
+	next := hops[1]
+	screenX, screenY := pf.gameCoordsToScreenCords(hops.From().X, hops.From().Y, next.X, next.Y)
+	if screenX < 0 || screenY < 0 || screenX > pf.gr.GameAreaSizeX || screenY > int(float32(pf.gr.GameAreaSizeY)/1.21) {
+		return false
+	}
+
+	pf.MoveCharacter(screenX, screenY)
+
+	return true
+}
+
 func (pf *PathFinder) MoveCharacter(x, y int) {
 	if pf.data.CanTeleport() {
 		pf.hid.Click(game.RightButton, x, y)
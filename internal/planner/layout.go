@@ -0,0 +1,278 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/object"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather"
+)
+
+// Travel speeds used to estimate the run durations, in tiles per second
+const (
+	walkingSpeed  = 8
+	teleportSpeed = 30
+)
+
+// Layout is the analysis of the map rolled for the current seed, for a single run
+type Layout struct {
+	// Distance is the walking distance, in tiles, from the waypoint where the run starts through its key locations
+	Distance int
+	// Variant describes the layout rolled, empty for the runs without preset variants
+	Variant string
+	// Estimated is the time needed to travel the distance, fighting not included
+	Estimated time.Duration
+}
+
+// leg is a walk inside a single area, visiting the targets in order
+type leg struct {
+	area    area.ID
+	from    data.Position
+	targets []data.Position
+}
+
+// layoutAnalyzer returns the legs of the run and the layout variant, false if the map doesn't contain what the run
+// needs
+type layoutAnalyzer func(areas map[area.ID]game.AreaData) ([]leg, string, bool)
+
+var layoutAnalyzers = map[config.Run]layoutAnalyzer{
+	config.CountessRun:       countessLayout,
+	config.SummonerRun:       summonerLayout,
+	config.DurielRun:         durielLayout,
+	config.DiabloRun:         diabloLayout,
+	config.AncientTunnelsRun: ancientTunnelsLayout,
+}
+
+// AnalyzeLayout calculates the distance to the key locations of the run, false if the run can't be analyzed or the
+// map data is not complete
+func AnalyzeLayout(r config.Run, areas map[area.ID]game.AreaData, canTeleport bool) (Layout, bool) {
+	analyzer, found := layoutAnalyzers[r]
+	if !found || len(areas) == 0 {
+		return Layout{}, false
+	}
+
+	legs, variant, found := analyzer(areas)
+	if !found {
+		return Layout{}, false
+	}
+
+	total := 0
+	for _, l := range legs {
+		distance, reachable := legDistance(areas[l.area], l)
+		if !reachable {
+			return Layout{}, false
+		}
+		total += distance
+	}
+
+	speed := walkingSpeed
+	if canTeleport {
+		speed = teleportSpeed
+	}
+
+	return Layout{Distance: total, Variant: variant, Estimated: time.Duration(total) * time.Second / time.Duration(speed)}, true
+}
+
+// legDistance sums the walking distances between the consecutive stops of the leg. Walking distances are the same in
+// both directions, so a single flood from every other stop measures the walks before and after it.
+func legDistance(a game.AreaData, l leg) (int, bool) {
+	if a.Grid == nil {
+		return 0, false
+	}
+
+	stops := append([]data.Position{l.from}, l.targets...)
+	total := 0
+	for i := 1; i < len(stops); i += 2 {
+		targets := []data.Position{stops[i-1]}
+		if i+1 < len(stops) {
+			targets = append(targets, stops[i+1])
+		}
+
+		for _, distance := range pather.WalkDistances(a.Grid, stops[i], targets) {
+			if distance < 0 {
+				return 0, false
+			}
+			total += distance
+		}
+	}
+
+	return total, true
+}
+
+// throughAreas returns the legs to walk from the waypoint of the first area to the last one, and the position where
+// we appear in the last area
+func throughAreas(areas map[area.ID]game.AreaData, route ...area.ID) ([]leg, data.Position, bool) {
+	first, found := areas[route[0]]
+	if !found {
+		return nil, data.Position{}, false
+	}
+
+	legs := make([]leg, 0, len(route))
+	pos := pather.WaypointPosition(first)
+	for i := 0; i < len(route)-1; i++ {
+		current, next := areas[route[i]], areas[route[i+1]]
+		exit, found := exitTo(current, route[i+1])
+		if !found || next.Grid == nil {
+			return nil, data.Position{}, false
+		}
+		legs = append(legs, leg{area: route[i], from: pos, targets: []data.Position{exit}})
+		pos = pather.EntryPosition(next, route[i], exit)
+	}
+
+	return legs, pos, true
+}
+
+func exitTo(a game.AreaData, to area.ID) (data.Position, bool) {
+	for _, lvl := range a.AdjacentLevels {
+		if lvl.Area == to {
+			return lvl.Position, true
+		}
+	}
+
+	return data.Position{}, false
+}
+
+func findObject(a game.AreaData, name object.Name) (data.Position, bool) {
+	for _, o := range a.Objects {
+		if o.Name == name {
+			return o.Position, true
+		}
+	}
+
+	return data.Position{}, false
+}
+
+func countessLayout(areas map[area.ID]game.AreaData) ([]leg, string, bool) {
+	legs, entry, found := throughAreas(areas, area.BlackMarsh, area.ForgottenTower, area.TowerCellarLevel1,
+		area.TowerCellarLevel2, area.TowerCellarLevel3, area.TowerCellarLevel4, area.TowerCellarLevel5)
+	if !found {
+		return nil, "", false
+	}
+
+	chest, found := findObject(areas[area.TowerCellarLevel5], object.GoodChest)
+	if !found {
+		return nil, "", false
+	}
+
+	return append(legs, leg{area: area.TowerCellarLevel5, from: entry, targets: []data.Position{chest}}), "", true
+}
+
+func summonerLayout(areas map[area.ID]game.AreaData) ([]leg, string, bool) {
+	sanctuary, found := areas[area.ArcaneSanctuary]
+	if !found {
+		return nil, "", false
+	}
+
+	summoner, found := sanctuary.NPCs.FindOne(npc.Summoner)
+	if !found || len(summoner.Positions) == 0 {
+		return nil, "", false
+	}
+
+	// The sanctuary has four arms around the waypoint, the variant is the arm where the Summoner is
+	wp := pather.WaypointPosition(sanctuary)
+	target := summoner.Positions[0]
+
+	return []leg{{area: area.ArcaneSanctuary, from: wp, targets: []data.Position{target}}}, compassDirection(wp, target) + " arm", true
+}
+
+func durielLayout(areas map[area.ID]game.AreaData) ([]leg, string, bool) {
+	for _, tomb := range []area.ID{area.TalRashasTomb1, area.TalRashasTomb2, area.TalRashasTomb3, area.TalRashasTomb4,
+		area.TalRashasTomb5, area.TalRashasTomb6, area.TalRashasTomb7} {
+		orifice, found := findObject(areas[tomb], object.HoradricOrifice)
+		if !found {
+			continue
+		}
+
+		legs, entry, found := throughAreas(areas, area.CanyonOfTheMagi, tomb)
+		if !found {
+			return nil, "", false
+		}
+
+		return append(legs, leg{area: tomb, from: entry, targets: []data.Position{orifice}}), tomb.Area().Name, true
+	}
+
+	return nil, "", false
+}
+
+func diabloLayout(areas map[area.ID]game.AreaData) ([]leg, string, bool) {
+	legs, entry, found := throughAreas(areas, area.RiverOfFlame, area.ChaosSanctuary)
+	if !found {
+		return nil, "", false
+	}
+
+	// Same order the seals are opened in the run
+	sanctuary := areas[area.ChaosSanctuary]
+	seals := make([]data.Position, 0, 5)
+	for _, name := range []object.Name{object.DiabloSeal4, object.DiabloSeal5, object.DiabloSeal3, object.DiabloSeal1, object.DiabloSeal2} {
+		seal, found := findObject(sanctuary, name)
+		if !found {
+			return nil, "", false
+		}
+		seals = append(seals, seal)
+	}
+
+	// Every seal boss has its own preset layouts, the position of its first seal identifies the one rolled
+	variant := fmt.Sprintf("Vizier %s, De Seis %s, Infector %s",
+		relativeTo(sanctuary, seals[0]), relativeTo(sanctuary, seals[2]), relativeTo(sanctuary, seals[3]))
+
+	return append(legs, leg{area: area.ChaosSanctuary, from: entry, targets: seals}), variant, true
+}
+
+func ancientTunnelsLayout(areas map[area.ID]game.AreaData) ([]leg, string, bool) {
+	legs, entry, found := throughAreas(areas, area.LostCity, area.AncientTunnels)
+	if !found {
+		return nil, "", false
+	}
+
+	chests := make([]data.Position, 0)
+	for _, o := range areas[area.AncientTunnels].Objects {
+		if o.IsSuperChest() {
+			chests = append(chests, o.Position)
+		}
+	}
+
+	return append(legs, leg{area: area.AncientTunnels, from: entry, targets: closestFirst(entry, chests)}), fmt.Sprintf("%d super chests", len(chests)), true
+}
+
+// closestFirst orders the positions visiting always the closest one next
+func closestFirst(from data.Position, positions []data.Position) []data.Position {
+	ordered := make([]data.Position, 0, len(positions))
+	visited := make([]bool, len(positions))
+	for len(ordered) < len(positions) {
+		next := -1
+		for i, p := range positions {
+			if !visited[i] && (next == -1 || pather.DistanceFromPoint(from, p) < pather.DistanceFromPoint(from, positions[next])) {
+				next = i
+			}
+		}
+		visited[next] = true
+		from = positions[next]
+		ordered = append(ordered, from)
+	}
+
+	return ordered
+}
+
+// relativeTo formats the position relative to the area origin, so it's the same for every seed with that layout
+func relativeTo(a game.AreaData, p data.Position) string {
+	return fmt.Sprintf("%d,%d", p.X-a.OffsetX, p.Y-a.OffsetY)
+}
+
+// compassDirection returns the direction from one position to the other, as seen in the game screen
+func compassDirection(from, to data.Position) string {
+	// Game coordinates are rotated 45 degrees in the screen, X goes to the south-east and Y to the south-west
+	screenX := float64((to.X - from.X) - (to.Y - from.Y))
+	screenY := float64((to.X - from.X) + (to.Y - from.Y))
+	angle := math.Atan2(screenY, screenX) * 180 / math.Pi
+
+	directions := []string{"east", "south-east", "south", "south-west", "west", "north-west", "north", "north-east"}
+	idx := int(math.Round(angle/45)+8) % 8
+
+	return directions[idx]
+}
@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/object"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather"
+)
+
+// openArea returns an area without walls, waypoint positions are in game coordinates
+func openArea(id area.ID, offsetX, offsetY, size int) game.AreaData {
+	cg := make([][]game.CollisionType, size)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, size)
+		for x := range cg[y] {
+			cg[y][x] = game.CollisionTypeWalkable
+		}
+	}
+
+	return game.AreaData{Area: id, Grid: game.NewGrid(cg, offsetX, offsetY)}
+}
+
+// durielAreas is a canyon with the true tomb 40 tiles away from the waypoint and the orifice 20 tiles inside the tomb
+func durielAreas() map[area.ID]game.AreaData {
+	canyon := openArea(area.CanyonOfTheMagi, 2000, 2000, 60)
+	canyon.Objects = []data.Object{{Name: object.WaypointPortal, Position: data.Position{X: 2010, Y: 2010}}}
+	canyon.AdjacentLevels = []data.Level{
+		{Area: area.TalRashasTomb1, Position: data.Position{X: 2010, Y: 2050}, IsEntrance: true},
+		{Area: area.TalRashasTomb3, Position: data.Position{X: 2050, Y: 2010}, IsEntrance: true},
+	}
+
+	tomb := openArea(area.TalRashasTomb3, 3000, 3000, 40)
+	tomb.AdjacentLevels = []data.Level{{Area: area.CanyonOfTheMagi, Position: data.Position{X: 3005, Y: 3005}}}
+	tomb.Objects = []data.Object{{Name: object.HoradricOrifice, Position: data.Position{X: 3025, Y: 3005}}}
+
+	return map[area.ID]game.AreaData{
+		area.CanyonOfTheMagi: canyon,
+		area.TalRashasTomb1:  openArea(area.TalRashasTomb1, 4000, 4000, 40),
+		area.TalRashasTomb3:  tomb,
+	}
+}
+
+func TestSummonerLayout(t *testing.T) {
+	sanctuary := openArea(area.ArcaneSanctuary, 1000, 1000, 50)
+	sanctuary.Objects = []data.Object{{Name: object.WaypointPortal, Position: data.Position{X: 1010, Y: 1010}}}
+	sanctuary.NPCs = data.NPCs{{ID: npc.Summoner, Positions: []data.Position{{X: 1040, Y: 1010}}}}
+
+	layout, found := AnalyzeLayout(config.SummonerRun, map[area.ID]game.AreaData{area.ArcaneSanctuary: sanctuary}, false)
+	if !found {
+		t.Fatalf("Expected summoner layout to be analyzed")
+	}
+	if layout.Distance != 30 {
+		t.Errorf("Expected distance 30, got %d", layout.Distance)
+	}
+	if layout.Variant != "south-east arm" {
+		t.Errorf("Expected south-east arm, got %s", layout.Variant)
+	}
+	if expected := 30 * time.Second / walkingSpeed; layout.Estimated != expected {
+		t.Errorf("Expected %s walking, got %s", expected, layout.Estimated)
+	}
+
+	teleporting, _ := AnalyzeLayout(config.SummonerRun, map[area.ID]game.AreaData{area.ArcaneSanctuary: sanctuary}, true)
+	if teleporting.Estimated >= layout.Estimated {
+		t.Errorf("Expected teleporting to be faster than walking, got %s", teleporting.Estimated)
+	}
+}
+
+func TestDurielLayout(t *testing.T) {
+	layout, found := AnalyzeLayout(config.DurielRun, durielAreas(), false)
+	if !found {
+		t.Fatalf("Expected duriel layout to be analyzed")
+	}
+	if layout.Distance != 60 {
+		t.Errorf("Expected distance 60, got %d", layout.Distance)
+	}
+	if expected := area.TalRashasTomb3.Area().Name; layout.Variant != expected {
+		t.Errorf("Expected variant %s, got %s", expected, layout.Variant)
+	}
+}
+
+func TestLegDistance(t *testing.T) {
+	a := openArea(area.ChaosSanctuary, 100, 100, 30)
+	// Wall in the middle with a single gap at the bottom
+	for y := 0; y < 28; y++ {
+		a.Grid.CollisionGrid[y][15] = game.CollisionTypeNonWalkable
+	}
+
+	stops := []data.Position{{X: 105, Y: 105}, {X: 125, Y: 105}, {X: 125, Y: 125}, {X: 105, Y: 125}, {X: 110, Y: 110}}
+	for targets := 1; targets < len(stops); targets++ {
+		// Same as walking every stop from the previous one
+		expected := 0
+		for i := 1; i <= targets; i++ {
+			expected += pather.WalkDistances(a.Grid, stops[i-1], []data.Position{stops[i]})[0]
+		}
+
+		distance, found := legDistance(a, leg{area: a.Area, from: stops[0], targets: stops[1 : targets+1]})
+		if !found || distance != expected {
+			t.Errorf("Expected distance %d through %d targets, got %d", expected, targets, distance)
+		}
+	}
+
+	a.Grid.CollisionGrid[28][15] = game.CollisionTypeNonWalkable
+	a.Grid.CollisionGrid[29][15] = game.CollisionTypeNonWalkable
+	if _, found := legDistance(a, leg{area: a.Area, from: stops[0], targets: stops[1:]}); found {
+		t.Errorf("Expected leg not to be walkable with the gap closed")
+	}
+}
+
+func TestLayoutWithoutMapData(t *testing.T) {
+	areas := durielAreas()
+	delete(areas, area.TalRashasTomb3)
+
+	if _, found := AnalyzeLayout(config.DurielRun, areas, false); found {
+		t.Errorf("Expected layout not to be analyzed without the true tomb")
+	}
+	if _, found := AnalyzeLayout(config.BaalRun, areas, false); found {
+		t.Errorf("Expected layout not to be analyzed for runs without analyzer")
+	}
+}
+
+func TestMaxLayoutDistance(t *testing.T) {
+	d := fakeData(80, config.DurielRun)
+	d.Areas = durielAreas()
+	d.CharacterCfg.Game.RunConditions[config.DurielRun] = config.RunConditions{MaxLayoutDistance: 50}
+
+	p := Evaluate(d, NewHistory())
+	assertRuns(t, p)
+	if p.Layouts[config.DurielRun].Distance != 60 {
+		t.Errorf("Expected skipped run layout to be kept, got %+v", p.Layouts)
+	}
+
+	d.CharacterCfg.Game.RunConditions[config.DurielRun] = config.RunConditions{MaxLayoutDistance: 100}
+	assertRuns(t, Evaluate(d, NewHistory()), config.DurielRun)
+}
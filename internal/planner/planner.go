@@ -0,0 +1,221 @@
+package planner
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/skill"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// Decision explains why a configured run has been selected or skipped for the game
+type Decision struct {
+	Run      config.Run
+	Selected bool
+	Reason   string
+
+	failureSkip bool
+}
+
+// Entry is a selected run, in execution order
+type Entry struct {
+	Run        config.Run
+	TimeBudget time.Duration
+}
+
+type Plan struct {
+	// Game is the number of the game being planned, starting at 1
+	Game      int
+	Runs      []Entry
+	Decisions []Decision
+	// Layouts is the analysis of the map for the selected runs that support it
+	Layouts map[config.Run]Layout
+}
+
+// History contains the results of the previous games used to evaluate the conditions
+type History struct {
+	Games               int
+	ConsecutiveFailures map[config.Run]int
+	// SkippedSince contains the game number where a run started to be skipped due to failures
+	SkippedSince map[config.Run]int
+}
+
+func NewHistory() History {
+	return History{
+		ConsecutiveFailures: make(map[config.Run]int),
+		SkippedSince:        make(map[config.Run]int),
+	}
+}
+
+// Evaluate plans the next game without modifying the history, so it can be used as a dry run
+func Evaluate(d game.Data, h History) Plan {
+	cfg := d.CharacterCfg
+	plan := Plan{Game: h.Games + 1, Runs: make([]Entry, 0), Layouts: make(map[config.Run]Layout)}
+	_, teleportBound := d.KeyBindings.KeyBindingForSkill(skill.Teleport)
+	canTeleport := cfg.Character.UseTeleport && teleportBound
+
+	tzActive := false
+	if slices.Contains(cfg.Game.Runs, config.TerrorZoneRun) {
+		tzActive = len(availableTZs(d)) > 0
+	}
+
+	// Terror zone always goes first, as it used to be when building the run list
+	ordered := make([]config.Run, 0, len(cfg.Game.Runs))
+	if tzActive {
+		ordered = append(ordered, config.TerrorZoneRun)
+	}
+	for _, r := range cfg.Game.Runs {
+		if r != config.TerrorZoneRun {
+			ordered = append(ordered, r)
+		}
+	}
+	if !tzActive && slices.Contains(cfg.Game.Runs, config.TerrorZoneRun) {
+		plan.Decisions = append(plan.Decisions, Decision{Run: config.TerrorZoneRun, Reason: "no configured terror zone is active"})
+	}
+
+	for _, r := range ordered {
+		if _, found := config.AvailableRuns[r]; !found {
+			plan.Decisions = append(plan.Decisions, Decision{Run: r, Reason: "unknown run"})
+			continue
+		}
+
+		if tzActive && r != config.TerrorZoneRun && cfg.Game.TerrorZone.SkipOtherRuns {
+			plan.Decisions = append(plan.Decisions, Decision{Run: r, Reason: "terror zone is active and other runs are skipped"})
+			continue
+		}
+
+		decision := evaluateConditions(r, cfg.Game.RunConditions[r], d, h, plan.Game)
+		if decision.Selected {
+			if layout, found := AnalyzeLayout(r, d.Areas, canTeleport); found {
+				plan.Layouts[r] = layout
+				if maxDistance := cfg.Game.RunConditions[r].MaxLayoutDistance; maxDistance > 0 && layout.Distance > maxDistance {
+					decision = Decision{Run: r, Reason: fmt.Sprintf("layout distance %d is longer than %d", layout.Distance, maxDistance)}
+				}
+			}
+		}
+		plan.Decisions = append(plan.Decisions, decision)
+		if decision.Selected {
+			plan.Runs = append(plan.Runs, Entry{Run: r, TimeBudget: cfg.Game.RunConditions[r].TimeBudget})
+		}
+	}
+
+	return plan
+}
+
+func evaluateConditions(r config.Run, cond config.RunConditions, d game.Data, h History, gameNumber int) Decision {
+	if len(cond.TerrorZones) > 0 && !slices.ContainsFunc(d.TerrorZones, func(tz area.ID) bool { return slices.Contains(cond.TerrorZones, tz) }) {
+		return Decision{Run: r, Reason: "required terror zone is not active"}
+	}
+
+	lvl, _ := d.PlayerUnit.FindStat(stat.Level, 0)
+	if cond.MinLevel > 0 && lvl.Value < cond.MinLevel {
+		return Decision{Run: r, Reason: fmt.Sprintf("character level %d is below %d", lvl.Value, cond.MinLevel)}
+	}
+	if cond.MaxLevel > 0 && lvl.Value >= cond.MaxLevel {
+		return Decision{Run: r, Reason: fmt.Sprintf("character level %d is not below %d", lvl.Value, cond.MaxLevel)}
+	}
+
+	if cond.EveryNthGame > 1 && (gameNumber-1)%cond.EveryNthGame != 0 {
+		return Decision{Run: r, Reason: fmt.Sprintf("only executed every %d games", cond.EveryNthGame)}
+	}
+
+	if failures := h.ConsecutiveFailures[r]; cond.SkipAfterFailures > 0 && failures >= cond.SkipAfterFailures {
+		skippedSince, skipping := h.SkippedSince[r]
+		if cond.FailureCooldownGames <= 0 {
+			return Decision{Run: r, Reason: fmt.Sprintf("failed %d times in a row", failures), failureSkip: true}
+		}
+		if !skipping || gameNumber-skippedSince < cond.FailureCooldownGames {
+			return Decision{Run: r, Reason: fmt.Sprintf("failed %d times in a row, retrying after %d games", failures, cond.FailureCooldownGames), failureSkip: true}
+		}
+
+		return Decision{Run: r, Selected: true, Reason: "retrying after failure cooldown"}
+	}
+
+	return Decision{Run: r, Selected: true}
+}
+
+func availableTZs(d game.Data) []area.ID {
+	available := make([]area.ID, 0)
+	for _, tz := range d.TerrorZones {
+		if slices.Contains(d.CharacterCfg.Game.TerrorZone.Areas, tz) {
+			available = append(available, tz)
+		}
+	}
+
+	return available
+}
+
+// Planner keeps the history of a supervisor and plans the runs of every game
+type Planner struct {
+	mu      sync.Mutex
+	history History
+}
+
+func New() *Planner {
+	return &Planner{history: NewHistory()}
+}
+
+// Next plans the next game and moves the history forward
+func (p *Planner) Next(d game.Data) Plan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	plan := Evaluate(d, p.history)
+	p.history.Games = plan.Game
+	for _, decision := range plan.Decisions {
+		_, skipping := p.history.SkippedSince[decision.Run]
+		switch {
+		case decision.failureSkip && !skipping:
+			p.history.SkippedSince[decision.Run] = plan.Game
+		case decision.Selected && skipping:
+			delete(p.history.SkippedSince, decision.Run)
+		}
+	}
+
+	if d.CharacterCfg.Game.RandomizeRuns {
+		// Terror zone is kept as the first run
+		toShuffle := plan.Runs
+		if len(toShuffle) > 0 && toShuffle[0].Run == config.TerrorZoneRun {
+			toShuffle = toShuffle[1:]
+		}
+		rand.Shuffle(len(toShuffle), func(i, j int) { toShuffle[i], toShuffle[j] = toShuffle[j], toShuffle[i] })
+	}
+
+	return plan
+}
+
+// RecordResult updates the consecutive failures of the run, a nil error resets them
+func (p *Planner) RecordResult(r config.Run, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err == nil {
+		delete(p.history.ConsecutiveFailures, r)
+		return
+	}
+
+	p.history.ConsecutiveFailures[r]++
+}
+
+// History returns a copy of the current history, useful to dry run the next game
+func (p *Planner) History() History {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := NewHistory()
+	h.Games = p.history.Games
+	for r, failures := range p.history.ConsecutiveFailures {
+		h.ConsecutiveFailures[r] = failures
+	}
+	for r, game := range p.history.SkippedSince {
+		h.SkippedSince[r] = game
+	}
+
+	return h
+}
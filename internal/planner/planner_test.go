@@ -0,0 +1,172 @@
+package planner
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func fakeData(level int, runs ...config.Run) game.Data {
+	d := game.Data{}
+	d.PlayerUnit = data.PlayerUnit{Stats: stat.Stats{{ID: stat.Level, Value: level}}}
+	d.CharacterCfg.Game.Runs = runs
+	d.CharacterCfg.Game.RunConditions = make(map[config.Run]config.RunConditions)
+
+	return d
+}
+
+func planned(p Plan) []config.Run {
+	runs := make([]config.Run, 0, len(p.Runs))
+	for _, e := range p.Runs {
+		runs = append(runs, e.Run)
+	}
+
+	return runs
+}
+
+func assertRuns(t *testing.T, p Plan, expected ...config.Run) {
+	t.Helper()
+	if got := planned(p); !slices.Equal(got, expected) {
+		t.Errorf("expected runs %v, got %v (decisions: %+v)", expected, got, p.Decisions)
+	}
+}
+
+func TestEvaluateWithoutConditions(t *testing.T) {
+	d := fakeData(80, config.PindleskinRun, config.MephistoRun, config.Run("unknown"))
+
+	assertRuns(t, Evaluate(d, NewHistory()), config.PindleskinRun, config.MephistoRun)
+}
+
+func TestTerrorZoneGoesFirst(t *testing.T) {
+	d := fakeData(80, config.PindleskinRun, config.TerrorZoneRun, config.MephistoRun)
+	d.CharacterCfg.Game.TerrorZone.Areas = []area.ID{area.Tristram}
+
+	assertRuns(t, Evaluate(d, NewHistory()), config.PindleskinRun, config.MephistoRun)
+
+	d.TerrorZones = []area.ID{area.Tristram}
+	assertRuns(t, Evaluate(d, NewHistory()), config.TerrorZoneRun, config.PindleskinRun, config.MephistoRun)
+
+	d.CharacterCfg.Game.TerrorZone.SkipOtherRuns = true
+	assertRuns(t, Evaluate(d, NewHistory()), config.TerrorZoneRun)
+}
+
+func TestTerrorZoneCondition(t *testing.T) {
+	d := fakeData(80, config.CowsRun, config.PindleskinRun)
+	d.CharacterCfg.Game.RunConditions[config.CowsRun] = config.RunConditions{TerrorZones: []area.ID{area.MooMooFarm}}
+
+	assertRuns(t, Evaluate(d, NewHistory()), config.PindleskinRun)
+
+	d.TerrorZones = []area.ID{area.MooMooFarm}
+	assertRuns(t, Evaluate(d, NewHistory()), config.CowsRun, config.PindleskinRun)
+}
+
+func TestLevelConditions(t *testing.T) {
+	d := fakeData(30, config.CountessRun, config.BaalRun)
+	d.CharacterCfg.Game.RunConditions[config.CountessRun] = config.RunConditions{MaxLevel: 40}
+	d.CharacterCfg.Game.RunConditions[config.BaalRun] = config.RunConditions{MinLevel: 60}
+
+	assertRuns(t, Evaluate(d, NewHistory()), config.CountessRun)
+
+	d = fakeData(70, config.CountessRun, config.BaalRun)
+	d.CharacterCfg.Game.RunConditions[config.CountessRun] = config.RunConditions{MaxLevel: 40}
+	d.CharacterCfg.Game.RunConditions[config.BaalRun] = config.RunConditions{MinLevel: 60}
+
+	assertRuns(t, Evaluate(d, NewHistory()), config.BaalRun)
+}
+
+func TestEveryNthGame(t *testing.T) {
+	d := fakeData(80, config.CowsRun, config.PindleskinRun)
+	d.CharacterCfg.Game.RunConditions[config.CowsRun] = config.RunConditions{EveryNthGame: 3}
+
+	p := New()
+	executed := make([]int, 0)
+	for range 7 {
+		plan := p.Next(d)
+		if slices.Contains(planned(plan), config.CowsRun) {
+			executed = append(executed, plan.Game)
+		}
+	}
+
+	if !slices.Equal(executed, []int{1, 4, 7}) {
+		t.Errorf("expected cows on games 1, 4 and 7, got %v", executed)
+	}
+}
+
+func TestSkipAfterFailures(t *testing.T) {
+	d := fakeData(80, config.DiabloRun, config.PindleskinRun)
+	d.CharacterCfg.Game.RunConditions[config.DiabloRun] = config.RunConditions{SkipAfterFailures: 2}
+
+	p := New()
+	errFailed := errors.New("failed")
+
+	p.Next(d)
+	p.RecordResult(config.DiabloRun, errFailed)
+	// A success resets the counter
+	p.Next(d)
+	p.RecordResult(config.DiabloRun, nil)
+	p.Next(d)
+	p.RecordResult(config.DiabloRun, errFailed)
+	assertRuns(t, p.Next(d), config.DiabloRun, config.PindleskinRun)
+	p.RecordResult(config.DiabloRun, errFailed)
+
+	// Without cooldown it's skipped for the rest of the session
+	for range 5 {
+		assertRuns(t, p.Next(d), config.PindleskinRun)
+	}
+}
+
+func TestFailureCooldown(t *testing.T) {
+	d := fakeData(80, config.DiabloRun)
+	d.CharacterCfg.Game.RunConditions[config.DiabloRun] = config.RunConditions{SkipAfterFailures: 1, FailureCooldownGames: 2}
+
+	p := New()
+	p.Next(d)
+	p.RecordResult(config.DiabloRun, errors.New("failed"))
+
+	assertRuns(t, p.Next(d))
+	assertRuns(t, p.Next(d))
+	assertRuns(t, p.Next(d), config.DiabloRun)
+
+	// Failing again starts a new cooldown
+	p.RecordResult(config.DiabloRun, errors.New("failed"))
+	assertRuns(t, p.Next(d))
+
+	// Dry run doesn't modify the history
+	h := p.History()
+	Evaluate(d, h)
+	Evaluate(d, h)
+	if got := p.History().Games; got != h.Games {
+		t.Errorf("dry run modified the history, expected %d games, got %d", h.Games, got)
+	}
+}
+
+func TestTimeBudgetIsPlanned(t *testing.T) {
+	d := fakeData(80, config.CowsRun)
+	d.CharacterCfg.Game.RunConditions[config.CowsRun] = config.RunConditions{TimeBudget: 90e9}
+
+	plan := Evaluate(d, NewHistory())
+	if len(plan.Runs) != 1 || plan.Runs[0].TimeBudget.Seconds() != 90 {
+		t.Errorf("expected cows with a 90s time budget, got %+v", plan.Runs)
+	}
+}
+
+func TestRandomizeKeepsTerrorZoneFirst(t *testing.T) {
+	d := fakeData(80, config.TerrorZoneRun, config.CowsRun, config.PindleskinRun, config.MephistoRun, config.BaalRun)
+	d.CharacterCfg.Game.RandomizeRuns = true
+	d.CharacterCfg.Game.TerrorZone.Areas = []area.ID{area.Tristram}
+	d.TerrorZones = []area.ID{area.Tristram}
+
+	p := New()
+	for range 10 {
+		runs := planned(p.Next(d))
+		if len(runs) != 5 || runs[0] != config.TerrorZoneRun {
+			t.Fatalf("expected terror zone first and every run planned, got %v", runs)
+		}
+	}
+}
@@ -3,21 +3,20 @@ package discord
 import (
 	"context"
 	"fmt"
-	"slices"
-	"strings"
+	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/hectorgimenez/koolo/internal/bot"
-	"github.com/hectorgimenez/koolo/internal/config"
 )
 
 type Bot struct {
 	discordSession *discordgo.Session
 	channelID      string
-	manager        *bot.SupervisorManager
+	commands       *commandHandler
+	logger         *slog.Logger
 }
 
-func NewBot(token, channelID string, manager *bot.SupervisorManager) (*Bot, error) {
+func NewBot(token, channelID string, manager *bot.SupervisorManager, logger *slog.Logger) (*Bot, error) {
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Discord session: %w", err)
@@ -26,45 +25,50 @@ func NewBot(token, channelID string, manager *bot.SupervisorManager) (*Bot, erro
 	return &Bot{
 		discordSession: dg,
 		channelID:      channelID,
-		manager:        manager,
+		commands:       newCommandHandler(manager, logger),
+		logger:         logger,
 	}, nil
 }
 
 func (b *Bot) Start(ctx context.Context) error {
 	//b.discordSession.Debug = true
-	b.discordSession.AddHandler(b.onMessageCreated)
-	b.discordSession.Identify.Intents = discordgo.IntentsGuildMessages
+	b.discordSession.AddHandler(b.onInteractionCreated)
+	b.discordSession.Identify.Intents = discordgo.IntentsGuilds
 	err := b.discordSession.Open()
 	if err != nil {
 		return fmt.Errorf("error opening connection: %w", err)
 	}
 
+	if err = b.registerCommands(); err != nil {
+		b.discordSession.Close()
+		return err
+	}
+
 	// Wait until context is finished
 	<-ctx.Done()
 
 	return b.discordSession.Close()
 }
 
-func (b *Bot) onMessageCreated(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == s.State.User.ID {
-		return
+// registerCommands registers the slash commands in the guild of the configured channel, so they are available
+// immediately. Commands are registered globally if the guild can not be found.
+func (b *Bot) registerCommands() error {
+	guildID := ""
+	if ch, err := b.discordSession.Channel(b.channelID); err == nil {
+		guildID = ch.GuildID
+	} else {
+		b.logger.Warn("Discord channel not found, registering global commands", slog.Any("error", err))
 	}
 
-	// Check if the message is from a bot admin
-	if !slices.Contains(config.Koolo.Discord.BotAdmins, m.Author.ID) {
-		return
+	if _, err := b.discordSession.ApplicationCommandBulkOverwrite(b.discordSession.State.User.ID, guildID, applicationCommands); err != nil {
+		return fmt.Errorf("error registering Discord commands: %w", err)
 	}
 
-	prefix := strings.Split(m.Content, " ")[0]
-	switch prefix {
-	case "!start":
-		b.handleStartRequest(s, m)
-	case "!stop":
-		b.handleStopRequest(s, m)
-	case "!stats":
-		b.handleStatsRequest(s, m)
-	case "!status":
-		b.handleStatusRequest(s, m)
-	}
+	return nil
+}
 
+func (b *Bot) onInteractionCreated(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if err := b.commands.Handle(s, i.Interaction); err != nil {
+		b.logger.Error("error handling Discord interaction", slog.Any("error", err))
+	}
 }
@@ -2,180 +2,328 @@ package discord
 
 import (
 	"fmt"
+	"log/slog"
 	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
 )
 
-func (b *Bot) supervisorExists(supervisor string) bool {
-	supervisors := b.manager.AvailableSupervisors()
-	return slices.Contains(supervisors, supervisor)
-}
+const (
+	supervisorOption = "supervisor"
+	pauseButtonID    = "pause"
+	stopButtonID     = "stop"
 
-func (b *Bot) handleStartRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Discord doesn't accept more than 25 autocomplete choices
+	maxAutocompleteChoices = 25
+)
 
-	// Start supervisor(s) provided as in the stop command
-	words := strings.Fields(m.Content)
+// Session is the subset of discordgo.Session used to answer interactions
+type Session interface {
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// SupervisorManager is the subset of bot.SupervisorManager controlled by the commands
+type SupervisorManager interface {
+	AvailableSupervisors() []string
+	Start(supervisorName string, attachToExisting bool, pidHwnd ...uint32) error
+	Stop(supervisor string)
+	TogglePause(supervisor string)
+	Status(characterName string) bot.Stats
+}
 
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
+var applicationCommands = []*discordgo.ApplicationCommand{
+	{Name: "start", Description: "Start a supervisor", Options: []*discordgo.ApplicationCommandOption{supervisorCommandOption(true)}},
+	{Name: "stop", Description: "Stop a supervisor", Options: []*discordgo.ApplicationCommandOption{supervisorCommandOption(true)}},
+	{Name: "pause", Description: "Pause or resume a supervisor", Options: []*discordgo.ApplicationCommandOption{supervisorCommandOption(true)}},
+	{Name: "status", Description: "Show the status of a supervisor, or all of them", Options: []*discordgo.ApplicationCommandOption{supervisorCommandOption(false)}},
+	{Name: "stats", Description: "Show the stats of a supervisor", Options: []*discordgo.ApplicationCommandOption{supervisorCommandOption(true)}},
+}
 
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+func supervisorCommandOption(required bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         supervisorOption,
+		Description:  "Supervisor name",
+		Required:     required,
+		Autocomplete: true,
+	}
+}
 
-			// Attempt to start the specified supervisor
-			b.manager.Start(supervisor, false)
+type commandHandler struct {
+	manager SupervisorManager
+	logger  *slog.Logger
+}
 
-			// Wait for the supervisor to start
-			time.Sleep(1 * time.Second)
+func newCommandHandler(manager SupervisorManager, logger *slog.Logger) *commandHandler {
+	return &commandHandler{manager: manager, logger: logger}
+}
 
-			// Send a confirmation message
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' has been started.", supervisor))
+// Handle answers slash commands, autocomplete requests and button clicks
+func (h *commandHandler) Handle(s Session, i *discordgo.Interaction) error {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		return h.handleAutocomplete(s, i)
+	case discordgo.InteractionApplicationCommand:
+		if !isBotAdmin(i) {
+			return respondEphemeral(s, i, "You are not allowed to use this command.")
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !start <supervisor1> [supervisor2] ...")
+		return h.handleCommand(s, i)
+	case discordgo.InteractionMessageComponent:
+		if !isBotAdmin(i) {
+			return respondEphemeral(s, i, "You are not allowed to use this command.")
+		}
+		return h.handleButton(s, i)
 	}
+
+	return nil
 }
 
-func (b *Bot) handleStopRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+func (h *commandHandler) handleCommand(s Session, i *discordgo.Interaction) error {
+	data := i.ApplicationCommandData()
+	supervisor := ""
+	for _, opt := range data.Options {
+		if opt.Name == supervisorOption {
+			supervisor = opt.StringValue()
+		}
+	}
 
-	// Split the message content into words
-	words := strings.Fields(m.Content)
+	if supervisor == "" {
+		if data.Name == "status" {
+			return h.handleStatusAll(s, i)
+		}
+		return respondEphemeral(s, i, "A supervisor name is required.")
+	}
 
-	// Check if there are any supervisors specified after "!stop"
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
+	if !h.supervisorExists(supervisor) {
+		return respondEphemeral(s, i, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
+	}
 
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+	switch data.Name {
+	case "start":
+		return h.handleStart(s, i, supervisor)
+	case "stop":
+		return h.handleStop(s, i, supervisor, discordgo.InteractionResponseChannelMessageWithSource)
+	case "pause":
+		return h.handlePause(s, i, supervisor, discordgo.InteractionResponseChannelMessageWithSource)
+	case "status", "stats":
+		return respond(s, i, discordgo.InteractionResponseChannelMessageWithSource, h.statusMessage(supervisor))
+	}
 
-			// Check if the supervisor is running
-			if b.manager.Status(supervisor).SupervisorStatus == bot.NotStarted || b.manager.Status(supervisor).SupervisorStatus == "" {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is not running.", supervisor))
-				continue
-			}
+	return respondEphemeral(s, i, fmt.Sprintf("Unknown command '%s'.", data.Name))
+}
 
-			// Attempt to stop the specified supervisor
-			b.manager.Stop(supervisor)
+// handleButton handles the pause/stop buttons of the status embed, the embed is updated in place
+func (h *commandHandler) handleButton(s Session, i *discordgo.Interaction) error {
+	action, supervisor, found := strings.Cut(i.MessageComponentData().CustomID, ":")
+	if !found || !h.supervisorExists(supervisor) {
+		return respondEphemeral(s, i, "Supervisor not found.")
+	}
 
-			// Wait for the supervisor to stop
-			time.Sleep(1 * time.Second)
+	switch action {
+	case pauseButtonID:
+		return h.handlePause(s, i, supervisor, discordgo.InteractionResponseUpdateMessage)
+	case stopButtonID:
+		return h.handleStop(s, i, supervisor, discordgo.InteractionResponseUpdateMessage)
+	}
+
+	return respondEphemeral(s, i, fmt.Sprintf("Unknown action '%s'.", action))
+}
 
-			// Send a confirmation message
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' has been stopped.", supervisor))
+func (h *commandHandler) handleAutocomplete(s Session, i *discordgo.Interaction) error {
+	typed := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Focused {
+			typed = strings.ToLower(opt.StringValue())
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !stop <supervisor1> [supervisor2] ...")
 	}
-}
 
-func (b *Bot) handleStatusRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+	supervisors := h.manager.AvailableSupervisors()
+	sort.Strings(supervisors)
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, supervisor := range supervisors {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+		if strings.Contains(strings.ToLower(supervisor), typed) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: supervisor, Value: supervisor})
+		}
+	}
 
-	// Split the message content into words
-	words := strings.Fields(m.Content)
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+}
 
-	if len(words) > 1 {
-		for _, supervisor := range words[1:] {
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+func (h *commandHandler) handleStart(s Session, i *discordgo.Interaction, supervisor string) error {
+	if h.isRunning(supervisor) {
+		return respondEphemeral(s, i, fmt.Sprintf("Supervisor '%s' is already running.", supervisor))
+	}
 
-			status := b.manager.Status(supervisor)
-			if status.SupervisorStatus == bot.NotStarted || status.SupervisorStatus == "" {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is offline.", supervisor))
-				continue
-			}
+	respondErr := respond(s, i, discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("Supervisor '%s' is starting.", supervisor),
+	})
 
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is %s", supervisor, status.SupervisorStatus))
+	// Start keeps running the game loop until the supervisor is stopped, errors are reported as a follow-up message,
+	// which Discord only accepts once the interaction is acknowledged
+	go func() {
+		err := h.manager.Start(supervisor, false)
+		if err == nil {
+			return
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !status <supervisor1> [supervisor2] ...")
-	}
-}
-
-func (b *Bot) handleStatsRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
-
-	// Split the message content into words
-	words := strings.Fields(m.Content)
-
-	// Check if there are any supervisors specified after "!stats"
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
-
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
-
-			// Fix for the status not being started
-			supStatus := string(b.manager.Status(supervisor).SupervisorStatus)
-			if supStatus == string(bot.NotStarted) || supStatus == "" {
-				supStatus = "Offline"
-			}
-			// Create the embed
-			embed := &discordgo.MessageEmbed{
-				Title: fmt.Sprintf("Stats for %s", supervisor),
-				Fields: []*discordgo.MessageEmbedField{
-					{
-						Name:   "Status",
-						Value:  supStatus,
-						Inline: true,
-					},
-					{
-						Name:   "Uptime",
-						Value:  time.Since(b.manager.Status(supervisor).StartedAt).String(),
-						Inline: true,
-					},
-
-					// Runs data
-
-					{
-						Name:   "Games",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalGames()),
-						Inline: true,
-					},
-					{
-						Name:   "Drops",
-						Value:  fmt.Sprintf("%d", len(b.manager.GetSupervisorStats(supervisor).Drops)),
-						Inline: true,
-					},
-					{
-						Name:   "Deaths",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalDeaths()),
-						Inline: true,
-					},
-					{
-						Name:   "Chickens",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalChickens()),
-						Inline: true,
-					},
-					{
-						Name:   "Errors",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalErrors()),
-						Inline: true,
-					},
-				},
-			}
-
-			// Send the embed to the channel
-			s.ChannelMessageSendEmbed(m.ChannelID, embed)
+		if respondErr != nil {
+			h.logger.Error("error starting supervisor", slog.String("supervisor", supervisor), slog.Any("error", err))
+			return
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !stats <supervisor1> [supervisor2] ...")
+
+		_, err = s.FollowupMessageCreate(i, false, &discordgo.WebhookParams{
+			Content: fmt.Sprintf("Error starting supervisor '%s': %s", supervisor, err.Error()),
+		})
+		if err != nil {
+			h.logger.Error("error sending Discord follow-up message", slog.Any("error", err))
+		}
+	}()
+
+	return respondErr
+}
+
+func (h *commandHandler) handleStop(s Session, i *discordgo.Interaction, supervisor string, responseType discordgo.InteractionResponseType) error {
+	if !h.isRunning(supervisor) {
+		return respondEphemeral(s, i, fmt.Sprintf("Supervisor '%s' is not running.", supervisor))
 	}
+
+	h.manager.Stop(supervisor)
+
+	return respond(s, i, responseType, h.statusMessage(supervisor))
+}
+
+func (h *commandHandler) handlePause(s Session, i *discordgo.Interaction, supervisor string, responseType discordgo.InteractionResponseType) error {
+	if !h.isRunning(supervisor) {
+		return respondEphemeral(s, i, fmt.Sprintf("Supervisor '%s' is not running.", supervisor))
+	}
+
+	h.manager.TogglePause(supervisor)
+
+	return respond(s, i, responseType, h.statusMessage(supervisor))
+}
+
+func (h *commandHandler) handleStatusAll(s Session, i *discordgo.Interaction) error {
+	supervisors := h.manager.AvailableSupervisors()
+	if len(supervisors) == 0 {
+		return respondEphemeral(s, i, "No supervisors configured.")
+	}
+	sort.Strings(supervisors)
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(supervisors))
+	for _, supervisor := range supervisors {
+		stats := h.manager.Status(supervisor)
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   supervisor,
+			Value:  fmt.Sprintf("%s\nGames: %d | Drops: %d", statusText(stats), stats.TotalGames(), len(stats.Drops)),
+			Inline: true,
+		})
+	}
+
+	return respond(s, i, discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{{Title: "Supervisors", Fields: fields}},
+	})
+}
+
+// statusMessage builds the status embed of a supervisor, with pause/stop buttons while it's running
+func (h *commandHandler) statusMessage(supervisor string) *discordgo.InteractionResponseData {
+	stats := h.manager.Status(supervisor)
+	running := h.isRunning(supervisor)
+
+	uptime := "-"
+	if running && !stats.StartedAt.IsZero() {
+		uptime = time.Since(stats.StartedAt).Round(time.Second).String()
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Stats for %s", supervisor),
+		Color: statusColor(stats.SupervisorStatus),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: statusText(stats), Inline: true},
+			{Name: "Uptime", Value: uptime, Inline: true},
+			{Name: "Games", Value: fmt.Sprintf("%d", stats.TotalGames()), Inline: true},
+			{Name: "Drops", Value: fmt.Sprintf("%d", len(stats.Drops)), Inline: true},
+			{Name: "Deaths", Value: fmt.Sprintf("%d", stats.TotalDeaths()), Inline: true},
+			{Name: "Chickens", Value: fmt.Sprintf("%d", stats.TotalChickens()), Inline: true},
+			{Name: "Errors", Value: fmt.Sprintf("%d", stats.TotalErrors()), Inline: true},
+		},
+	}
+
+	pauseLabel := "Pause"
+	if stats.SupervisorStatus == bot.Paused {
+		pauseLabel = "Resume"
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: pauseLabel, Style: discordgo.PrimaryButton, CustomID: pauseButtonID + ":" + supervisor, Disabled: !running},
+				discordgo.Button{Label: "Stop", Style: discordgo.DangerButton, CustomID: stopButtonID + ":" + supervisor, Disabled: !running},
+			}},
+		},
+	}
+}
+
+func (h *commandHandler) supervisorExists(supervisor string) bool {
+	return slices.Contains(h.manager.AvailableSupervisors(), supervisor)
+}
+
+func (h *commandHandler) isRunning(supervisor string) bool {
+	status := h.manager.Status(supervisor).SupervisorStatus
+	return status != bot.NotStarted && status != ""
+}
+
+func isBotAdmin(i *discordgo.Interaction) bool {
+	userID := ""
+	if i.Member != nil && i.Member.User != nil {
+		userID = i.Member.User.ID
+	} else if i.User != nil {
+		userID = i.User.ID
+	}
+
+	return userID != "" && slices.Contains(config.Koolo.Discord.BotAdmins, userID)
+}
+
+func statusText(stats bot.Stats) string {
+	if stats.SupervisorStatus == bot.NotStarted || stats.SupervisorStatus == "" {
+		return "Offline"
+	}
+
+	return string(stats.SupervisorStatus)
+}
+
+func statusColor(status bot.SupervisorStatus) int {
+	switch status {
+	case bot.InGame:
+		return 0x2ecc71
+	case bot.Starting, bot.Paused:
+		return 0xf1c40f
+	case bot.Crashed:
+		return 0xe74c3c
+	default:
+		return 0x95a5a6
+	}
+}
+
+func respond(s Session, i *discordgo.Interaction, responseType discordgo.InteractionResponseType, data *discordgo.InteractionResponseData) error {
+	return s.InteractionRespond(i, &discordgo.InteractionResponse{Type: responseType, Data: data})
+}
+
+func respondEphemeral(s Session, i *discordgo.Interaction, content string) error {
+	return respond(s, i, discordgo.InteractionResponseChannelMessageWithSource, &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
 }
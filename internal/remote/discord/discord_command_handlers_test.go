@@ -0,0 +1,248 @@
+package discord
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+const adminID = "1234"
+
+type fakeSession struct {
+	responses []*discordgo.InteractionResponse
+	followups chan string
+	// acknowledged is true if the interaction was responded before the last follow-up
+	acknowledged bool
+}
+
+func (s *fakeSession) InteractionRespond(_ *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func (s *fakeSession) FollowupMessageCreate(_ *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.acknowledged = len(s.responses) > 0
+	s.followups <- data.Content
+	return &discordgo.Message{}, nil
+}
+
+func (s *fakeSession) last(t *testing.T) *discordgo.InteractionResponse {
+	t.Helper()
+	if len(s.responses) == 0 {
+		t.Fatal("expected a response")
+	}
+
+	return s.responses[len(s.responses)-1]
+}
+
+type fakeManager struct {
+	statuses map[string]bot.SupervisorStatus
+	started  chan string
+	startErr error
+	stopped  []string
+	paused   []string
+}
+
+func (m *fakeManager) AvailableSupervisors() []string {
+	supervisors := make([]string, 0, len(m.statuses))
+	for name := range m.statuses {
+		supervisors = append(supervisors, name)
+	}
+
+	return supervisors
+}
+
+func (m *fakeManager) Start(supervisorName string, _ bool, _ ...uint32) error {
+	m.started <- supervisorName
+	return m.startErr
+}
+
+func (m *fakeManager) Stop(supervisor string) {
+	m.stopped = append(m.stopped, supervisor)
+	m.statuses[supervisor] = bot.NotStarted
+}
+
+func (m *fakeManager) TogglePause(supervisor string) {
+	m.paused = append(m.paused, supervisor)
+	m.statuses[supervisor] = bot.Paused
+}
+
+func (m *fakeManager) Status(characterName string) bot.Stats {
+	return bot.Stats{SupervisorStatus: m.statuses[characterName], StartedAt: time.Now()}
+}
+
+func newTestHandler() (*commandHandler, *fakeManager, *fakeSession) {
+	config.Koolo = &config.KooloCfg{}
+	config.Koolo.Discord.BotAdmins = []string{adminID}
+
+	manager := &fakeManager{
+		statuses: map[string]bot.SupervisorStatus{"sorc": bot.InGame, "pally": bot.NotStarted, "barb": ""},
+		started:  make(chan string, 1),
+	}
+
+	return newCommandHandler(manager, slog.New(slog.NewTextHandler(io.Discard, nil))), manager, &fakeSession{followups: make(chan string, 1)}
+}
+
+func commandInteraction(userID, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type:   discordgo.InteractionApplicationCommand,
+		Member: &discordgo.Member{User: &discordgo.User{ID: userID}},
+		Data:   discordgo.ApplicationCommandInteractionData{Name: name, Options: options},
+	}
+}
+
+func supervisorArg(name string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{Name: supervisorOption, Type: discordgo.ApplicationCommandOptionString, Value: name}
+}
+
+func TestCommandRejectsNonAdmin(t *testing.T) {
+	h, manager, s := newTestHandler()
+
+	if err := h.Handle(s, commandInteraction("999", "stop", supervisorArg("sorc"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manager.stopped) > 0 {
+		t.Error("non admin user stopped a supervisor")
+	}
+	if s.last(t).Data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Error("expected an ephemeral rejection")
+	}
+}
+
+func TestStartCommand(t *testing.T) {
+	h, manager, s := newTestHandler()
+
+	if err := h.Handle(s, commandInteraction(adminID, "start", supervisorArg("pally"))); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case name := <-manager.started:
+		if name != "pally" {
+			t.Errorf("expected pally to be started, got %s", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("supervisor was not started")
+	}
+
+	// Already running supervisors are not started twice
+	if err := h.Handle(s, commandInteraction(adminID, "start", supervisorArg("sorc"))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-manager.started:
+		t.Error("running supervisor was started again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStartCommandReportsErrors(t *testing.T) {
+	h, manager, s := newTestHandler()
+	manager.startErr = errors.New("invalid config: game.runs: unknown run")
+
+	if err := h.Handle(s, commandInteraction(adminID, "start", supervisorArg("pally"))); err != nil {
+		t.Fatal(err)
+	}
+	<-manager.started
+
+	select {
+	case msg := <-s.followups:
+		if !strings.Contains(msg, "invalid config") {
+			t.Errorf("expected the start error in the follow-up, got %s", msg)
+		}
+		if !s.acknowledged {
+			t.Errorf("expected the follow-up to be sent after responding to the interaction")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("start error was not reported")
+	}
+}
+
+func TestStatusEmbedHasButtons(t *testing.T) {
+	h, _, s := newTestHandler()
+
+	if err := h.Handle(s, commandInteraction(adminID, "status", supervisorArg("sorc"))); err != nil {
+		t.Fatal(err)
+	}
+
+	data := s.last(t).Data
+	if len(data.Embeds) != 1 || data.Embeds[0].Fields[0].Value != string(bot.InGame) {
+		t.Fatalf("unexpected status embed: %+v", data.Embeds)
+	}
+
+	buttons := data.Components[0].(discordgo.ActionsRow).Components
+	if len(buttons) != 2 {
+		t.Fatalf("expected pause and stop buttons, got %d components", len(buttons))
+	}
+	if id := buttons[1].(discordgo.Button).CustomID; id != "stop:sorc" {
+		t.Errorf("unexpected stop button id: %s", id)
+	}
+}
+
+func TestButtonsUpdateMessage(t *testing.T) {
+	h, manager, s := newTestHandler()
+
+	i := &discordgo.Interaction{
+		Type:   discordgo.InteractionMessageComponent,
+		Member: &discordgo.Member{User: &discordgo.User{ID: adminID}},
+		Data:   discordgo.MessageComponentInteractionData{CustomID: "pause:sorc"},
+	}
+	if err := h.Handle(s, i); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manager.paused) != 1 || manager.paused[0] != "sorc" {
+		t.Fatalf("expected sorc to be paused, got %v", manager.paused)
+	}
+
+	resp := s.last(t)
+	if resp.Type != discordgo.InteractionResponseUpdateMessage {
+		t.Errorf("expected the status message to be updated, got response type %d", resp.Type)
+	}
+	if label := resp.Data.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button).Label; label != "Resume" {
+		t.Errorf("expected resume button once paused, got %s", label)
+	}
+}
+
+func TestStopNotRunning(t *testing.T) {
+	h, manager, s := newTestHandler()
+
+	for _, supervisor := range []string{"pally", "barb"} {
+		if err := h.Handle(s, commandInteraction(adminID, "stop", supervisorArg(supervisor))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(manager.stopped) > 0 {
+		t.Errorf("stopped supervisors that were not running: %v", manager.stopped)
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	h, _, s := newTestHandler()
+
+	option := supervisorArg("PA")
+	option.Focused = true
+	i := commandInteraction("", "stop", option)
+	i.Type = discordgo.InteractionApplicationCommandAutocomplete
+
+	if err := h.Handle(s, i); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := s.last(t)
+	if resp.Type != discordgo.InteractionApplicationCommandAutocompleteResult {
+		t.Fatalf("unexpected response type %d", resp.Type)
+	}
+	if len(resp.Data.Choices) != 1 || resp.Data.Choices[0].Value != "pally" {
+		t.Errorf("unexpected choices: %+v", resp.Data.Choices)
+	}
+}
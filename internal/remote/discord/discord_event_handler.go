@@ -4,16 +4,22 @@ import (
 	"bytes"
 	"context"
 	"image/jpeg"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/remote/drops"
 )
 
 func (b *Bot) Handle(_ context.Context, e event.Event) error {
 	if b.shouldPublish(e) {
 
-		switch e.(type) {
+		switch evt := e.(type) {
+		case event.ItemStashedEvent:
+			return b.publishDrop(evt)
 		case event.GameCreatedEvent, event.GameFinishedEvent, event.RunStartedEvent, event.RunFinishedEvent:
 			_, err := b.discordSession.ChannelMessageSend(b.channelID, e.Message())
 			return err
@@ -38,9 +44,65 @@ func (b *Bot) Handle(_ context.Context, e event.Event) error {
 	return nil
 }
 
+func (b *Bot) publishDrop(e event.ItemStashedEvent) error {
+	details := drops.NewDetails(e)
+	embed := &discordgo.MessageEmbed{
+		Title:       details.Title(),
+		Description: strings.Join(details.Stats, "\n"),
+		Color:       qualityColor(e.Item.Item.Quality),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Supervisor", Value: details.Supervisor, Inline: true},
+			{Name: "Rule", Value: valueOrDash(details.Rule), Inline: false},
+			{Name: "Rule file", Value: valueOrDash(details.RuleFile), Inline: true},
+		},
+		Timestamp: e.OccurredAt().Format(time.RFC3339),
+	}
+
+	msg := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+	if img := drops.Screenshot(e); img != nil {
+		buf := new(bytes.Buffer)
+		if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 80}); err != nil {
+			return err
+		}
+		msg.Files = []*discordgo.File{{Name: "drop.jpeg", ContentType: "image/jpeg", Reader: buf}}
+		embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://drop.jpeg"}
+	}
+
+	_, err := b.discordSession.ChannelMessageSendComplex(b.channelID, msg)
+
+	return err
+}
+
+func qualityColor(q item.Quality) int {
+	switch q {
+	case item.QualityUnique:
+		return 0xc7b377
+	case item.QualitySet:
+		return 0x00ff00
+	case item.QualityRare:
+		return 0xffff77
+	case item.QualityMagic:
+		return 0x6969ff
+	case item.QualityCrafted:
+		return 0xffa800
+	default:
+		return 0xc4c4c4
+	}
+}
+
+func valueOrDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+
+	return v
+}
+
 func (b *Bot) shouldPublish(e event.Event) bool {
 
 	switch evt := e.(type) {
+	case event.ItemStashedEvent:
+		return drops.ShouldNotify(evt.Item)
 	case event.GameFinishedEvent:
 		if evt.Reason == event.FinishedChicken || evt.Reason == event.FinishedMercChicken || evt.Reason == event.FinishedDied {
 			return config.Koolo.Discord.EnableDiscordChickenMessages
@@ -0,0 +1,176 @@
+package drops
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// Tooltip area around the hovered item, tooltips are drawn above the cursor and can be quite tall
+const (
+	cropWidth  = 800
+	cropAbove  = 650
+	cropBelow  = 100
+	statsLimit = 20
+	// fieldLimit is the max length of the Discord embed field values, the shortest limit of the integrations
+	fieldLimit = 1024
+)
+
+// AvailableQualities are the quality names accepted by the filter, as returned by item.Quality.ToString
+var AvailableQualities = []string{"LowQuality", "Normal", "Superior", "Magic", "Set", "Rare", "Unique", "Crafted"}
+
+// Details contains the item information rendered by the remote integrations
+type Details struct {
+	Supervisor string
+	Name       string
+	Quality    string
+	Ethereal   bool
+	Identified bool
+	Stats      []string
+	Rule       string
+	RuleFile   string
+}
+
+// ShouldNotify returns if the drop matches the configured filter. When qualities and rules are both empty every
+// drop is notified, otherwise the drop has to match any of them.
+func ShouldNotify(d data.Drop) bool {
+	cfg := config.Koolo.DropNotifications
+	if !cfg.Enabled {
+		return false
+	}
+
+	if len(cfg.Qualities) == 0 && len(cfg.Rules) == 0 {
+		return true
+	}
+
+	quality := d.Item.Quality.ToString()
+	if slices.ContainsFunc(cfg.Qualities, func(q string) bool { return strings.EqualFold(strings.TrimSpace(q), quality) }) {
+		return true
+	}
+
+	rule := strings.ToLower(d.Rule)
+	ruleFile := strings.ToLower(d.RuleFile)
+	for _, r := range cfg.Rules {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r != "" && (strings.Contains(rule, r) || strings.Contains(ruleFile, r)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewDetails(e event.ItemStashedEvent) Details {
+	d := e.Item
+	stats := make([]string, 0, len(d.Item.Stats))
+	for _, st := range d.Item.Stats {
+		if len(stats) == statsLimit {
+			stats = append(stats, fmt.Sprintf("... and %d more", len(d.Item.Stats)-statsLimit))
+			break
+		}
+
+		if st.Layer != 0 {
+			stats = append(stats, fmt.Sprintf("%s (%d): %d", st.ID.String(), st.Layer, st.Value))
+			continue
+		}
+		stats = append(stats, fmt.Sprintf("%s: %d", st.ID.String(), st.Value))
+	}
+
+	return Details{
+		Supervisor: truncate(e.Supervisor(), fieldLimit),
+		Name:       string(d.Item.Name),
+		Quality:    d.Item.Quality.ToString(),
+		Ethereal:   d.Item.Ethereal,
+		Identified: d.Item.Identified,
+		Stats:      stats,
+		Rule:       truncate(d.Rule, fieldLimit),
+		RuleFile:   truncate(d.RuleFile, fieldLimit),
+	}
+}
+
+// truncate cuts the text to the given amount of characters, ending it with an ellipsis when it's cut
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	return string(runes[:length-1]) + "…"
+}
+
+// Title returns a short one line description of the drop, like "Shako [Unique, Ethereal]"
+func (d Details) Title() string {
+	tags := []string{d.Quality}
+	if d.Ethereal {
+		tags = append(tags, "Ethereal")
+	}
+	if !d.Identified {
+		tags = append(tags, "Unidentified")
+	}
+
+	return fmt.Sprintf("%s [%s]", d.Name, strings.Join(tags, ", "))
+}
+
+// Screenshot returns the event screenshot cropped around the item tooltip, nil if screenshots are disabled
+func Screenshot(e event.ItemStashedEvent) image.Image {
+	if !config.Koolo.DropNotifications.IncludeScreenshot || e.Image() == nil {
+		return nil
+	}
+
+	return CropAround(e.Image(), e.ScreenPosition)
+}
+
+// CropAround crops the image around the given position, keeping the area where tooltips are drawn
+func CropAround(img image.Image, pos data.Position) image.Image {
+	bounds := img.Bounds()
+	if pos.X == 0 && pos.Y == 0 {
+		return img
+	}
+
+	rect := image.Rect(pos.X-cropWidth/2, pos.Y-cropAbove, pos.X+cropWidth/2, pos.Y+cropBelow).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return img
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, rect.Min, draw.Src)
+
+	return cropped
+}
+
+// ParseQualities parses a comma separated list of qualities, used by the settings page
+func ParseQualities(qualities string) ([]string, error) {
+	parsed := make([]string, 0)
+	for _, q := range strings.Split(qualities, ",") {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			continue
+		}
+
+		idx := slices.IndexFunc(AvailableQualities, func(available string) bool { return strings.EqualFold(available, q) })
+		if idx == -1 {
+			return nil, fmt.Errorf("unknown item quality: %s", q)
+		}
+		parsed = append(parsed, AvailableQualities[idx])
+	}
+
+	return parsed, nil
+}
+
+// ParseRules parses a comma separated list of rule filters, used by the settings page
+func ParseRules(rules string) []string {
+	parsed := make([]string, 0)
+	for _, r := range strings.Split(rules, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			parsed = append(parsed, r)
+		}
+	}
+
+	return parsed
+}
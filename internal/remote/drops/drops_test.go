@@ -0,0 +1,133 @@
+package drops
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func drop(quality item.Quality, rule, ruleFile string) data.Drop {
+	return data.Drop{Item: data.Item{Name: "Shako", Quality: quality}, Rule: rule, RuleFile: ruleFile}
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		qualities []string
+		rules     []string
+		drop      data.Drop
+		expected  bool
+	}{
+		{name: "disabled", drop: drop(item.QualityUnique, "", ""), expected: false},
+		{name: "no filter", enabled: true, drop: drop(item.QualityMagic, "", ""), expected: true},
+		{name: "quality matches", enabled: true, qualities: []string{" unique "}, drop: drop(item.QualityUnique, "", ""), expected: true},
+		{name: "quality doesn't match", enabled: true, qualities: []string{"Set"}, drop: drop(item.QualityUnique, "", ""), expected: false},
+		{name: "rule matches", enabled: true, rules: []string{"BER"}, drop: drop(item.QualityNormal, "[name] == berrune", "runes.nip"), expected: true},
+		{name: "rule file matches", enabled: true, rules: []string{"runes.nip"}, drop: drop(item.QualityNormal, "[name] == jahrune", "runes.nip"), expected: true},
+		{name: "empty rule is ignored", enabled: true, rules: []string{" "}, drop: drop(item.QualityNormal, "[name] == jahrune", "runes.nip"), expected: false},
+		{name: "any filter matches", enabled: true, qualities: []string{"Set"}, rules: []string{"ring"}, drop: drop(item.QualityRare, "[type] == ring", "rare.nip"), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Koolo = &config.KooloCfg{}
+			config.Koolo.DropNotifications.Enabled = tt.enabled
+			config.Koolo.DropNotifications.Qualities = tt.qualities
+			config.Koolo.DropNotifications.Rules = tt.rules
+
+			if got := ShouldNotify(tt.drop); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseQualities(t *testing.T) {
+	qualities, err := ParseQualities(" unique, SET,,rare ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"Unique", "Set", "Rare"}; !slices.Equal(qualities, expected) {
+		t.Errorf("Expected %v, got %v", expected, qualities)
+	}
+
+	if qualities, err = ParseQualities(""); err != nil || len(qualities) != 0 {
+		t.Errorf("Expected no qualities, got %v: %v", qualities, err)
+	}
+	if _, err = ParseQualities("unique, legendary"); err == nil || !strings.Contains(err.Error(), "legendary") {
+		t.Errorf("Expected unknown quality error, got %v", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	if rules, expected := ParseRules(" ber , runes.nip,, "), []string{"ber", "runes.nip"}; !slices.Equal(rules, expected) {
+		t.Errorf("Expected %v, got %v", expected, rules)
+	}
+	if rules := ParseRules(""); rules == nil || len(rules) != 0 {
+		t.Errorf("Expected an empty list, got %v", rules)
+	}
+}
+
+func TestNewDetails(t *testing.T) {
+	d := drop(item.QualityUnique, strings.Repeat("ñ", 2000), "unique.nip")
+	for i := 0; i < statsLimit+5; i++ {
+		d.Item.Stats = append(d.Item.Stats, stat.Data{ID: stat.Strength, Value: i})
+	}
+
+	details := NewDetails(event.ItemStashedEvent{BaseEvent: event.Text("sorc", ""), Item: d})
+	if utf8.RuneCountInString(details.Rule) != fieldLimit || !strings.HasSuffix(details.Rule, "…") {
+		t.Errorf("Expected rule to be truncated to %d characters, got %d", fieldLimit, utf8.RuneCountInString(details.Rule))
+	}
+	if details.RuleFile != "unique.nip" || details.Supervisor != "sorc" {
+		t.Errorf("Expected short values to be kept, got %s and %s", details.RuleFile, details.Supervisor)
+	}
+	if len(details.Stats) != statsLimit+1 || details.Stats[statsLimit] != "... and 5 more" {
+		t.Errorf("Expected %d stats and a summary of the rest, got %d", statsLimit, len(details.Stats))
+	}
+	if title := details.Title(); title != "Shako [Unique, Unidentified]" {
+		t.Errorf("Expected title Shako [Unique, Unidentified], got %s", title)
+	}
+}
+
+func TestCropAround(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	img.Set(960, 500, color.White)
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		pos      data.Position
+		expected image.Rectangle
+	}{
+		{name: "no position", img: img, pos: data.Position{}, expected: img.Bounds()},
+		{name: "centered", img: img, pos: data.Position{X: 960, Y: 800}, expected: image.Rect(0, 0, cropWidth, cropAbove+cropBelow)},
+		{name: "clamped to the top left corner", img: img, pos: data.Position{X: 100, Y: 200}, expected: image.Rect(0, 0, 100+cropWidth/2, 200+cropBelow)},
+		{name: "clamped to the bottom right corner", img: img, pos: data.Position{X: 1900, Y: 1050}, expected: image.Rect(0, 0, 20+cropWidth/2, cropAbove+30)},
+		{name: "outside the image", img: img, pos: data.Position{X: 5000, Y: 5000}, expected: img.Bounds()},
+		{name: "bounds not starting at zero", img: img.SubImage(image.Rect(100, 100, 1920, 1080)), pos: data.Position{X: 50, Y: 50}, expected: image.Rect(0, 0, 50+cropWidth/2, 50+cropBelow)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CropAround(tt.img, tt.pos).Bounds(); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// The pixels are copied from the cropped area
+	cropped := CropAround(img, data.Position{X: 960, Y: 800})
+	if r, _, _, _ := cropped.At(cropWidth/2, 500-(800-cropAbove)).RGBA(); r != 0xffff {
+		t.Errorf("Expected the white pixel to be copied")
+	}
+}
@@ -3,31 +3,36 @@ package telegram
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
 )
 
 type Bot struct {
-	bot    *tgbotapi.BotAPI
-	chatID int64
-	logger *slog.Logger
+	bot     *tgbotapi.BotAPI
+	chatID  int64
+	manager *bot.SupervisorManager
+	logger  *slog.Logger
 }
 
-func NewBot(token string, chatID int64, logger *slog.Logger) (*Bot, error) {
+func NewBot(token string, chatID int64, manager *bot.SupervisorManager, logger *slog.Logger) (*Bot, error) {
 	bot, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Bot{
-		bot:    bot,
-		chatID: chatID,
-		logger: logger,
+		bot:     bot,
+		chatID:  chatID,
+		manager: manager,
+		logger:  logger,
 	}, nil
 }
 
-func (b *Bot) Start(_ context.Context) error {
+func (b *Bot) Start(ctx context.Context) error {
 	offset, err := b.getLatestOffset()
 	if err != nil {
 		return err
@@ -36,22 +41,62 @@ func (b *Bot) Start(_ context.Context) error {
 	u := tgbotapi.NewUpdate(offset)
 	u.Timeout = 5
 	updates := b.bot.GetUpdatesChan(u)
-	for update := range updates {
-		if update.Message != nil && update.Message.Chat.ID == b.chatID { // If we got a message
-			switch strings.ToLower(update.Message.Text) {
-			case "stats":
-				if err := b.publishStats(); err != nil {
-					b.logger.Error("error sending telegram message", slog.Any("error", err))
-				}
-			case "start":
-				// TODO: Implement
-			case "stop":
-				//b.sueprvisor.Stop()
+	defer b.bot.StopReceivingUpdates()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if update.Message != nil && b.isAllowed(update.Message) { // If we got a message
+				b.onMessage(update.Message)
 			}
+		case <-ctx.Done():
+			return nil
 		}
 	}
+}
+
+// isAllowed only accepts messages from the configured chat, and from the bot admins if any is configured
+func (b *Bot) isAllowed(m *tgbotapi.Message) bool {
+	if m.Chat == nil || m.Chat.ID != b.chatID {
+		return false
+	}
+
+	if len(config.Koolo.Telegram.BotAdmins) == 0 {
+		return true
+	}
 
-	return nil
+	return m.From != nil && slices.Contains(config.Koolo.Telegram.BotAdmins, m.From.ID)
+}
+
+func (b *Bot) onMessage(m *tgbotapi.Message) {
+	words := strings.Fields(m.Text)
+	if len(words) == 0 {
+		return
+	}
+
+	// Commands are accepted with or without slash, "/stats@koolo_bot" is also valid in group chats
+	command, _, _ := strings.Cut(strings.TrimPrefix(strings.ToLower(words[0]), "/"), "@")
+	args := words[1:]
+
+	switch command {
+	case "start":
+		b.handleStartRequest(args)
+	case "stop":
+		b.handleStopRequest(args)
+	case "pause":
+		b.handlePauseRequest(args)
+	case "status":
+		b.handleStatusRequest(args)
+	case "stats":
+		b.handleStatsRequest(args)
+	case "drops":
+		b.handleDropsRequest(args)
+	case "help":
+		b.sendMessage(helpMessage)
+	}
 }
 
 func (b *Bot) getLatestOffset() (int, error) {
@@ -69,27 +114,10 @@ func (b *Bot) getLatestOffset() (int, error) {
 	return offset, nil
 }
 
-func (b *Bot) publishStats() error {
-	return nil
-	//msg := "Run | Items | Deaths | Chickens | Merc Chickens | Errors | Healing Pots | Mana Pots | Reju Pots | Merc Pots \n"
-	//for run, st := range stat.Status.RunStats {
-	//	msg += fmt.Sprintf(
-	//		"%s | %d | %d | %d | %d | %d | %d | %d| %d | %d | %d \n",
-	//		run,
-	//		len(st.ItemsFound),
-	//		st.Kills,
-	//		st.Deaths,
-	//		st.Chickens,
-	//		st.MerChicken,
-	//		st.Errors,
-	//		st.HealingPotionsUsed,
-	//		st.ManaPotionsUsed,
-	//		st.RejuvPotionsUsed,
-	//		st.MercHealingPotionsUsed,
-	//	)
-	//}
-	//
-	//_, err := b.bot.Send(tgbotapi.NewMessage(b.chatID, msg))
-	//
-	//return err
+func (b *Bot) sendMessage(text string) {
+	msg := tgbotapi.NewMessage(b.chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.bot.Send(msg); err != nil {
+		b.logger.Error("error sending telegram message", slog.Any("error", err))
+	}
 }
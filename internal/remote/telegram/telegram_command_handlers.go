@@ -0,0 +1,295 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/analytics"
+	"github.com/hectorgimenez/koolo/internal/bot"
+)
+
+const (
+	helpMessage = `<b>Koolo commands</b>
+/start &lt;supervisor1&gt; [supervisor2] ...
+/stop &lt;supervisor1&gt; [supervisor2] ...
+/pause &lt;supervisor1&gt; [supervisor2] ...
+/status [supervisor1] ... (all if empty)
+/stats [supervisor1] ... (all if empty)
+/drops &lt;supervisor&gt; [amount]`
+
+	defaultDropsAmount = 10
+	maxDropsAmount     = 100
+	// maxMessageLength is the Telegram limit of a message. It applies to the text after parsing the HTML tags, so
+	// checking it against the raw text is conservative.
+	maxMessageLength = 4096
+)
+
+func (b *Bot) supervisorExists(supervisor string) bool {
+	return slices.Contains(b.manager.AvailableSupervisors(), supervisor)
+}
+
+func (b *Bot) isRunning(supervisor string) bool {
+	status := b.manager.Status(supervisor).SupervisorStatus
+	return status != bot.NotStarted && status != ""
+}
+
+// supervisorsOrAll returns the given supervisors, or all the available ones if none was given
+func (b *Bot) supervisorsOrAll(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+
+	supervisors := b.manager.AvailableSupervisors()
+	sort.Strings(supervisors)
+
+	return supervisors
+}
+
+func (b *Bot) handleStartRequest(args []string) {
+	if len(args) == 0 {
+		b.sendMessage("Usage: /start &lt;supervisor1&gt; [supervisor2] ...")
+		return
+	}
+
+	for _, supervisor := range args {
+		if !b.supervisorExists(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' not found.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		if b.isRunning(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' is already running.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		// Start keeps running the game loop until the supervisor is stopped
+		go func(supervisor string) {
+			if err := b.manager.Start(supervisor, false); err != nil {
+				b.sendMessage(fmt.Sprintf("Error starting supervisor '%s': %s", html.EscapeString(supervisor), html.EscapeString(err.Error())))
+			}
+		}(supervisor)
+
+		b.sendMessage(fmt.Sprintf("Supervisor '%s' is starting.", html.EscapeString(supervisor)))
+	}
+}
+
+func (b *Bot) handleStopRequest(args []string) {
+	if len(args) == 0 {
+		b.sendMessage("Usage: /stop &lt;supervisor1&gt; [supervisor2] ...")
+		return
+	}
+
+	for _, supervisor := range args {
+		if !b.supervisorExists(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' not found.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		if !b.isRunning(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' is not running.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		b.manager.Stop(supervisor)
+		b.sendMessage(fmt.Sprintf("Supervisor '%s' has been stopped.", html.EscapeString(supervisor)))
+	}
+}
+
+func (b *Bot) handlePauseRequest(args []string) {
+	if len(args) == 0 {
+		b.sendMessage("Usage: /pause &lt;supervisor1&gt; [supervisor2] ...")
+		return
+	}
+
+	for _, supervisor := range args {
+		if !b.supervisorExists(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' not found.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		if !b.isRunning(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' is not running.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		b.manager.TogglePause(supervisor)
+		b.sendMessage(fmt.Sprintf("Supervisor '%s' pause toggled.", html.EscapeString(supervisor)))
+	}
+}
+
+func (b *Bot) handleStatusRequest(args []string) {
+	lines := make([]string, 0)
+	for _, supervisor := range b.supervisorsOrAll(args) {
+		if !b.supervisorExists(supervisor) {
+			lines = append(lines, fmt.Sprintf("<b>%s</b>: not found", html.EscapeString(supervisor)))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("<b>%s</b>: %s", html.EscapeString(supervisor), statusText(b.manager.Status(supervisor))))
+	}
+
+	if len(lines) == 0 {
+		b.sendMessage("No supervisors configured.")
+		return
+	}
+
+	b.sendMessage(strings.Join(lines, "\n"))
+}
+
+func (b *Bot) handleStatsRequest(args []string) {
+	for _, supervisor := range b.supervisorsOrAll(args) {
+		if !b.supervisorExists(supervisor) {
+			b.sendMessage(fmt.Sprintf("Supervisor '%s' not found.", html.EscapeString(supervisor)))
+			continue
+		}
+
+		for _, msg := range formatStats(supervisor, b.manager.Status(supervisor)) {
+			b.sendMessage(msg)
+		}
+	}
+}
+
+func (b *Bot) handleDropsRequest(args []string) {
+	if len(args) == 0 {
+		b.sendMessage("Usage: /drops &lt;supervisor&gt; [amount]")
+		return
+	}
+
+	supervisor := args[0]
+	if !b.supervisorExists(supervisor) {
+		b.sendMessage(fmt.Sprintf("Supervisor '%s' not found.", html.EscapeString(supervisor)))
+		return
+	}
+
+	amount := defaultDropsAmount
+	if len(args) > 1 {
+		if n, err := strconv.Atoi(args[1]); err == nil && n > 0 {
+			amount = min(n, maxDropsAmount)
+		}
+	}
+
+	drops := b.manager.Status(supervisor).Drops
+	if len(drops) == 0 {
+		b.sendMessage(fmt.Sprintf("No drops yet for '%s'.", html.EscapeString(supervisor)))
+		return
+	}
+
+	lines := []string{fmt.Sprintf("<b>Last drops for %s</b> (%d total)", html.EscapeString(supervisor), len(drops))}
+	for i := len(drops) - 1; i >= 0 && i >= len(drops)-amount; i-- {
+		d := drops[i]
+		lines = append(lines, fmt.Sprintf("• %s [%s] - %s", html.EscapeString(string(d.Item.Name)), d.Item.Quality.ToString(), html.EscapeString(d.Rule)))
+	}
+
+	for _, msg := range splitMessage(lines, maxMessageLength) {
+		b.sendMessage(msg)
+	}
+}
+
+// splitMessage joins the lines in as few messages as possible without exceeding the limit. Lines are kept whole, so
+// their HTML tags stay closed, unless a single line is longer than the limit, then it's truncated.
+func splitMessage(lines []string, limit int) []string {
+	messages := make([]string, 0)
+	msg := ""
+	for _, line := range lines {
+		line = truncate(line, limit)
+		if msg != "" && len(msg)+1+len(line) > limit {
+			messages = append(messages, msg)
+			msg = ""
+		}
+		if msg != "" {
+			msg += "\n"
+		}
+		msg += line
+	}
+	if msg != "" {
+		messages = append(messages, msg)
+	}
+
+	return messages
+}
+
+// truncate cuts the text to the given length in bytes without splitting a UTF-8 character
+func truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+
+	cut := 0
+	for i := range text {
+		if i > length-len("…") {
+			break
+		}
+		cut = i
+	}
+
+	return text[:cut] + "…"
+}
+
+func statusText(stats bot.Stats) string {
+	if stats.SupervisorStatus == bot.NotStarted || stats.SupervisorStatus == "" {
+		return "Offline"
+	}
+
+	return string(stats.SupervisorStatus)
+}
+
+// formatStats returns the stats split in messages under the Telegram length limit
+func formatStats(supervisor string, stats bot.Stats) []string {
+	lines := []string{
+		fmt.Sprintf("<b>Stats for %s</b>", html.EscapeString(supervisor)),
+		fmt.Sprintf("Status: %s", statusText(stats)),
+	}
+	if !stats.StartedAt.IsZero() {
+		lines = append(lines, fmt.Sprintf("Uptime: %s", time.Since(stats.StartedAt).Round(time.Second)))
+	}
+	lines = append(lines,
+		fmt.Sprintf("Games: %d | Drops: %d", stats.TotalGames(), len(stats.Drops)),
+		fmt.Sprintf("Deaths: %d | Chickens: %d | Errors: %d", stats.TotalDeaths(), stats.TotalChickens(), stats.TotalErrors()),
+	)
+
+	runs := analytics.Compute(stats)
+	if len(runs) > 0 {
+		rows := make([]string, 0, len(runs))
+		for _, r := range runs {
+			rows = append(rows, fmt.Sprintf(
+				"%s | %d | %s | %d | %d | %d | %.1f | %d",
+				html.EscapeString(r.Name),
+				r.Runs,
+				r.AvgDuration.Round(time.Second),
+				r.Deaths,
+				r.Chickens,
+				r.Errors,
+				r.PotionsPerRun,
+				r.Drops,
+			))
+		}
+		lines = append(lines, "")
+		lines = append(lines, preBlocks("Run | Runs | Avg | Deaths | Chickens | Errors | Pots/run | Drops", rows, maxMessageLength)...)
+	}
+
+	return splitMessage(lines, maxMessageLength)
+}
+
+// preBlocks groups the table rows in <pre> blocks not longer than the limit, every block starts with the header, so
+// the table can be split between messages keeping its tags closed
+func preBlocks(header string, rows []string, limit int) []string {
+	const open, closing = "<pre>", "</pre>"
+
+	blocks := make([]string, 0)
+	block := header
+	for _, row := range rows {
+		row = truncate(row, limit-len(open)-len(header)-len("\n")-len(closing))
+		if block != header && len(open)+len(block)+len("\n")+len(row)+len(closing) > limit {
+			blocks = append(blocks, open+block+closing)
+			block = header
+		}
+		block += "\n" + row
+	}
+
+	return append(blocks, open+block+closing)
+}
@@ -0,0 +1,56 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessage(t *testing.T) {
+	lines := []string{"<b>Last drops</b>", strings.Repeat("a", 5), strings.Repeat("b", 5), strings.Repeat("c", 12)}
+
+	messages := splitMessage(lines, 24)
+	expected := []string{"<b>Last drops</b>\naaaaa", "bbbbb\ncccccccccccc"}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %q", len(expected), messages)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("Expected message %q, got %q", expected[i], messages[i])
+		}
+	}
+
+	if messages = splitMessage(nil, 24); len(messages) != 0 {
+		t.Errorf("Expected no messages without lines, got %q", messages)
+	}
+}
+
+func TestSplitMessageTruncatesLongLines(t *testing.T) {
+	messages := splitMessage([]string{strings.Repeat("ñ", 20)}, 16)
+
+	if len(messages) != 1 || len(messages[0]) > 16 || !utf8.ValidString(messages[0]) || !strings.HasSuffix(messages[0], "…") {
+		t.Errorf("Expected a single valid message truncated to 16 bytes, got %q", messages)
+	}
+}
+
+func TestPreBlocks(t *testing.T) {
+	rows := []string{strings.Repeat("a", 10), strings.Repeat("b", 10), strings.Repeat("c", 10)}
+
+	blocks := preBlocks("Run", rows, 40)
+	expected := []string{"<pre>Run\naaaaaaaaaa\nbbbbbbbbbb</pre>", "<pre>Run\ncccccccccc</pre>"}
+	if len(blocks) != len(expected) {
+		t.Fatalf("Expected %d blocks, got %q", len(expected), blocks)
+	}
+	for i := range expected {
+		if blocks[i] != expected[i] {
+			t.Errorf("Expected block %q, got %q", expected[i], blocks[i])
+		}
+	}
+
+	// Every message keeps its blocks closed
+	for _, msg := range splitMessage(append([]string{"<b>Stats</b>", ""}, blocks...), 40) {
+		if strings.Count(msg, "<pre>") != strings.Count(msg, "</pre>") || len(msg) > 40 {
+			t.Errorf("Expected a message with closed blocks under the limit, got %q", msg)
+		}
+	}
+}
@@ -3,13 +3,27 @@ package telegram
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"html"
 	"image/jpeg"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/remote/drops"
 )
 
+// Telegram doesn't accept photo captions longer than 1024 characters
+const maxCaptionLength = 1024
+
 func (b *Bot) Handle(_ context.Context, e event.Event) error {
+	if evt, ok := e.(event.ItemStashedEvent); ok {
+		if !drops.ShouldNotify(evt.Item) {
+			return nil
+		}
+		return b.publishDrop(evt)
+	}
+
 	if e.Image() != nil {
 		buf := new(bytes.Buffer)
 		err := jpeg.Encode(buf, e.Image(), nil)
@@ -32,3 +46,43 @@ func (b *Bot) Handle(_ context.Context, e event.Event) error {
 
 	return err
 }
+
+func (b *Bot) publishDrop(e event.ItemStashedEvent) error {
+	text := formatDrop(drops.NewDetails(e))
+
+	img := drops.Screenshot(e)
+	if img == nil || len(text) > maxCaptionLength {
+		b.sendMessage(text)
+		if img == nil {
+			return nil
+		}
+		text = ""
+	}
+
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return err
+	}
+
+	photo := tgbotapi.NewPhoto(b.chatID, tgbotapi.FileBytes{Name: "drop.jpeg", Bytes: buf.Bytes()})
+	photo.Caption = text
+	photo.ParseMode = tgbotapi.ModeHTML
+	_, err := b.bot.Send(photo)
+
+	return err
+}
+
+func formatDrop(d drops.Details) string {
+	msg := fmt.Sprintf("<b>%s</b>\nSupervisor: %s\n", html.EscapeString(d.Title()), html.EscapeString(d.Supervisor))
+	if len(d.Stats) > 0 {
+		msg += html.EscapeString(strings.Join(d.Stats, "\n")) + "\n"
+	}
+	if d.Rule != "" {
+		msg += fmt.Sprintf("Rule: <code>%s</code>\n", html.EscapeString(d.Rule))
+	}
+	if d.RuleFile != "" {
+		msg += fmt.Sprintf("File: %s\n", html.EscapeString(d.RuleFile))
+	}
+
+	return msg
+}
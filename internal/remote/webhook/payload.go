@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/jpeg"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// SchemaVersion is increased on every breaking change of the Payload format
+const SchemaVersion = 1
+
+const (
+	TypeText                 = "text"
+	TypeGameCreated          = "game_created"
+	TypeGameFinished         = "game_finished"
+	TypeRunStarted           = "run_started"
+	TypeRunFinished          = "run_finished"
+	TypeItemStashed          = "item_stashed"
+	TypeUsedPotion           = "used_potion"
+	TypeGamePaused           = "game_paused"
+	TypeInteractedTo         = "interacted_to"
+	TypeCompanionAttack      = "companion_leader_attack"
+	TypeCompanionRequestedTP = "companion_requested_tp"
+	TypeConfigReloaded       = "config_reloaded"
+)
+
+var AvailableEventTypes = []string{
+	TypeText,
+	TypeGameCreated,
+	TypeGameFinished,
+	TypeRunStarted,
+	TypeRunFinished,
+	TypeItemStashed,
+	TypeUsedPotion,
+	TypeGamePaused,
+	TypeInteractedTo,
+	TypeCompanionAttack,
+	TypeCompanionRequestedTP,
+	TypeConfigReloaded,
+}
+
+// Payload is the JSON body POSTed for every event
+type Payload struct {
+	Version    int       `json:"version"`
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Supervisor string    `json:"supervisor"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Message    string    `json:"message"`
+	Data       any       `json:"data,omitempty"`
+	// Screenshot is a base64 encoded JPEG, only sent when enabled in the config
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+type GameCreatedData struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type FinishedData struct {
+	Reason event.FinishReason `json:"reason"`
+}
+
+type RunData struct {
+	Run    string             `json:"run"`
+	Reason event.FinishReason `json:"reason,omitempty"`
+}
+
+type ItemStashedData struct {
+	Name       string     `json:"name"`
+	Quality    string     `json:"quality"`
+	Ethereal   bool       `json:"ethereal"`
+	Identified bool       `json:"identified"`
+	Stats      []StatData `json:"stats"`
+	Rule       string     `json:"rule"`
+	RuleFile   string     `json:"ruleFile"`
+}
+
+type StatData struct {
+	Name  string `json:"name"`
+	Layer int    `json:"layer,omitempty"`
+	Value int    `json:"value"`
+}
+
+type UsedPotionData struct {
+	PotionType data.PotionType `json:"potionType"`
+	OnMerc     bool            `json:"onMerc"`
+}
+
+type GamePausedData struct {
+	Paused bool `json:"paused"`
+}
+
+type InteractedToData struct {
+	ID              int                   `json:"id"`
+	InteractionType event.InteractionType `json:"interactionType"`
+}
+
+type CompanionAttackData struct {
+	TargetUnitID data.UnitID `json:"targetUnitId"`
+}
+
+type ConfigReloadedData struct {
+	Applied bool     `json:"applied"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// EventType returns the stable type name used in the payload and in the event filter
+func EventType(e event.Event) string {
+	switch e.(type) {
+	case event.GameCreatedEvent:
+		return TypeGameCreated
+	case event.GameFinishedEvent:
+		return TypeGameFinished
+	case event.RunStartedEvent:
+		return TypeRunStarted
+	case event.RunFinishedEvent:
+		return TypeRunFinished
+	case event.ItemStashedEvent:
+		return TypeItemStashed
+	case event.UsedPotionEvent:
+		return TypeUsedPotion
+	case event.GamePausedEvent:
+		return TypeGamePaused
+	case event.InteractedToEvent:
+		return TypeInteractedTo
+	case event.CompanionLeaderAttackEvent:
+		return TypeCompanionAttack
+	case event.CompanionRequestedTPEvent:
+		return TypeCompanionRequestedTP
+	case event.ConfigReloadedEvent:
+		return TypeConfigReloaded
+	default:
+		return TypeText
+	}
+}
+
+func NewPayload(id string, e event.Event, includeScreenshot bool) (Payload, error) {
+	p := Payload{
+		Version:    SchemaVersion,
+		ID:         id,
+		Type:       EventType(e),
+		Supervisor: e.Supervisor(),
+		OccurredAt: e.OccurredAt(),
+		Message:    e.Message(),
+	}
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		p.Data = GameCreatedData{Name: evt.Name, Password: evt.Password}
+	case event.GameFinishedEvent:
+		p.Data = FinishedData{Reason: evt.Reason}
+	case event.RunStartedEvent:
+		p.Data = RunData{Run: evt.RunName}
+	case event.RunFinishedEvent:
+		p.Data = RunData{Run: evt.RunName, Reason: evt.Reason}
+	case event.ItemStashedEvent:
+		p.Data = newItemStashedData(evt.Item)
+	case event.UsedPotionEvent:
+		p.Data = UsedPotionData{PotionType: evt.PotionType, OnMerc: evt.OnMerc}
+	case event.GamePausedEvent:
+		p.Data = GamePausedData{Paused: evt.Paused}
+	case event.InteractedToEvent:
+		p.Data = InteractedToData{ID: evt.ID, InteractionType: evt.InteractionType}
+	case event.CompanionLeaderAttackEvent:
+		p.Data = CompanionAttackData{TargetUnitID: evt.TargetUnitID}
+	case event.ConfigReloadedEvent:
+		p.Data = ConfigReloadedData{Applied: evt.Applied, Errors: evt.Errors}
+	}
+
+	if includeScreenshot && e.Image() != nil {
+		buf := new(bytes.Buffer)
+		if err := jpeg.Encode(buf, e.Image(), &jpeg.Options{Quality: 80}); err != nil {
+			return p, err
+		}
+		p.Screenshot = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+
+	return p, nil
+}
+
+func newItemStashedData(d data.Drop) ItemStashedData {
+	stats := make([]StatData, 0, len(d.Item.Stats))
+	for _, st := range d.Item.Stats {
+		stats = append(stats, StatData{Name: st.ID.String(), Layer: st.Layer, Value: st.Value})
+	}
+
+	return ItemStashedData{
+		Name:       string(d.Item.Name),
+		Quality:    d.Item.Quality.ToString(),
+		Ethereal:   d.Item.Ethereal,
+		Identified: d.Item.Identified,
+		Stats:      stats,
+		Rule:       d.Rule,
+		RuleFile:   d.RuleFile,
+	}
+}
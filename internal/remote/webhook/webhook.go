@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	SignatureHeader = "X-Koolo-Signature"
+	EventHeader     = "X-Koolo-Event"
+	DeliveryHeader  = "X-Koolo-Delivery"
+
+	queueSize        = 100
+	defaultRetries   = 3
+	deliveryTimeout  = 10 * time.Second
+	deliveryIDPrefix = "koolo-"
+)
+
+// Backoff between delivery attempts, it doubles with every retry
+var (
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+type Config struct {
+	URL    string
+	Secret string
+	Events []string
+	// MaxRetries of a failed delivery, nil retries defaultRetries times and 0 disables the retries
+	MaxRetries         *int
+	IncludeScreenshots bool
+}
+
+// Notifier POSTs every event matching the filter to the configured URL. Deliveries are queued and sent from Start,
+// so a slow endpoint doesn't block the event listener.
+type Notifier struct {
+	cfg     Config
+	client  *http.Client
+	logger  *slog.Logger
+	queue   chan Payload
+	counter atomic.Uint64
+}
+
+func NewNotifier(cfg Config, logger *slog.Logger) (*Notifier, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	if cfg.MaxRetries == nil {
+		retries := defaultRetries
+		cfg.MaxRetries = &retries
+	}
+	if *cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("webhook max retries can not be negative")
+	}
+
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: deliveryTimeout},
+		logger: logger,
+		queue:  make(chan Payload, queueSize),
+	}, nil
+}
+
+func (n *Notifier) Start(ctx context.Context) error {
+	for {
+		select {
+		case p := <-n.queue:
+			if err := n.deliver(ctx, p); err != nil {
+				n.logger.Error("error delivering webhook", slog.String("type", p.Type), slog.Any("error", err))
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (n *Notifier) Handle(_ context.Context, e event.Event) error {
+	if !n.shouldPublish(e) {
+		return nil
+	}
+
+	p, err := NewPayload(fmt.Sprintf("%s%d-%d", deliveryIDPrefix, time.Now().UnixNano(), n.counter.Add(1)), e, n.cfg.IncludeScreenshots)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case n.queue <- p:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue is full, dropping %s event", p.Type)
+	}
+}
+
+// shouldPublish matches the event type against the configured filter, an empty filter publishes everything
+func (n *Notifier) shouldPublish(e event.Event) bool {
+	if len(n.cfg.Events) == 0 {
+		return true
+	}
+
+	return slices.Contains(n.cfg.Events, EventType(e))
+}
+
+func (n *Notifier) deliver(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("error encoding payload: %w", err)
+	}
+
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, p, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= *n.cfg.MaxRetries {
+			return err
+		}
+
+		n.logger.Debug("Webhook delivery failed, retrying", slog.Int("attempt", attempt+1), slog.Duration("backoff", backoff), slog.Any("error", err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post sends the payload once, returning whether the error is worth retrying
+func (n *Notifier) post(ctx context.Context, p Payload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Koolo-Webhook")
+	req.Header.Set(EventHeader, p.Type)
+	req.Header.Set(DeliveryHeader, p.ID)
+	if n.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+}
+
+// Sign returns the HMAC-SHA256 signature of the body, formatted as "sha256=<hex>"
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseEventTypes parses a comma separated list of event types, used by the settings page
+func ParseEventTypes(events string) ([]string, error) {
+	types := make([]string, 0)
+	for _, t := range strings.Split(events, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !slices.Contains(AvailableEventTypes, t) {
+			return nil, fmt.Errorf("unknown webhook event type: %s", t)
+		}
+		types = append(types, t)
+	}
+
+	return types, nil
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func init() {
+	initialBackoff = time.Millisecond
+	maxBackoff = 4 * time.Millisecond
+}
+
+// testEndpoint answers with the given status codes in order, the last one is repeated
+func testEndpoint(t *testing.T, codes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	attempts := &atomic.Int32{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := int(attempts.Add(1))
+		w.WriteHeader(codes[min(attempt, len(codes))-1])
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, attempts
+}
+
+func newTestNotifier(t *testing.T, cfg Config) *Notifier {
+	t.Helper()
+
+	n, err := NewNotifier(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func retries(n int) *int {
+	return &n
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxRetries       *int
+		codes            []int
+		expectedAttempts int32
+		expectErr        bool
+	}{
+		{name: "delivered", codes: []int{http.StatusNoContent}, expectedAttempts: 1},
+		{name: "retried until delivered", codes: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, expectedAttempts: 3},
+		{name: "default retries", codes: []int{http.StatusInternalServerError}, expectedAttempts: defaultRetries + 1, expectErr: true},
+		{name: "configured retries", maxRetries: retries(1), codes: []int{http.StatusInternalServerError}, expectedAttempts: 2, expectErr: true},
+		{name: "retries disabled", maxRetries: retries(0), codes: []int{http.StatusInternalServerError}, expectedAttempts: 1, expectErr: true},
+		{name: "client errors are not retried", codes: []int{http.StatusBadRequest}, expectedAttempts: 1, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := testEndpoint(t, tt.codes...)
+			n := newTestNotifier(t, Config{URL: srv.URL, MaxRetries: tt.maxRetries})
+
+			err := n.deliver(context.Background(), Payload{ID: "koolo-1", Type: TypeText})
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if attempts.Load() != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts.Load())
+			}
+		})
+	}
+}
+
+func TestNegativeRetries(t *testing.T) {
+	if _, err := NewNotifier(Config{URL: "http://localhost", MaxRetries: retries(-1)}, slog.Default()); err == nil {
+		t.Errorf("Expected an error with negative retries")
+	}
+}
+
+func TestDeliverSignature(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, Config{URL: srv.URL, Secret: "s3cr3t"})
+	if err := n.deliver(context.Background(), Payload{ID: "koolo-1", Type: TypeText, Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	r, body := <-received, <-bodies
+
+	// Verified the way a receiver would do it
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := r.Header.Get(SignatureHeader); !hmac.Equal([]byte(signature), []byte(expected)) {
+		t.Errorf("Expected signature %s, got %s", expected, signature)
+	}
+	if r.Header.Get(EventHeader) != TypeText || r.Header.Get(DeliveryHeader) != "koolo-1" {
+		t.Errorf("Expected event and delivery headers, got %s and %s", r.Header.Get(EventHeader), r.Header.Get(DeliveryHeader))
+	}
+
+	p := Payload{}
+	if err := json.Unmarshal(body, &p); err != nil || p.Message != "hello" {
+		t.Errorf("Expected payload to be sent as JSON, got %s: %v", body, err)
+	}
+
+	if Sign("s3cr3t", body) == Sign("other secret", body) || Sign("s3cr3t", body) == Sign("s3cr3t", append(body, ' ')) {
+		t.Errorf("Expected signature to depend on the secret and the body")
+	}
+}
+
+func TestDeliverWithoutSecretIsNotSigned(t *testing.T) {
+	signatures := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures <- r.Header.Get(SignatureHeader)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := newTestNotifier(t, Config{URL: srv.URL})
+	if err := n.deliver(context.Background(), Payload{ID: "koolo-1", Type: TypeText}); err != nil {
+		t.Fatal(err)
+	}
+	if signature := <-signatures; signature != "" {
+		t.Errorf("Expected no signature without a secret, got %s", signature)
+	}
+}
+
+func TestQueueOverflow(t *testing.T) {
+	// Not started, so nothing is taken from the queue
+	n := newTestNotifier(t, Config{URL: "http://localhost"})
+	e := event.Text("sorc", "hello")
+
+	for i := 0; i < queueSize; i++ {
+		if err := n.Handle(context.Background(), e); err != nil {
+			t.Fatalf("Expected event %d to be queued, got %v", i+1, err)
+		}
+	}
+	if err := n.Handle(context.Background(), e); err == nil || !strings.Contains(err.Error(), "queue is full") {
+		t.Errorf("Expected queue full error, got %v", err)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	n := newTestNotifier(t, Config{URL: "http://localhost", Events: []string{TypeGameCreated}})
+
+	if err := n.Handle(context.Background(), event.Text("sorc", "hello")); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.queue) != 0 {
+		t.Errorf("Expected filtered event not to be queued, got %d queued", len(n.queue))
+	}
+}
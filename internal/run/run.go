@@ -2,6 +2,7 @@ package run
 
 import (
 	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/planner"
 )
 
 type Run interface {
@@ -9,84 +10,86 @@ type Run interface {
 	Run() error
 }
 
-func BuildRuns(cfg *config.CharacterCfg) (runs []Run) {
+// Planned is a run built from a planner entry, the entry is kept to report the result and apply the time budget
+type Planned struct {
+	Run   Run
+	Entry planner.Entry
+}
+
+// BuildRuns builds the runs selected by the planner, keeping the planned order
+func BuildRuns(plan planner.Plan) (runs []Planned) {
 	//if cfg.Companion.Enabled && !cfg.Companion.Leader {
 	//	return []Run{Companion{baseRun: baseRun}}
 	//}
 
-	for _, run := range cfg.Game.Runs {
-		// Prepend terror zone runs, we want to run it always first
-		if run == config.TerrorZoneRun {
-			tz := NewTerrorZone()
-
-			if len(tz.AvailableTZs()) > 0 {
-				runs = append(runs, tz)
-				// If we are skipping other runs, we can return here
-				if cfg.Game.TerrorZone.SkipOtherRuns {
-					return runs
-				}
-			}
+	for _, entry := range plan.Runs {
+		if r := buildRun(entry.Run); r != nil {
+			runs = append(runs, Planned{Run: r, Entry: entry})
 		}
 	}
 
-	for _, run := range cfg.Game.Runs {
-		switch run {
-		case config.CountessRun:
-			runs = append(runs, NewCountess())
-		case config.AndarielRun:
-			runs = append(runs, NewAndariel())
-		case config.SummonerRun:
-			runs = append(runs, NewSummoner())
-		case config.DurielRun:
-			runs = append(runs, NewDuriel())
-		case config.MephistoRun:
-			runs = append(runs, NewMephisto(nil))
-		case config.TravincalRun:
-			runs = append(runs, NewTravincal())
-		case config.DiabloRun:
-			runs = append(runs, NewDiablo())
-		case config.EldritchRun:
-			runs = append(runs, NewEldritch())
-		case config.PindleskinRun:
-			runs = append(runs, NewPindleskin())
-		case config.NihlathakRun:
-			runs = append(runs, NewNihlathak())
-		case config.AncientTunnelsRun:
-			runs = append(runs, NewAncientTunnels())
-		case config.MausoleumRun:
-			runs = append(runs, NewMausoleum())
-		case config.PitRun:
-			runs = append(runs, NewPit())
-		case config.StonyTombRun:
-			runs = append(runs, NewStonyTomb())
-		case config.ArachnidLairRun:
-			runs = append(runs, NewArachnidLair())
-		case config.TristramRun:
-			runs = append(runs, NewTristram())
-		case config.LowerKurastRun:
-			runs = append(runs, NewLowerKurast())
-		case config.LowerKurastChestRun:
-			runs = append(runs, NewLowerKurastChest())
-		case config.BaalRun:
-			runs = append(runs, NewBaal(nil))
-		case config.TalRashaTombsRun:
-			runs = append(runs, NewTalRashaTombs())
-		case config.LevelingRun:
-			runs = append(runs, NewLeveling())
-		case config.QuestsRun:
-			runs = append(runs, NewQuests())
-		case config.CowsRun:
-			runs = append(runs, NewCows())
-		case config.ThreshsocketRun:
-			runs = append(runs, NewThreshsocket())
-		case config.SpiderCavernRun:
-			runs = append(runs, NewSpiderCavern())
-		case config.DrifterCavernRun:
-			runs = append(runs, NewDriverCavern())
-		case config.EnduguRun:
-			runs = append(runs, NewEndugu())
-		}
+	return runs
+}
+
+func buildRun(r config.Run) Run {
+	switch r {
+	case config.CountessRun:
+		return NewCountess()
+	case config.AndarielRun:
+		return NewAndariel()
+	case config.SummonerRun:
+		return NewSummoner()
+	case config.DurielRun:
+		return NewDuriel()
+	case config.MephistoRun:
+		return NewMephisto(nil)
+	case config.TravincalRun:
+		return NewTravincal()
+	case config.DiabloRun:
+		return NewDiablo()
+	case config.EldritchRun:
+		return NewEldritch()
+	case config.PindleskinRun:
+		return NewPindleskin()
+	case config.NihlathakRun:
+		return NewNihlathak()
+	case config.AncientTunnelsRun:
+		return NewAncientTunnels()
+	case config.MausoleumRun:
+		return NewMausoleum()
+	case config.PitRun:
+		return NewPit()
+	case config.StonyTombRun:
+		return NewStonyTomb()
+	case config.ArachnidLairRun:
+		return NewArachnidLair()
+	case config.TristramRun:
+		return NewTristram()
+	case config.LowerKurastRun:
+		return NewLowerKurast()
+	case config.LowerKurastChestRun:
+		return NewLowerKurastChest()
+	case config.BaalRun:
+		return NewBaal(nil)
+	case config.TalRashaTombsRun:
+		return NewTalRashaTombs()
+	case config.LevelingRun:
+		return NewLeveling()
+	case config.QuestsRun:
+		return NewQuests()
+	case config.CowsRun:
+		return NewCows()
+	case config.ThreshsocketRun:
+		return NewThreshsocket()
+	case config.SpiderCavernRun:
+		return NewSpiderCavern()
+	case config.DrifterCavernRun:
+		return NewDriverCavern()
+	case config.EnduguRun:
+		return NewEndugu()
+	case config.TerrorZoneRun:
+		return NewTerrorZone()
 	}
 
-	return runs
+	return nil
 }
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+const apiPrefix = "/api/v1"
+
+// apiStartTimeout is how long a start request waits for the supervisor to be Starting
+var apiStartTimeout = 30 * time.Second
+
+var (
+	errSupervisorNotFound   = errors.New("supervisor not found")
+	errSupervisorRunning    = errors.New("supervisor is already running")
+	errSupervisorNotRunning = errors.New("supervisor is not running")
+	errTokenAuthStarting    = errors.New("another client is starting and token auth is in use, try again later")
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiSupervisor struct {
+	Name      string               `json:"name"`
+	Character string               `json:"character"`
+	Status    bot.SupervisorStatus `json:"status"`
+	Details   string               `json:"details"`
+	StartedAt *time.Time           `json:"startedAt,omitempty"`
+	Games     int                  `json:"games"`
+	Deaths    int                  `json:"deaths"`
+	Chickens  int                  `json:"chickens"`
+	Errors    int                  `json:"errors"`
+	Drops     int                  `json:"drops"`
+}
+
+// apiSession are the counters of a past or current session of a supervisor, from the stats journal
+type apiSession struct {
+	StartedAt time.Time `json:"startedAt"`
+	Games     int       `json:"games"`
+	Deaths    int       `json:"deaths"`
+	Chickens  int       `json:"chickens"`
+	Errors    int       `json:"errors"`
+	Drops     int       `json:"drops"`
+}
+
+type apiRunQueue struct {
+	Runs       []config.Run `json:"runs"`
+	CurrentRun string       `json:"currentRun,omitempty"`
+	Completed  []string     `json:"completed"`
+}
+
+func (s *HttpServer) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors", s.apiListSupervisors)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}", s.apiGetSupervisor)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/status", s.apiGetSupervisor)
+	mux.HandleFunc("POST "+apiPrefix+"/supervisors/{name}/start", s.apiStartSupervisor)
+	mux.HandleFunc("POST "+apiPrefix+"/supervisors/{name}/stop", s.apiStopSupervisor)
+	mux.HandleFunc("POST "+apiPrefix+"/supervisors/{name}/pause", s.apiTogglePause)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/stats", s.apiGetStats)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/drops", s.apiGetDrops)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/sessions", s.apiGetSessions)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/config", s.apiGetConfig)
+	mux.HandleFunc("GET "+apiPrefix+"/supervisors/{name}/runs", s.apiGetRuns)
+	mux.HandleFunc("PUT "+apiPrefix+"/supervisors/{name}/runs", s.apiSetRuns)
+	mux.HandleFunc(apiPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, errors.New("resource not found"))
+	})
+}
+
+func (s *HttpServer) apiListSupervisors(w http.ResponseWriter, r *http.Request) {
+	names := s.manager.AvailableSupervisors()
+	sort.Strings(names)
+
+	supervisors := make([]apiSupervisor, 0, len(names))
+	for _, name := range names {
+		supervisors = append(supervisors, s.apiSupervisor(name))
+	}
+
+	writeJSON(w, http.StatusOK, supervisors)
+}
+
+func (s *HttpServer) apiGetSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.apiSupervisor(name))
+}
+
+func (s *HttpServer) apiStartSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if s.isRunning(name) {
+		writeAPIError(w, http.StatusConflict, errSupervisorRunning)
+		return
+	}
+
+	if err := s.checkTokenAuthConflict(name); err != nil {
+		writeAPIError(w, http.StatusConflict, err)
+		return
+	}
+
+	if cfg, found := config.Characters()[name]; found {
+		if err := cfg.Runtime.Validation.Err(); err != nil {
+			writeAPIError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	// Supervisor keeps running the game loop until it's stopped, so we only wait until it's Starting to return the
+	// errors found before that, like a config that became invalid since it was loaded
+	errCh := make(chan error, 1)
+	go func() {
+		err := s.manager.Start(name, false)
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("error starting supervisor %s: %s", name, err.Error()))
+		}
+		errCh <- err
+	}()
+
+	if err := s.waitUntilStarting(name, errCh); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, config.ErrInvalidConfig) {
+			status = http.StatusUnprocessableEntity
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, s.apiSupervisor(name))
+}
+
+func (s *HttpServer) apiStopSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.isRunning(name) {
+		writeAPIError(w, http.StatusConflict, errSupervisorNotRunning)
+		return
+	}
+
+	s.manager.Stop(name)
+	writeJSON(w, http.StatusOK, s.apiSupervisor(name))
+}
+
+func (s *HttpServer) apiTogglePause(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.isRunning(name) {
+		writeAPIError(w, http.StatusConflict, errSupervisorNotRunning)
+		return
+	}
+
+	s.manager.TogglePause(name)
+	writeJSON(w, http.StatusOK, s.apiSupervisor(name))
+}
+
+func (s *HttpServer) apiGetStats(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	_, stats, _ := s.statsForScope(name, r.URL.Query().Get("scope"))
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *HttpServer) apiGetDrops(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	_, stats, _ := s.statsForScope(name, r.URL.Query().Get("scope"))
+	drops := stats.Drops
+	if drops == nil {
+		drops = make([]data.Drop, 0)
+	}
+
+	writeJSON(w, http.StatusOK, drops)
+}
+
+func (s *HttpServer) apiGetSessions(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	history, err := s.manager.History(name)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sessions := make([]apiSession, 0, len(history.Sessions))
+	for _, st := range history.Sessions {
+		sessions = append(sessions, apiSession{
+			StartedAt: st.StartedAt,
+			Games:     st.TotalGames(),
+			Deaths:    st.TotalDeaths(),
+			Chickens:  st.TotalChickens(),
+			Errors:    st.TotalErrors(),
+			Drops:     len(st.Drops),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *HttpServer) apiGetConfig(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, config.Characters()[name].Redacted())
+}
+
+func (s *HttpServer) apiGetRuns(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.apiRunQueue(name))
+}
+
+func (s *HttpServer) apiSetRuns(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	runs := make([]config.Run, 0)
+	if err := json.NewDecoder(r.Body).Decode(&runs); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid run list: %w", err))
+		return
+	}
+
+	for _, run := range runs {
+		if _, found := config.AvailableRuns[run]; !found {
+			writeAPIError(w, http.StatusUnprocessableEntity, fmt.Errorf("unknown run: %s", run))
+			return
+		}
+	}
+
+	cfg := *config.Characters()[name]
+	cfg.Game.Runs = runs
+	if err := config.SaveSupervisorConfig(name, &cfg); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.apiRunQueue(name))
+}
+
+// apiSupervisorName returns the supervisor name from the path, writing a 404 response if it doesn't exist
+func (s *HttpServer) apiSupervisorName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if !slices.Contains(s.manager.AvailableSupervisors(), name) {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("%w: %s", errSupervisorNotFound, name))
+		return "", false
+	}
+
+	return name, true
+}
+
+func (s *HttpServer) apiSupervisor(name string) apiSupervisor {
+	stats := s.manager.GetSupervisorStats(name)
+	sup := apiSupervisor{
+		Name:     name,
+		Status:   stats.SupervisorStatus,
+		Details:  stats.Details,
+		Games:    stats.TotalGames(),
+		Deaths:   stats.TotalDeaths(),
+		Chickens: stats.TotalChickens(),
+		Errors:   stats.TotalErrors(),
+		Drops:    len(stats.Drops),
+	}
+
+	if cfg, found := config.Characters()[name]; found {
+		sup.Character = cfg.CharacterName
+	}
+	if sup.Status == "" {
+		sup.Status = bot.NotStarted
+	}
+	if !stats.StartedAt.IsZero() {
+		sup.StartedAt = &stats.StartedAt
+	}
+
+	return sup
+}
+
+func (s *HttpServer) apiRunQueue(name string) apiRunQueue {
+	queue := apiRunQueue{Runs: config.Characters()[name].Game.Runs, Completed: make([]string, 0)}
+	if queue.Runs == nil {
+		queue.Runs = make([]config.Run, 0)
+	}
+
+	stats := s.manager.GetSupervisorStats(name)
+	if len(stats.Games) > 0 {
+		for _, run := range stats.Games[len(stats.Games)-1].Runs {
+			if run.FinishedAt.IsZero() {
+				queue.CurrentRun = run.Name
+			} else {
+				queue.Completed = append(queue.Completed, run.Name)
+			}
+		}
+	}
+
+	return queue
+}
+
+func (s *HttpServer) isRunning(name string) bool {
+	status := s.manager.GetSupervisorStats(name).SupervisorStatus
+	return status != "" && status != bot.NotStarted
+}
+
+// waitUntilStarting waits until the supervisor is Starting or Start returns, whatever comes first. It gives up after
+// apiStartTimeout, the client can keep polling the status then.
+func (s *HttpServer) waitUntilStarting(name string, errCh <-chan error) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(apiStartTimeout)
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case <-ticker.C:
+			if s.isRunning(name) {
+				return nil
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+// checkTokenAuthConflict prevents launching a client while there's a client using TokenAuth still starting
+func (s *HttpServer) checkTokenAuthConflict(name string) error {
+	supCfg, found := config.Characters()[name]
+	if !found {
+		return fmt.Errorf("%w: %s", errSupervisorNotFound, name)
+	}
+
+	for _, sup := range s.manager.AvailableSupervisors() {
+		// If the current don't check against the one we're trying to launch
+		if sup == name {
+			continue
+		}
+
+		if s.manager.GetSupervisorStats(sup).SupervisorStatus != bot.Starting {
+			continue
+		}
+
+		// Prevent launching if we're using token auth & another client is starting (no matter what auth method)
+		if supCfg.AuthMethod == "TokenAuth" {
+			return errTokenAuthStarting
+		}
+
+		// Prevent launching if another client that is using token auth is starting
+		if sCfg, found := config.Characters()[sup]; found && sCfg.AuthMethod == "TokenAuth" {
+			return errTokenAuthStarting
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
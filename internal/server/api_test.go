@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
+	ctx "github.com/hectorgimenez/koolo/internal/context"
+)
+
+// fakeManager registers the supervisors as Starting when started, unless startErr is set
+type fakeManager struct {
+	mu       sync.Mutex
+	names    []string
+	status   map[string]bot.SupervisorStatus
+	startErr error
+	context  *ctx.Context
+}
+
+func (m *fakeManager) AvailableSupervisors() []string { return m.names }
+
+func (m *fakeManager) Start(supervisorName string, attachToExisting bool, pidHwnd ...uint32) error {
+	if m.startErr != nil {
+		return m.startErr
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[supervisorName] = bot.Starting
+
+	return nil
+}
+
+func (m *fakeManager) Stop(supervisor string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.status, supervisor)
+}
+
+func (m *fakeManager) TogglePause(supervisor string) {}
+
+func (m *fakeManager) Status(characterName string) bot.Stats {
+	return m.GetSupervisorStats(characterName)
+}
+
+func (m *fakeManager) GetSupervisorStats(supervisor string) bot.Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return bot.Stats{SupervisorStatus: m.status[supervisor]}
+}
+
+func (m *fakeManager) GetContext(characterName string) *ctx.Context { return m.context }
+
+func (m *fakeManager) History(characterName string) (bot.StatsHistory, error) {
+	return bot.StatsHistory{}, nil
+}
+
+func newTestAPI(t *testing.T, manager *fakeManager) *httptest.Server {
+	t.Helper()
+
+	previous := config.Characters()
+	characters := map[string]*config.CharacterCfg{}
+	for _, name := range manager.names {
+		characters[name] = &config.CharacterCfg{CharacterName: name}
+	}
+	config.SetCharacters(characters)
+	t.Cleanup(func() { config.SetCharacters(previous) })
+
+	s := &HttpServer{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), manager: manager}
+	mux := http.NewServeMux()
+	s.registerAPI(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestAPIStartSupervisor(t *testing.T) {
+	tests := []struct {
+		name           string
+		supervisor     string
+		status         bot.SupervisorStatus
+		startErr       error
+		validationErr  bool
+		expectedStatus int
+		expectedError  string
+	}{
+		{name: "unknown supervisor", supervisor: "paladin", expectedStatus: http.StatusNotFound, expectedError: "supervisor not found"},
+		{name: "already running", supervisor: "sorc", status: bot.InGame, expectedStatus: http.StatusConflict, expectedError: "already running"},
+		{name: "invalid config", supervisor: "sorc", validationErr: true, expectedStatus: http.StatusUnprocessableEntity, expectedError: "invalid config"},
+		{name: "config invalid when starting", supervisor: "sorc", startErr: config.Validation{Errors: []config.ValidationIssue{{Field: "game.runs", Message: "unknown run"}}}.Err(), expectedStatus: http.StatusUnprocessableEntity, expectedError: "game.runs"},
+		{name: "error starting", supervisor: "sorc", startErr: errors.New("error loading config"), expectedStatus: http.StatusInternalServerError, expectedError: "error loading config"},
+		{name: "started", supervisor: "sorc", expectedStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeManager{names: []string{"sorc"}, status: map[string]bot.SupervisorStatus{}, startErr: tt.startErr}
+			if tt.status != "" {
+				manager.status["sorc"] = tt.status
+			}
+			srv := newTestAPI(t, manager)
+			if tt.validationErr {
+				config.Characters()["sorc"].Runtime.Validation.Errors = []config.ValidationIssue{{Field: "game.runs", Message: "unknown run"}}
+			}
+
+			resp, err := http.Post(srv.URL+apiPrefix+"/supervisors/"+tt.supervisor+"/start", "application/json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if tt.expectedError != "" {
+				body := apiError{}
+				if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(body.Error, tt.expectedError) {
+					t.Errorf("Expected error containing %q, got %q", tt.expectedError, body.Error)
+				}
+				return
+			}
+
+			body := apiSupervisor{}
+			if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Status != bot.Starting {
+				t.Errorf("Expected supervisor to be %s, got %s", bot.Starting, body.Status)
+			}
+		})
+	}
+}
+
+func TestAPIStopSupervisor(t *testing.T) {
+	manager := &fakeManager{names: []string{"sorc"}, status: map[string]bot.SupervisorStatus{"sorc": bot.InGame}}
+	srv := newTestAPI(t, manager)
+
+	for _, expected := range []int{http.StatusOK, http.StatusConflict} {
+		resp, err := http.Post(srv.URL+apiPrefix+"/supervisors/sorc/stop", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Expected status %d, got %d", expected, resp.StatusCode)
+		}
+	}
+}
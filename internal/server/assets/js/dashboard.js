@@ -89,6 +89,9 @@ let socket;
                     <button class="btn btn-outline" onclick="location.href='/debug?characterName=${key}'">
                         <i class="bi bi-bug btn-icon"></i>Debug
                     </button>
+                    <button class="btn btn-outline" onclick="location.href='/analytics?supervisor=${key}'">
+                        <i class="bi bi-bar-chart btn-icon"></i>Analytics
+                    </button>
                     <button class="btn btn-outline" onclick="location.href='/supervisorSettings?supervisor=${key}'">
                         <i class="bi bi-gear btn-icon"></i>Settings
                     </button>
@@ -172,6 +175,11 @@ let socket;
                 fetch(`/${action}?characterName=${key}`)
                     .then(response => response.json())
                     .then(data => {
+                        if (data.error) {
+                            alert(data.error);
+                            fetchInitialData();
+                            return;
+                        }
                         updateDashboard(data);
                     })
                     .catch(error => console.error('Error:', error));
@@ -7,6 +7,9 @@ const searchInput = document.getElementById('search-input');
 const searchPrevBtn = document.getElementById('search-prev-btn');
 const searchNextBtn = document.getElementById('search-next-btn');
 const searchResults = document.getElementById('search-results');
+const mapContainer = document.getElementById('map-container');
+const mapImage = document.getElementById('map-image');
+const toggleMapBtn = document.getElementById('toggle-map-btn');
 
 let refreshInterval = 1000; // Default to 1 second
 let refreshIntervalId;
@@ -17,6 +20,7 @@ let currentSearchMatch = -1;
 let lastSearchTerm = '';
 let expandedState = {};
 let currentSearchMatchPath = null;
+let isMapVisible = false;
 
 function createTreeView(data, path = '') {
     const fragment = document.createDocumentFragment();
@@ -148,6 +152,9 @@ function updateExpandAllButton() {
 function fetchDebugData() {
     const urlParams = new URLSearchParams(window.location.search);
     const characterName = urlParams.get('characterName') || 'nullref';
+    if (isMapVisible) {
+        refreshMap(characterName);
+    }
     fetch(`/debug-data?characterName=${characterName}`)
         .then(response => response.json())
         .then(data => {
@@ -316,6 +323,32 @@ function createCopyDataButton() {
     });
 }
 
+function createDownloadFixtureButton() {
+    const downloadFixtureBtn = document.getElementById('download-fixture-btn');
+    downloadFixtureBtn.addEventListener('click', () => {
+        const urlParams = new URLSearchParams(window.location.search);
+        const characterName = urlParams.get('characterName') || 'nullref';
+        window.location.href = `/debug-fixture?characterName=${encodeURIComponent(characterName)}`;
+    });
+}
+
+function refreshMap(characterName) {
+    // The timestamp avoids the browser caching the previous image
+    mapImage.src = `/debug-map?characterName=${encodeURIComponent(characterName)}&t=${Date.now()}`;
+}
+
+function createToggleMapButton() {
+    toggleMapBtn.addEventListener('click', () => {
+        isMapVisible = !isMapVisible;
+        mapContainer.style.display = isMapVisible ? 'block' : 'none';
+        toggleMapBtn.textContent = isMapVisible ? 'Hide Map' : 'Show Map';
+        if (isMapVisible) {
+            const urlParams = new URLSearchParams(window.location.search);
+            refreshMap(urlParams.get('characterName') || 'nullref');
+        }
+    });
+}
+
 // Event Listeners
 setIntervalBtn.addEventListener('click', setRefreshInterval);
 expandAllBtn.addEventListener('click', toggleExpandAll);
@@ -325,5 +358,7 @@ searchPrevBtn.addEventListener('click', goToPreviousSearchResult);
 
 // Initialize
 createCopyDataButton();
+createDownloadFixtureButton();
+createToggleMapButton();
 fetchDebugData();
 refreshIntervalId = setInterval(fetchDebugData, refreshInterval);
\ No newline at end of file
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+const (
+	sessionCookieName = "koolo_session"
+	sessionTTL        = 7 * 24 * time.Hour
+	loginKeyTTL       = time.Minute
+	defaultBindAddr   = "127.0.0.1"
+
+	// Failed logins from the same IP are free up to loginFreeAttempts, then the client has to wait a delay that
+	// doubles with every failure up to loginMaxBackoff. Failures are forgotten after loginFailureTTL.
+	loginFreeAttempts = 3
+	loginMaxBackoff   = 5 * time.Minute
+	loginFailureTTL   = time.Hour
+)
+
+// sessionStore keeps the dashboard sessions in memory, restarting Koolo logs out every client
+type sessionStore struct {
+	mu            sync.Mutex
+	sessions      map[string]time.Time
+	loginKeys     map[string]time.Time
+	loginFailures map[string]loginFailures
+}
+
+type loginFailures struct {
+	count        int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions:      make(map[string]time.Time),
+		loginKeys:     make(map[string]time.Time),
+		loginFailures: make(map[string]loginFailures),
+	}
+}
+
+func (ss *sessionStore) create() (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.sessions[id] = time.Now().Add(sessionTTL)
+
+	return id, nil
+}
+
+func (ss *sessionStore) valid(id string) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	expiresAt, found := ss.sessions[id]
+	if !found {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(ss.sessions, id)
+		return false
+	}
+
+	return true
+}
+
+func (ss *sessionStore) delete(id string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, id)
+}
+
+// createLoginKey returns a short-lived single use key that can be exchanged for a session
+func (ss *sessionStore) createLoginKey() (string, error) {
+	key, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.loginKeys[key] = time.Now().Add(loginKeyTTL)
+
+	return key, nil
+}
+
+func (ss *sessionStore) useLoginKey(key string) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	expiresAt, found := ss.loginKeys[key]
+	if !found {
+		return false
+	}
+	delete(ss.loginKeys, key)
+
+	return time.Now().Before(expiresAt)
+}
+
+// loginBlockedFor returns how long the given IP has to wait before trying to log in again
+func (ss *sessionStore) loginBlockedFor(ip string, now time.Time) time.Duration {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	f, found := ss.loginFailures[ip]
+	if !found {
+		return 0
+	}
+	if now.Sub(f.lastFailure) > loginFailureTTL {
+		delete(ss.loginFailures, ip)
+		return 0
+	}
+
+	return max(f.blockedUntil.Sub(now), 0)
+}
+
+func (ss *sessionStore) loginFailed(ip string, now time.Time) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	f := ss.loginFailures[ip]
+	if now.Sub(f.lastFailure) > loginFailureTTL {
+		f = loginFailures{}
+	}
+	f.count++
+	f.lastFailure = now
+	if f.count > loginFreeAttempts {
+		backoff := loginMaxBackoff
+		if shift := f.count - loginFreeAttempts - 1; shift < 16 {
+			backoff = min(time.Second<<shift, loginMaxBackoff)
+		}
+		f.blockedUntil = now.Add(backoff)
+	}
+	ss.loginFailures[ip] = f
+}
+
+func (ss *sessionStore) loginSucceeded(ip string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.loginFailures, ip)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func authEnabled() bool {
+	return config.Koolo.Server.Password != "" || config.Koolo.Server.Token != ""
+}
+
+func secretMatches(provided, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
+
+func bindAddress() string {
+	if config.Koolo.Server.BindAddress == "" || serverSecretsUnavailable() {
+		return defaultBindAddr
+	}
+
+	return config.Koolo.Server.BindAddress
+}
+
+// serverSecretsUnavailable is true when the dashboard password or token couldn't be read from the secrets vault, the
+// dashboard is only served on localhost then
+func serverSecretsUnavailable() bool {
+	for _, field := range []string{"server.password", "server.token"} {
+		if _, found := config.Koolo.Runtime.UnavailableSecrets[field]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLoopback(addr string) bool {
+	if addr == "" || addr == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsLoopback()
+}
+
+// DashboardURL returns the URL used by the local webview, already authenticated when auth is enabled
+func (s *HttpServer) DashboardURL(port int) string {
+	host := bindAddress()
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	dashboardURL := "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+
+	if !authEnabled() {
+		return dashboardURL
+	}
+
+	key, err := s.sessions.createLoginKey()
+	if err != nil {
+		s.logger.Error("error creating login key for the dashboard", "error", err)
+		return dashboardURL
+	}
+
+	return dashboardURL + "/login?key=" + key
+}
+
+// requireAuth protects every route except the login page and static assets when a password or token is configured
+func (s *HttpServer) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authEnabled() || r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/assets/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.isAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, apiPrefix) || r.URL.Path == "/ws" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			writeAPIError(w, http.StatusUnauthorized, errors.New("authentication required"))
+			return
+		}
+
+		http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+	})
+}
+
+func (s *HttpServer) isAuthenticated(r *http.Request) bool {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && secretMatches(token, config.Koolo.Server.Token) {
+		return true
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return false
+	}
+
+	return s.sessions.valid(cookie.Value)
+}
+
+// localRedirect returns next when it's a path in this server, "/" otherwise. Browsers treat backslashes as slashes,
+// so /\evil.com would be a redirect to another host.
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return "/"
+	}
+
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+
+	return next
+}
+
+// clientIP is the IP of the client, the X-Forwarded-For header is ignored since it can be set by anyone
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (s *HttpServer) login(w http.ResponseWriter, r *http.Request) {
+	next := localRedirect(r.URL.Query().Get("next"))
+
+	if !authEnabled() {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	authenticated := false
+	if key := r.URL.Query().Get("key"); key != "" {
+		authenticated = s.sessions.useLoginKey(key)
+	}
+
+	if r.Method == http.MethodPost {
+		ip := clientIP(r)
+		if wait := s.sessions.loginBlockedFor(ip, time.Now()); wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			w.WriteHeader(http.StatusTooManyRequests)
+			s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{ErrorMessage: fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds), Next: next})
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{ErrorMessage: "Error parsing form", Next: next})
+			return
+		}
+
+		password := r.Form.Get("password")
+		authenticated = secretMatches(password, config.Koolo.Server.Password) || secretMatches(password, config.Koolo.Server.Token)
+		if !authenticated {
+			s.sessions.loginFailed(ip, time.Now())
+			s.logger.Warn("Failed login attempt", "ip", ip)
+			w.WriteHeader(http.StatusUnauthorized)
+			s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{ErrorMessage: "Invalid password", Next: next})
+			return
+		}
+		s.sessions.loginSucceeded(ip)
+	}
+
+	if !authenticated {
+		s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{Next: next})
+		return
+	}
+
+	sessionID, err := s.sessions.create()
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		Expires:  time.Now().Add(sessionTTL),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *HttpServer) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.sessions.delete(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// checkWebSocketOrigin rejects cross-site websocket connections when auth is enabled, since the session cookie
+// would be sent along with them
+func checkWebSocketOrigin(r *http.Request) bool {
+	if !authEnabled() {
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+func TestLocalRedirect(t *testing.T) {
+	tests := map[string]string{
+		"":                         "/",
+		"/":                        "/",
+		"/supervisors?name=sorc":   "/supervisors?name=sorc",
+		"/debug#top":               "/debug#top",
+		"//evil.com":               "/",
+		"/\\evil.com":              "/",
+		"/\\/evil.com":             "/",
+		"https://evil.com":         "/",
+		"evil.com":                 "/",
+		"javascript:alert(1)":      "/",
+		"/%5Cevil.com/still/local": "/%5Cevil.com/still/local",
+	}
+
+	for next, expected := range tests {
+		if got := localRedirect(next); got != expected {
+			t.Errorf("Expected %q to redirect to %q, got %q", next, expected, got)
+		}
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	ss := newSessionStore()
+	now := time.Now()
+
+	for i := 0; i < loginFreeAttempts; i++ {
+		ss.loginFailed("10.0.0.2", now)
+		if wait := ss.loginBlockedFor("10.0.0.2", now); wait != 0 {
+			t.Fatalf("Expected failure %d to be free, got a wait of %s", i+1, wait)
+		}
+	}
+
+	// Every failure after the free ones doubles the wait
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		ss.loginFailed("10.0.0.2", now)
+		if wait := ss.loginBlockedFor("10.0.0.2", now); wait != expected {
+			t.Errorf("Expected a wait of %s, got %s", expected, wait)
+		}
+	}
+	if wait := ss.loginBlockedFor("10.0.0.3", now); wait != 0 {
+		t.Errorf("Expected other IPs not to wait, got %s", wait)
+	}
+
+	for i := 0; i < 100; i++ {
+		ss.loginFailed("10.0.0.2", now)
+	}
+	if wait := ss.loginBlockedFor("10.0.0.2", now); wait != loginMaxBackoff {
+		t.Errorf("Expected the wait to be capped at %s, got %s", loginMaxBackoff, wait)
+	}
+
+	if wait := ss.loginBlockedFor("10.0.0.2", now.Add(loginFailureTTL+time.Second)); wait != 0 {
+		t.Errorf("Expected failures to be forgotten after %s, got a wait of %s", loginFailureTTL, wait)
+	}
+
+	ss.loginFailed("10.0.0.4", now)
+	ss.loginSucceeded("10.0.0.4")
+	if _, found := ss.loginFailures["10.0.0.4"]; found {
+		t.Errorf("Expected a successful login to reset the failures")
+	}
+}
+
+func TestBindAddressWithUnavailableSecrets(t *testing.T) {
+	previous := config.Koolo
+	t.Cleanup(func() { config.Koolo = previous })
+
+	config.Koolo = &config.KooloCfg{}
+	config.Koolo.Server.BindAddress = "0.0.0.0"
+	if addr := bindAddress(); addr != "0.0.0.0" {
+		t.Errorf("Expected the configured bind address, got %s", addr)
+	}
+
+	config.Koolo.Runtime.UnavailableSecrets = map[string]string{"server.password": "wrong master key"}
+	if addr := bindAddress(); addr != defaultBindAddr {
+		t.Errorf("Expected the dashboard to be kept on %s without its password, got %s", defaultBindAddr, addr)
+	}
+}
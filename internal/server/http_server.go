@@ -10,7 +10,9 @@ import (
 	"html/template"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"slices"
 	"sort"
 	"strconv"
@@ -24,22 +26,40 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/analytics"
 	"github.com/hectorgimenez/koolo/internal/bot"
 	"github.com/hectorgimenez/koolo/internal/config"
 	ctx "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/game/fixture"
+	"github.com/hectorgimenez/koolo/internal/remote/drops"
+	"github.com/hectorgimenez/koolo/internal/remote/webhook"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/lxn/win"
 	"golang.org/x/sys/windows"
+	"gopkg.in/yaml.v3"
 )
 
+// supervisorManager is the part of bot.SupervisorManager used by the server
+type supervisorManager interface {
+	AvailableSupervisors() []string
+	Start(supervisorName string, attachToExisting bool, pidHwnd ...uint32) error
+	Stop(supervisor string)
+	TogglePause(supervisor string)
+	Status(characterName string) bot.Stats
+	GetSupervisorStats(supervisor string) bot.Stats
+	GetContext(characterName string) *ctx.Context
+	History(characterName string) (bot.StatsHistory, error)
+}
+
 type HttpServer struct {
 	logger    *slog.Logger
 	server    *http.Server
-	manager   *bot.SupervisorManager
+	manager   supervisorManager
 	templates *template.Template
 	wsServer  *WebSocketServer
+	sessions  *sessionStore
 }
 
 var (
@@ -49,9 +69,7 @@ var (
 	templatesFS embed.FS
 
 	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		CheckOrigin: checkWebSocketOrigin,
 	}
 )
 
@@ -197,7 +215,11 @@ func New(logger *slog.Logger, manager *bot.SupervisorManager) (*HttpServer, erro
 		},
 		"qualityClass": qualityClass,
 		"statIDToText": statIDToText,
+		"percent":      percent,
+		"duration":     formatDuration,
 		"contains":     containss,
+		"join":         strings.Join,
+		"joinIDs":      joinIDs,
 		"seq": func(start, end int) []int {
 			var result []int
 			for i := start; i <= end; i++ {
@@ -215,6 +237,7 @@ func New(logger *slog.Logger, manager *bot.SupervisorManager) (*HttpServer, erro
 		logger:    logger,
 		manager:   manager,
 		templates: templates,
+		sessions:  newSessionStore(),
 	}, nil
 }
 
@@ -366,6 +389,23 @@ func statIDToText(id stat.ID) string {
 	return stat.StringStats[id]
 }
 
+func percent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v*100)
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+func joinIDs(ids []int64) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatInt(id, 10))
+	}
+
+	return strings.Join(values, ",")
+}
+
 func containss(slice []string, item string) bool {
 	for _, v := range slice {
 		if v == item {
@@ -407,24 +447,36 @@ func (s *HttpServer) Listen(port int) error {
 	go s.BroadcastStatus()
 
 	http.HandleFunc("/", s.getRoot)
+	http.HandleFunc("/login", s.login)
+	http.HandleFunc("/logout", s.logout)
 	http.HandleFunc("/config", s.config)
 	http.HandleFunc("/supervisorSettings", s.characterSettings)
+	http.HandleFunc("/effective-config", s.effectiveConfig)
 	http.HandleFunc("/start", s.startSupervisor)
 	http.HandleFunc("/stop", s.stopSupervisor)
 	http.HandleFunc("/togglePause", s.togglePause)
 	http.HandleFunc("/debug", s.debugHandler)
 	http.HandleFunc("/debug-data", s.debugData)
+	http.HandleFunc("/debug-fixture", s.debugFixture)
+	http.HandleFunc("/debug-map", s.debugMap)
 	http.HandleFunc("/drops", s.drops)
+	http.HandleFunc("/analytics", s.analytics)
 	http.HandleFunc("/process-list", s.getProcessList)
 	http.HandleFunc("/attach-process", s.attachProcess)
 	http.HandleFunc("/ws", s.wsServer.HandleWebSocket) // Web socket
 	http.HandleFunc("/initial-data", s.initialData)    // Web socket data
+	s.registerAPI(http.DefaultServeMux)
 
 	assets, _ := fs.Sub(assetsFS, "assets")
 	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(assets))))
 
+	if !isLoopback(bindAddress()) && !authEnabled() {
+		s.logger.Warn("Dashboard is exposed outside localhost without password or token, anyone in the network can control Koolo", slog.String("bindAddress", bindAddress()))
+	}
+
 	s.server = &http.Server{
-		Addr: fmt.Sprintf(":%d", port),
+		Addr:    net.JoinHostPort(bindAddress(), strconv.Itoa(port)),
+		Handler: s.requireAuth(http.DefaultServeMux),
 	}
 
 	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -469,9 +521,13 @@ func (s *HttpServer) debugData(w http.ResponseWriter, r *http.Request) {
 
 	context := s.manager.GetContext(characterName)
 
+	// The character config holds the decrypted passwords and tokens
+	gameData := *context.Data
+	gameData.CharacterCfg = *gameData.CharacterCfg.Redacted()
+
 	debugData := DebugData{
 		DebugData: context.ContextDebug,
-		GameData:  context.Data,
+		GameData:  &gameData,
 	}
 
 	jsonData, err := json.Marshal(debugData)
@@ -483,47 +539,84 @@ func (s *HttpServer) debugData(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonData)
 }
 
-func (s *HttpServer) debugHandler(w http.ResponseWriter, r *http.Request) {
-	s.templates.ExecuteTemplate(w, "debug.gohtml", nil)
+// debugFixture downloads a snapshot of the current area, to be used as a test fixture
+func (s *HttpServer) debugFixture(w http.ResponseWriter, r *http.Request) {
+	characterName := r.URL.Query().Get("characterName")
+	if characterName == "" {
+		http.Error(w, "Character name is required", http.StatusBadRequest)
+		return
+	}
+
+	context := s.manager.GetContext(characterName)
+	if context == nil || context.Data == nil {
+		http.Error(w, "Supervisor is not running", http.StatusNotFound)
+		return
+	}
+
+	f, err := fixture.FromData(*context.Data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to take the snapshot: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = fixture.Write(&buf, f); err != nil {
+		http.Error(w, "Failed to serialize the snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s.json.gz", characterName, strings.ReplaceAll(strings.ToLower(f.Name), " ", "_"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Write(buf.Bytes())
 }
 
-func (s *HttpServer) startSupervisor(w http.ResponseWriter, r *http.Request) {
-	supervisorList := s.manager.AvailableSupervisors()
-	Supervisor := r.URL.Query().Get("characterName")
+// debugMap serves the latest map rendered by the supervisor pather
+func (s *HttpServer) debugMap(w http.ResponseWriter, r *http.Request) {
+	characterName := r.URL.Query().Get("characterName")
+	if characterName == "" {
+		http.Error(w, "Character name is required", http.StatusBadRequest)
+		return
+	}
 
-	// Get the current auth method for the supervisor we wanna start
-	supCfg, currFound := config.Characters[Supervisor]
-	if !currFound {
-		// There's no config for the current supervisor. THIS SHOULDN'T HAPPEN
+	context := s.manager.GetContext(characterName)
+	if context == nil || context.PathFinder == nil {
+		http.Error(w, "Supervisor is not running", http.StatusNotFound)
 		return
 	}
 
-	// Prevent launching of other clients while there's a client with TokenAuth still starting
-	for _, sup := range supervisorList {
+	img, renderedAt, found := context.PathFinder.MapRenderer().Latest()
+	if !found {
+		http.Error(w, "No map rendered yet, enable Debug.RenderMap", http.StatusNotFound)
+		return
+	}
 
-		// If the current don't check against the one we're trying to launch
-		if sup == Supervisor {
-			continue
-		}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Last-Modified", renderedAt.UTC().Format(http.TimeFormat))
+	w.Write(img)
+}
 
-		if s.manager.GetSupervisorStats(sup).SupervisorStatus == bot.Starting {
+func (s *HttpServer) debugHandler(w http.ResponseWriter, r *http.Request) {
+	s.templates.ExecuteTemplate(w, "debug.gohtml", nil)
+}
 
-			// Prevent launching if we're using token auth & another client is starting (no matter what auth method)
-			if supCfg.AuthMethod == "TokenAuth" {
-				return
-			}
+func (s *HttpServer) startSupervisor(w http.ResponseWriter, r *http.Request) {
+	Supervisor := r.URL.Query().Get("characterName")
 
-			// Prevent launching if another client that is using token auth is starting
-			sCfg, found := config.Characters[sup]
-			if found {
-				if sCfg.AuthMethod == "TokenAuth" {
-					return
-				}
-			}
+	if err := s.checkTokenAuthConflict(Supervisor); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errSupervisorNotFound) {
+			status = http.StatusNotFound
 		}
+		writeAPIError(w, status, err)
+		return
 	}
 
-	s.manager.Start(Supervisor, false)
+	if err := s.manager.Start(Supervisor, false); err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
 	s.initialData(w, r)
 }
 
@@ -565,27 +658,66 @@ func (s *HttpServer) index(w http.ResponseWriter) {
 
 func (s *HttpServer) drops(w http.ResponseWriter, r *http.Request) {
 	sup := r.URL.Query().Get("supervisor")
-	cfg, found := config.Characters[sup]
+	cfg, found := config.Characters()[sup]
 	if !found {
 		http.Error(w, "Can't fetch drop data because the configuration "+sup+" wasn't found", http.StatusNotFound)
 		return
 	}
 
-	var Drops []data.Drop
+	scope, stats, days := s.statsForScope(sup, r.URL.Query().Get("scope"))
 
-	if s.manager.GetSupervisorStats(sup).Drops == nil {
+	Drops := stats.Drops
+	if Drops == nil {
 		Drops = make([]data.Drop, 0)
-	} else {
-		Drops = s.manager.GetSupervisorStats(sup).Drops
 	}
 
 	s.templates.ExecuteTemplate(w, "drops.gohtml", DropData{
 		NumberOfDrops: len(Drops),
 		Character:     cfg.CharacterName,
+		Supervisor:    sup,
+		Scope:         scope,
+		Days:          days,
 		Drops:         Drops,
 	})
 }
 
+func (s *HttpServer) analytics(w http.ResponseWriter, r *http.Request) {
+	sup := r.URL.Query().Get("supervisor")
+	cfg, found := config.Characters()[sup]
+	if !found {
+		http.Error(w, "Can't fetch analytics because the configuration "+sup+" wasn't found", http.StatusNotFound)
+		return
+	}
+
+	scope, stats, days := s.statsForScope(sup, r.URL.Query().Get("scope"))
+
+	s.templates.ExecuteTemplate(w, "analytics.gohtml", AnalyticsData{
+		Character:  cfg.CharacterName,
+		Supervisor: sup,
+		Scope:      scope,
+		Days:       days,
+		Runs:       analytics.Compute(stats),
+	})
+}
+
+// statsForScope returns the stats for the given scope: "session" (default), "lifetime" or a day formatted as YYYY-MM-DD,
+// along with the days available in the stats journal
+func (s *HttpServer) statsForScope(sup, scope string) (string, bot.Stats, []string) {
+	history, err := s.manager.History(sup)
+	if err != nil {
+		s.logger.Error("error loading stats history", slog.String("supervisor", sup), slog.Any("error", err))
+	}
+
+	switch scope {
+	case "", "session":
+		return "session", s.manager.GetSupervisorStats(sup), history.SortedDays()
+	case "lifetime":
+		return scope, history.Lifetime, history.SortedDays()
+	default:
+		return scope, history.Days[scope], history.SortedDays()
+	}
+}
+
 func validateSchedulerData(cfg *config.CharacterCfg) error {
 	for day := 0; day < 7; day++ {
 
@@ -632,6 +764,19 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 		// Debug
 		newConfig.Debug.Log = r.Form.Get("debug_log") == "true"
 		newConfig.Debug.Screenshots = r.Form.Get("debug_screenshots") == "true"
+		newConfig.Debug.RenderMap = r.Form.Get("debug_render_map") == "true"
+		// Remote access
+		newConfig.Server.BindAddress = strings.TrimSpace(r.Form.Get("server_bind_address"))
+		newConfig.Server.Password = secretFormValue(r, "server_password", newConfig.Server.Password)
+		newConfig.Server.Token = secretFormValue(r, "server_token", newConfig.Server.Token)
+		if newConfig.Server.BindAddress != "" && net.ParseIP(newConfig.Server.BindAddress) == nil && newConfig.Server.BindAddress != "localhost" {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid bind address"})
+			return
+		}
+		if !isLoopback(newConfig.Server.BindAddress) && newConfig.Server.Password == "" && newConfig.Server.Token == "" {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "A password or token is required when exposing the dashboard outside localhost"})
+			return
+		}
 		// Discord
 		newConfig.Discord.Enabled = r.Form.Get("discord_enabled") == "true"
 		newConfig.Discord.EnableGameCreatedMessages = r.Form.Has("enable_game_created_messages")
@@ -648,11 +793,11 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 			return -1
 		}, discordAdmins)
 		newConfig.Discord.BotAdmins = strings.Split(cleanedAdmins, ",")
-		newConfig.Discord.Token = r.Form.Get("discord_token")
+		newConfig.Discord.Token = secretFormValue(r, "discord_token", newConfig.Discord.Token)
 		newConfig.Discord.ChannelID = r.Form.Get("discord_channel_id")
 		// Telegram
 		newConfig.Telegram.Enabled = r.Form.Get("telegram_enabled") == "true"
-		newConfig.Telegram.Token = r.Form.Get("telegram_token")
+		newConfig.Telegram.Token = secretFormValue(r, "telegram_token", newConfig.Telegram.Token)
 		telegramChatId, err := strconv.ParseInt(r.Form.Get("telegram_chat_id"), 10, 64)
 		if err != nil {
 			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid Telegram Chat ID"})
@@ -660,6 +805,44 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 		}
 		newConfig.Telegram.ChatID = telegramChatId
 
+		// Telegram users who can use bot commands, everyone in the chat if empty
+		newConfig.Telegram.BotAdmins = make([]int64, 0)
+		for _, admin := range strings.Split(r.Form.Get("telegram_admins"), ",") {
+			admin = strings.TrimSpace(admin)
+			if admin == "" {
+				continue
+			}
+			adminID, err := strconv.ParseInt(admin, 10, 64)
+			if err != nil {
+				s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid Telegram admin user ID: " + admin})
+				return
+			}
+			newConfig.Telegram.BotAdmins = append(newConfig.Telegram.BotAdmins, adminID)
+		}
+		// Webhook
+		newConfig.Webhook.Enabled = r.Form.Get("webhook_enabled") == "true"
+		newConfig.Webhook.URL = strings.TrimSpace(r.Form.Get("webhook_url"))
+		newConfig.Webhook.Secret = secretFormValue(r, "webhook_secret", newConfig.Webhook.Secret)
+		newConfig.Webhook.IncludeScreenshots = r.Form.Get("webhook_include_screenshots") == "true"
+		newConfig.Webhook.Events, err = webhook.ParseEventTypes(r.Form.Get("webhook_events"))
+		if err != nil {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: err.Error()})
+			return
+		}
+		if newConfig.Webhook.Enabled && newConfig.Webhook.URL == "" {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Webhook URL is required"})
+			return
+		}
+		// Drop notifications
+		newConfig.DropNotifications.Enabled = r.Form.Get("drop_notifications_enabled") == "true"
+		newConfig.DropNotifications.IncludeScreenshot = r.Form.Get("drop_notifications_screenshot") == "true"
+		newConfig.DropNotifications.Rules = drops.ParseRules(r.Form.Get("drop_notifications_rules"))
+		newConfig.DropNotifications.Qualities, err = drops.ParseQualities(r.Form.Get("drop_notifications_qualities"))
+		if err != nil {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: err.Error()})
+			return
+		}
+
 		err = config.ValidateAndSaveConfig(newConfig)
 		if err != nil {
 			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: err.Error()})
@@ -686,7 +869,7 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		}
 
 		supervisorName := r.Form.Get("name")
-		cfg, found := config.Characters[supervisorName]
+		cfg, found := config.Characters()[supervisorName]
 		if !found {
 			err = config.CreateFromTemplate(supervisorName)
 			if err != nil {
@@ -697,7 +880,7 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 
 				return
 			}
-			cfg = config.Characters["template"]
+			cfg = config.Characters()["template"]
 		}
 
 		cfg.MaxGameLength, _ = strconv.Atoi(r.Form.Get("maxGameLength"))
@@ -706,13 +889,14 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.KillD2OnStop = r.Form.Has("kill_d2_process")
 		cfg.ClassicMode = r.Form.Has("classic_mode")
 		cfg.CloseMiniPanel = r.Form.Has("close_mini_panel")
+		cfg.Profiles = splitProfiles(r.Form.Get("profiles"))
 
 		// Bnet config
 		cfg.Username = r.Form.Get("username")
-		cfg.Password = r.Form.Get("password")
+		cfg.Password = secretFormValue(r, "password", cfg.Password)
 		cfg.Realm = r.Form.Get("realm")
 		cfg.AuthMethod = r.Form.Get("authmethod")
-		cfg.AuthToken = r.Form.Get("AuthToken")
+		cfg.AuthToken = secretFormValue(r, "AuthToken", cfg.AuthToken)
 
 		// Scheduler config
 		cfg.Scheduler.Enabled = r.Form.Has("schedulerEnabled")
@@ -937,7 +1121,7 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.Companion.Leader = r.Form.Has("companionLeader")
 		cfg.Companion.LeaderName = r.Form.Get("companionLeaderName")
 		cfg.Companion.GameNameTemplate = r.Form.Get("companionGameNameTemplate")
-		cfg.Companion.GamePassword = r.Form.Get("companionGamePassword")
+		cfg.Companion.GamePassword = secretFormValue(r, "companionGamePassword", cfg.Companion.GamePassword)
 
 		// Back to town config
 		cfg.BackToTown.NoHpPotions = r.Form.Has("noHpPotions")
@@ -946,14 +1130,20 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.BackToTown.EquipmentBroken = r.Form.Has("equipmentBroken")
 
 		config.SaveSupervisorConfig(supervisorName, cfg)
+
+		// Back to the settings when the saved config is not valid, so the errors are shown
+		if saved, found := config.Characters()[supervisorName]; found && !saved.Runtime.Validation.Valid() {
+			http.Redirect(w, r, "/supervisorSettings?supervisor="+url.QueryEscape(supervisorName), http.StatusSeeOther)
+			return
+		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
 	supervisor := r.URL.Query().Get("supervisor")
-	cfg := config.Characters["template"]
+	cfg := config.Characters()["template"]
 	if supervisor != "" {
-		cfg = config.Characters[supervisor]
+		cfg = config.Characters()[supervisor]
 	}
 
 	enabledRuns := make([]string, 0)
@@ -993,5 +1183,53 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		DisabledRuns: disabledRuns,
 		AvailableTZs: availableTZs,
 		RecipeList:   config.AvailableRecipes,
+		Validation:   cfg.Runtime.Validation,
 	})
 }
+
+// effectiveConfig shows the character config with its profiles merged, the one the supervisor runs with
+func (s *HttpServer) effectiveConfig(w http.ResponseWriter, r *http.Request) {
+	supervisor := r.URL.Query().Get("supervisor")
+	cfg, found := config.Characters()[supervisor]
+	if !found {
+		http.Error(w, "Supervisor not found", http.StatusNotFound)
+		return
+	}
+
+	text, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to serialize the config: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "# Effective config of %s, merged from:\n", supervisor)
+	for _, l := range cfg.Runtime.Layers {
+		fmt.Fprintf(w, "#   %s\n", l)
+	}
+	w.Write(text)
+}
+
+// secretFormValue returns the new value of a secret field. Secrets are never rendered back in the forms, so an empty
+// value keeps the current secret and checking <name>_remove removes it, it's deleted from the vault when saved.
+func secretFormValue(r *http.Request, name, current string) string {
+	if r.Form.Has(name + "_remove") {
+		return ""
+	}
+	if value := r.Form.Get(name); value != "" {
+		return value
+	}
+
+	return current
+}
+
+func splitProfiles(value string) []string {
+	profiles := make([]string, 0)
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+
+	return profiles
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+	ctx "github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func TestDebugDataRedactsSecrets(t *testing.T) {
+	cfg := config.CharacterCfg{Password: "plain password", AuthToken: "plain token"}
+	cfg.Companion.GamePassword = "game password"
+	cfg.Runtime.SecretRefs = map[string]string{"password": "vault:sorc/password"}
+	data := &game.Data{CharacterCfg: cfg}
+	s := &HttpServer{manager: &fakeManager{context: &ctx.Context{Data: data}}}
+
+	rec := httptest.NewRecorder()
+	s.debugData(rec, httptest.NewRequest(http.MethodGet, "/debug-data?characterName=sorc", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, secret := range []string{"plain password", "plain token", "game password"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Expected %q not to be sent, got %s", secret, body)
+		}
+	}
+	if !strings.Contains(body, "vault:sorc/password") {
+		t.Errorf("Expected the password to be replaced by its reference, got %s", body)
+	}
+	if data.CharacterCfg.Password != "plain password" {
+		t.Errorf("Expected the game data not to change, got %s", data.CharacterCfg.Password)
+	}
+}
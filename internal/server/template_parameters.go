@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/analytics"
 	"github.com/hectorgimenez/koolo/internal/bot"
 	"github.com/hectorgimenez/koolo/internal/config"
 )
@@ -16,9 +17,20 @@ type IndexData struct {
 type DropData struct {
 	NumberOfDrops int
 	Character     string
+	Supervisor    string
+	Scope         string
+	Days          []string
 	Drops         []data.Drop
 }
 
+type AnalyticsData struct {
+	Character  string
+	Supervisor string
+	Scope      string
+	Days       []string
+	Runs       []analytics.RunAnalytics
+}
+
 type CharacterSettings struct {
 	ErrorMessage string
 	Supervisor   string
@@ -28,6 +40,7 @@ type CharacterSettings struct {
 	DisabledRuns []string
 	AvailableTZs map[int]string
 	RecipeList   []string
+	Validation   config.Validation
 }
 
 type ConfigData struct {
@@ -35,6 +48,11 @@ type ConfigData struct {
 	*config.KooloCfg
 }
 
+type LoginData struct {
+	ErrorMessage string
+	Next         string
+}
+
 type AutoSettings struct {
 	ErrorMessage string
 }
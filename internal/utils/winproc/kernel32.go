@@ -10,4 +10,6 @@ const (
 var (
 	KERNEL32                = windows.NewLazySystemDLL("kernel32.dll")
 	SetThreadExecutionState = KERNEL32.NewProc("SetThreadExecutionState")
+	AllocConsole            = KERNEL32.NewProc("AllocConsole")
+	FreeConsole             = KERNEL32.NewProc("FreeConsole")
 )